@@ -1,18 +1,18 @@
 package main
 
 import (
+	"bufio"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/gaoliveira21/intel8080-space-invaders/pkg/cpu"
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/debug"
 	"github.com/gaoliveira21/intel8080-space-invaders/pkg/io"
 )
 
-func onInput(cpu *cpu.Intel8080) {
-	os.Exit(0)
-}
-
 func onOutput(cpu *cpu.Intel8080) {
 	registers := cpu.GetRegisters()
 	switch registers["C"] {
@@ -33,7 +33,76 @@ func onOutput(cpu *cpu.Intel8080) {
 	}
 }
 
+func createTracer(path, start, stop string) (*cpu.Tracer, func()) {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatalln("Cannot create trace file", err)
+	}
+
+	w := bufio.NewWriter(f)
+	tracer := cpu.NewTracer(w)
+
+	if start != "" {
+		condition, err := cpu.ParseTraceCondition(start)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		tracer.StartAt(condition)
+	}
+
+	if stop != "" {
+		condition, err := cpu.ParseTraceCondition(stop)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		tracer.StopAt(condition)
+	}
+
+	return tracer, func() {
+		if err := tracer.Err(); err != nil {
+			log.Println("Cannot write trace", err)
+		}
+		w.Flush()
+		f.Close()
+	}
+}
+
+// prompt runs monitor commands while the debugger is paused and returns
+// false when the user quits.
+func prompt(in *bufio.Scanner, monitor *debug.Monitor) bool {
+	for {
+		fmt.Print("\n> ")
+		if !in.Scan() {
+			return false
+		}
+
+		switch monitor.Execute(in.Text()) {
+		case debug.MonitorResume:
+			return true
+		case debug.MonitorQuit:
+			return false
+		}
+	}
+}
+
 func main() {
+	tracePath := flag.String("trace", "", "Write an execution trace to this file")
+	traceStart := flag.String("trace-start", "", "Start tracing at pc=<address> or cycle=<count>")
+	traceStop := flag.String("trace-stop", "", "Stop tracing at pc=<address> or cycle=<count>")
+	breakpoints := flag.String("break", "", "Comma-separated breakpoints, e.g. 0x01AB,write=0x2000:0x05,out=5")
+	monitorEnabled := flag.Bool("monitor", false, "Start paused in the machine-code monitor (h for help)")
+	symbolsPath := flag.String("symbols", "", "Name addresses in the trace, breakpoints and monitor with this symbol file")
+
+	flag.Parse()
+
+	symbols := &debug.Symbols{}
+	if *symbolsPath != "" {
+		var err error
+		if symbols, err = debug.LoadSymbols(*symbolsPath); err != nil {
+			log.Fatalln("Cannot read symbols", err)
+		}
+	}
+
 	fmt.Println("Running a test ROM - roms/tests/TST8080.COM")
 	rom, err := os.ReadFile("cmd/cpudiag/roms/tests/TST8080.COM")
 
@@ -48,18 +117,51 @@ func main() {
 	cpu.LoadProgram(rom, 0x100)
 	cpu.SetPC(0x100)
 
-	in := byte(0xDB)  // IN pa
+	hlt := byte(0x76) // HLT
 	out := byte(0xD3) // OUT
 	ret := byte(0xC9) // RET
 
-	cpu.WriteIntoMemory(0x0000, in)
+	// The test program jumps to $0000 (CP/M warm boot) once it is done
+	cpu.WriteIntoMemory(0x0000, hlt)
 	cpu.WriteIntoMemory(0x0005, out)
 	cpu.WriteIntoMemory(0x0007, ret)
 
-	cpu.SetInputListener(onInput)
 	cpu.SetOutputListener(onOutput)
 
-	for {
+	if *tracePath != "" {
+		tracer, closeTrace := createTracer(*tracePath, *traceStart, *traceStop)
+		defer closeTrace()
+		tracer.SetLabels(symbols.Label)
+		cpu.SetTracer(tracer)
+	}
+
+	var debugger *debug.Debugger
+	var monitor *debug.Monitor
+	if *breakpoints != "" || *monitorEnabled {
+		debugger = debug.NewDebugger(cpu)
+		debugger.SetSymbols(symbols)
+		monitor = debug.NewMonitor(debugger, os.Stdout)
+		if *breakpoints != "" {
+			for _, spec := range strings.Split(*breakpoints, ",") {
+				if err := debugger.AddBreakpointSpec(spec); err != nil {
+					log.Fatalln(err)
+				}
+			}
+		}
+		if *monitorEnabled {
+			debugger.Pause()
+		}
+	}
+
+	stdin := bufio.NewScanner(os.Stdin)
+	for !cpu.IsHalted() {
+		if debugger != nil && !debugger.CanExecute() {
+			if !prompt(stdin, monitor) {
+				break
+			}
+			continue
+		}
 		cpu.Run()
 	}
+	fmt.Println()
 }
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/debug"
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/disasm"
+)
+
+func parseAddress(name string, s string) uint16 {
+	addr, err := strconv.ParseUint(s, 0, 16)
+	if err != nil {
+		log.Fatalf("Invalid %s %q: %s\n", name, s, err)
+	}
+	return uint16(addr)
+}
+
+func writeFile(path string, write func(w io.Writer) error) {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Fatalf("Cannot write %s: %s\n", path, err)
+	}
+}
+
+// addSymbols names the labels of the listing after the symbols, and adds
+// their comments.
+func addSymbols(l *disasm.Listing, symbols *debug.Symbols) {
+	for _, sym := range symbols.All() {
+		if sym.Name != "" {
+			l.Labels[sym.Addr] = sym.Name
+		}
+		if comment, ok := symbols.Comment(sym.Addr); ok {
+			l.Comments[sym.Addr] = comment
+		}
+	}
+	l.Names = symbols.Label
+}
+
+func main() {
+	format := flag.String("format", "plain", "Output format: plain, color or json")
+	org := flag.String("org", "0x0000", "Address the ROM is loaded at")
+	start := flag.String("start", "", "First address to disassemble (default: -org)")
+	end := flag.String("end", "", "Last address to disassemble (default: end of the ROM)")
+	trace := flag.Bool("trace", false, "Follow control flow from the reset and RST vectors and write a listing that reassembles, -format is ignored")
+	entries := flag.String("entries", "", "File of extra addresses to trace from, one per line")
+	symbolsPath := flag.String("symbols", "", "Label the listing with the names and comments of this symbol file (requires -trace)")
+	graphPath := flag.String("graph", "", "Write the control-flow graph to this file in Graphviz DOT (requires -trace)")
+	xrefsPath := flag.String("xrefs", "", "Write the instructions reading and writing each RAM address to this file as JSON (requires -trace)")
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [rom]\n\nrom defaults to the Space Invaders ROM.\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	f, err := disasm.ParseFormat(*format)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if (*symbolsPath != "" || *graphPath != "" || *xrefsPath != "") && !*trace {
+		log.Fatalln("-symbols, -graph and -xrefs require -trace")
+	}
+
+	path := "cmd/invaders/roms/space-invaders/invaders"
+	if flag.NArg() > 0 {
+		path = flag.Arg(0)
+	}
+
+	rom, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalln("Cannot read ROM", err)
+	}
+
+	base := parseAddress("org", *org)
+	if int(base)+len(rom) > 0x10000 {
+		log.Fatalln("ROM does not fit in memory at", *org)
+	}
+
+	memory := make(disasm.Bytes, 0x10000)
+	copy(memory[base:], rom)
+
+	first, last := base, base+uint16(max(len(rom), 1)-1)
+	if *start != "" {
+		first = parseAddress("start", *start)
+	}
+	if *end != "" {
+		last = parseAddress("end", *end)
+	}
+	if first > last {
+		log.Fatalln(fmt.Sprintf("-start $%04X is after -end $%04X", first, last))
+	}
+
+	if *trace {
+		points := disasm.EntryPoints
+		if *entries != "" {
+			file, err := os.Open(*entries)
+			if err != nil {
+				log.Fatalln("Cannot read entry points", err)
+			}
+			extra, err := disasm.ReadEntryPoints(file)
+			file.Close()
+			if err != nil {
+				log.Fatalln("Invalid entry points", err)
+			}
+			points = append(points, extra...)
+		}
+
+		listing := disasm.Trace(memory, first, last, points)
+		if *symbolsPath != "" {
+			symbols, err := debug.LoadSymbols(*symbolsPath)
+			if err != nil {
+				log.Fatalln("Cannot read symbols", err)
+			}
+			addSymbols(listing, symbols)
+		}
+
+		if *graphPath != "" {
+			writeFile(*graphPath, listing.WriteDOT)
+		}
+
+		if *xrefsPath != "" {
+			writeFile(*xrefsPath, func(w io.Writer) error {
+				encoder := json.NewEncoder(w)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(listing.MemoryReferences())
+			})
+		}
+
+		if err := listing.Write(os.Stdout); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+
+	if err := disasm.Write(os.Stdout, disasm.Sweep(memory, first, last), f); err != nil {
+		log.Fatalln(err)
+	}
+}
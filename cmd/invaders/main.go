@@ -1,16 +1,23 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"embed"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/cheat"
 	"github.com/gaoliveira21/intel8080-space-invaders/pkg/cpu"
 	"github.com/gaoliveira21/intel8080-space-invaders/pkg/debug"
 	"github.com/gaoliveira21/intel8080-space-invaders/pkg/io"
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/machine"
 	"github.com/veandco/go-sdl2/sdl"
 )
 
@@ -18,22 +25,179 @@ import (
 //go:embed assets
 var fs embed.FS
 
-func onSignal(c chan os.Signal, running *bool, debugger *debug.Debugger) {
-	for signal := range c {
-		log.Printf("signal %s received\n", signal)
-		if debugger != nil {
-			debugger.Dump()
+// A cabinet control: a bit of an input port
+type input struct {
+	port uint8
+	bit  uint8
+}
+
+// Controls by the name debug stream clients send
+var inputs = map[string]input{
+	"coin":     {1, 0},
+	"2p-start": {1, 1},
+	"1p-start": {1, 2},
+	"1p-shot":  {1, 4},
+	"1p-left":  {1, 5},
+	"1p-right": {1, 6},
+	"tilt":     {2, 2}, // Game over
+	"2p-shot":  {2, 4},
+	"2p-left":  {2, 5},
+	"2p-right": {2, 6},
+}
+
+var inputKeys = map[sdl.Keycode]string{
+	sdl.K_c:     "coin",
+	sdl.K_2:     "2p-start",
+	sdl.K_1:     "1p-start",
+	sdl.K_w:     "1p-shot",
+	sdl.K_a:     "1p-left",
+	sdl.K_d:     "1p-right",
+	sdl.K_t:     "tilt",
+	sdl.K_UP:    "2p-shot",
+	sdl.K_LEFT:  "2p-left",
+	sdl.K_RIGHT: "2p-right",
+}
+
+// Labels memory dumps, see machine.Memory
+var memoryMap = []debug.MemoryRegion{
+	{Name: "ROM", Start: 0x0000, End: 0x1FFF},
+	{Name: "Work RAM", Start: 0x2000, End: 0x23FF},
+	{Name: "Video RAM", Start: 0x2400, End: 0x3FFF},
+	{Name: "Mirror", Start: 0x4000, End: 0xFFFF},
+}
+
+// runCommand carries out a control command from a debug client between frames.
+// Stepping leaves execution paused, so the steps are not followed by a frame.
+func runCommand(cmd debug.Command, debugger *debug.Debugger, m *machine.Machine, ioBus *io.IOBus) {
+	defer cmd.Done()
+
+	switch cmd.Kind {
+	case debug.CommandPause:
+		debugger.Pause()
+	case debug.CommandResume:
+		debugger.Resume()
+	case debug.CommandStep:
+		debugger.ExecSteps(cmd.Count, func() { m.Step() })
+	case debug.CommandStepFrame:
+		debugger.Pause()
+		debugger.Exec(m.StepFrame)
+	case debug.CommandReset:
+		debugger.Exec(m.Reset)
+	case debug.CommandInput:
+		if in, ok := inputs[cmd.Key]; ok {
+			debugger.Exec(func() { ioBus.OnInput(in.port, in.bit, cmd.Pressed) })
+		} else {
+			log.Printf("Unknown input %q\n", cmd.Key)
 		}
-		*running = false
+		return
 	}
+
+	log.Printf("Debug command %s done\n", cmd.Kind)
+}
+
+// runMonitor reads monitor commands from stdin while the game runs and closes
+// quit when the user quits.
+func runMonitor(monitor *debug.Monitor, quit chan struct{}) {
+	in := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print("> ")
+		if !in.Scan() {
+			return
+		}
+
+		if monitor.Execute(in.Text()) == debug.MonitorQuit {
+			close(quit)
+			return
+		}
+	}
+}
+
+func loadCheats(path string) *cheat.List {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalln("Cannot open cheats", err)
+	}
+	defer f.Close()
+
+	cheats, err := cheat.Load(f)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	log.Printf("%d cheats loaded\n", len(cheats.Cheats()))
+	return cheats
+}
+
+const saveStateDir = ".saves"
+
+var saveSlotKeys = map[sdl.Keycode]int{
+	sdl.K_F1: 1,
+	sdl.K_F2: 2,
+	sdl.K_F3: 3,
+	sdl.K_F4: 4,
+}
+
+func saveStatePath(slot int) string {
+	return filepath.Join(saveStateDir, fmt.Sprintf("slot%d.state", slot))
+}
+
+func saveState(m *machine.Machine, slot int) {
+	if err := os.MkdirAll(saveStateDir, os.ModePerm); err != nil {
+		log.Println("Cannot create save state folder", err)
+		return
+	}
+
+	f, err := os.Create(saveStatePath(slot))
+	if err != nil {
+		log.Println("Cannot create save state", err)
+		return
+	}
+	defer f.Close()
+
+	if err := m.SaveState(f); err != nil {
+		log.Println("Cannot save state", err)
+		return
+	}
+
+	log.Printf("State saved to slot %d\n", slot)
+}
+
+func loadState(m *machine.Machine, slot int) {
+	f, err := os.Open(saveStatePath(slot))
+	if err != nil {
+		log.Println("Cannot open save state", err)
+		return
+	}
+	defer f.Close()
+
+	if err := m.LoadState(bufio.NewReader(f)); err != nil {
+		log.Println("Cannot load state", err)
+		return
+	}
+
+	log.Printf("State loaded from slot %d\n", slot)
 }
 
 func main() {
 	debugEnabled := flag.Bool("debug", false, "Run emulator in Debug Mode")
+	debugAddr := flag.String("debug-addr", "localhost:8080", "Address the debug HTTP API is served on in Debug Mode")
 	audioDisabled := flag.Bool("sound-off", false, "Turn audio On/Off")
+	rewindSize := flag.Int("rewind-size", 600, "Number of snapshots kept for rewinding (0 disables rewind)")
+	rewindInterval := flag.Int("rewind-interval", 5, "Frames between rewind snapshots")
+	gdbAddr := flag.String("gdb", "", "Serve GDB remote clients on this address, e.g. localhost:1234 (requires -debug)")
+	dapAddr := flag.String("dap", "", "Serve Debug Adapter Protocol clients on this address, e.g. localhost:4711 (requires -debug)")
+	breakpoints := flag.String("break", "", "Comma-separated breakpoints, e.g. 0x01AB,write=0x2000:0x05,out=5 (requires -debug)")
+	cheatsPath := flag.String("cheats", "", "Apply the cheats in this JSON file every frame")
+	monitorEnabled := flag.Bool("monitor", false, "Read machine-code monitor commands from stdin, h for help (requires -debug)")
+	dumpPath := flag.String("load-dump", "", "Resume from a dump written on Ctrl-C, e.g. .dump (requires -debug)")
+	symbolsPath := flag.String("symbols", "", "Name addresses in breakpoints, disassembly and the debug clients with this symbol file (requires -debug)")
 
 	flag.Parse()
 
+	if *rewindSize < 0 {
+		log.Fatalln("-rewind-size must not be negative")
+	}
+
 	log.Println("Starting Space Invaders...")
 	log.Println("Reading ROM...")
 
@@ -53,90 +217,207 @@ func main() {
 		}
 	}
 
+	cheats := &cheat.List{}
+	if *cheatsPath != "" {
+		cheats = loadCheats(*cheatsPath)
+	}
+
 	ioBus := io.NewIOBus(soundManager)
-	cpu := cpu.NewIntel8080(ioBus)
-	cpu.LoadProgram(rom, 0)
+	cpu := cpu.NewIntel8080WithMemory(ioBus, machine.NewMemory())
+	m := machine.NewMachine(cpu, ioBus)
+	m.LoadROM(rom)
+
+	if (*breakpoints != "" || *gdbAddr != "" || *dapAddr != "" || *monitorEnabled || *dumpPath != "" || *symbolsPath != "") && !*debugEnabled {
+		log.Fatalln("-break, -gdb, -dap, -monitor, -load-dump and -symbols require -debug")
+	}
+
+	// Closed when the monitor quits
+	quit := make(chan struct{})
 
 	var debugger *debug.Debugger
 	if *debugEnabled {
 		debugger = debug.NewDebugger(cpu)
-		go debugger.StartHttpServer()
+		debugger.SetIO(ioBus)
+		debugger.SetMemoryMap(memoryMap)
+		if *symbolsPath != "" {
+			symbols, err := debug.LoadSymbols(*symbolsPath)
+			if err != nil {
+				log.Fatalln("Cannot read symbols", err)
+			}
+			debugger.SetSymbols(symbols)
+		}
+		if *dumpPath != "" {
+			if err := debugger.LoadDump(*dumpPath); err != nil {
+				log.Fatalln("Cannot load dump", err)
+			}
+			log.Printf("Dump loaded from %s\n", *dumpPath)
+		}
+		if *breakpoints != "" {
+			for _, spec := range strings.Split(*breakpoints, ",") {
+				if err := debugger.AddBreakpointSpec(spec); err != nil {
+					log.Fatalln(err)
+				}
+			}
+		}
+		m.SetBreaker(debugger)
+		debugger.SetCheats(cheats)
+		go func() {
+			if err := debugger.StartHttpServer(*debugAddr); err != nil {
+				log.Println("Cannot serve debug HTTP API", err)
+			}
+		}()
+
+		if *gdbAddr != "" {
+			go func() {
+				if err := debugger.StartGDBServer(*gdbAddr); err != nil {
+					log.Println("Cannot serve GDB", err)
+				}
+			}()
+		}
+
+		if *dapAddr != "" {
+			go func() {
+				if err := debugger.StartDAPServer(*dapAddr); err != nil {
+					log.Println("Cannot serve DAP", err)
+				}
+			}()
+		}
+
+		if *monitorEnabled {
+			go runMonitor(debug.NewMonitor(debugger, os.Stdout), quit)
+		}
 	}
 
-	running := true
+	// The machine and its IO bus are only touched inside exec, so debugger
+	// clients never see it mid-frame
+	exec := func(f func()) {
+		if debugger != nil {
+			debugger.Exec(f)
+		} else {
+			f()
+		}
+	}
 
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	go onSignal(c, &running, debugger)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+
+	// Debug clients are served by the main loop; without a debugger nothing
+	// is ever received
+	var commands <-chan debug.Command
+	if debugger != nil {
+		commands = debugger.Commands()
+	}
 
 	io.InitDisplay()
 	defer io.DestroyDisplay()
 
-	var instructionCycles uint
-	lastFrame := time.Now()
-	frameRate := time.Second / 120
-	interruptType := 1
+	pacer := machine.NewPacer(machine.FrameRate)
+	rewinder := machine.NewRewinder(m, *rewindSize, *rewindInterval)
+	rewinding := false
 
+	running := true
 	for running {
-		if instructionCycles > 0 {
-			instructionCycles--
-			time.Sleep(time.Duration(0))
-			continue
+	control:
+		for {
+			select {
+			case signal := <-signals:
+				log.Printf("signal %s received\n", signal)
+				if debugger != nil {
+					if err := debugger.Dump(".dump"); err != nil {
+						log.Println("Cannot dump machine state", err)
+					}
+				}
+				running = false
+			case <-quit:
+				running = false
+			case cmd := <-commands:
+				runCommand(cmd, debugger, m, ioBus)
+			default:
+				break control
+			}
 		}
 
-		instructionCycles = cpu.Run()
-
-		if time.Since(lastFrame) >= frameRate {
-			if interruptType == 1 {
-				interruptType = 2
+		exec(func() {
+			if rewinding {
+				if _, err := rewinder.Rewind(); err != nil {
+					log.Println("Cannot rewind", err)
+				}
 			} else {
-				interruptType = 1
+				cheats.Apply(cpu)
+				// Frames cut short by the debugger are not recorded
+				if m.RunFrame() {
+					if err := rewinder.Record(); err != nil {
+						log.Println("Cannot record rewind snapshot", err)
+					}
+				}
 			}
-
 			io.Draw(cpu.GetVRAM())
+		})
 
-			if cpu.InterruptEnabled {
-				cpu.Interrupt(interruptType)
-			}
+		if debugger != nil {
+			debugger.PublishFrame()
+		}
 
-			for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-				switch t := event.(type) {
-				case *sdl.KeyboardEvent:
-					pressed := false
-					if t.Type == sdl.KEYDOWN {
-						pressed = true
-					} else if t.Type == sdl.KEYUP {
-						pressed = false
-					}
+		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+			switch t := event.(type) {
+			case *sdl.KeyboardEvent:
+				pressed := false
+				if t.Type == sdl.KEYDOWN {
+					pressed = true
+				} else if t.Type == sdl.KEYUP {
+					pressed = false
+				}
 
+				if pressed && debugger != nil {
 					switch t.Keysym.Sym {
-					case sdl.K_c:
-						ioBus.OnInput(1, 0, pressed) // Coin
-					case sdl.K_2:
-						ioBus.OnInput(1, 1, pressed) // 2P start
-					case sdl.K_1:
-						ioBus.OnInput(1, 2, pressed) // 1P start
-					case sdl.K_w:
-						ioBus.OnInput(1, 4, pressed) // 1P shot
-					case sdl.K_a:
-						ioBus.OnInput(1, 5, pressed) // 1P left
-					case sdl.K_d:
-						ioBus.OnInput(1, 6, pressed) // 1P right
-					case sdl.K_t:
-						ioBus.OnInput(2, 2, pressed) // Tilt (Game over)
-					case sdl.K_UP:
-						ioBus.OnInput(2, 4, pressed) // 2P shot
-					case sdl.K_LEFT:
-						ioBus.OnInput(2, 5, pressed) // 2P left
-					case sdl.K_RIGHT:
-						ioBus.OnInput(2, 6, pressed) // 2P right
+					case sdl.K_F5:
+						if t.Repeat != 0 {
+							break
+						}
+						if debugger.Paused() {
+							debugger.Resume()
+						} else {
+							debugger.Pause()
+						}
+					case sdl.K_F6:
+						debugger.Step() // Hold to keep stepping
+					case sdl.K_F7:
+						debugger.StepOver()
+					case sdl.K_F8:
+						debugger.StepOut()
+					}
+				}
+
+				if pressed && t.Repeat == 0 {
+					if slot, ok := saveSlotKeys[t.Keysym.Sym]; ok {
+						if t.Keysym.Mod&sdl.KMOD_SHIFT != 0 {
+							exec(func() { saveState(m, slot) })
+						} else {
+							exec(func() { loadState(m, slot) })
+						}
 					}
-				case *sdl.QuitEvent:
-					running = false
 				}
+
+				if t.Keysym.Sym == sdl.K_BACKSPACE {
+					rewinding = pressed // Hold to rewind
+				} else if name, ok := inputKeys[t.Keysym.Sym]; ok {
+					in := inputs[name]
+					exec(func() { ioBus.OnInput(in.port, in.bit, pressed) })
+				}
+			case *sdl.QuitEvent:
+				running = false
 			}
+		}
+
+		pacer.Wait()
+	}
+
+	if debugger != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
 
-			lastFrame = time.Now()
+		if err := debugger.Shutdown(ctx); err != nil {
+			log.Println("Cannot shut down debug server", err)
 		}
 	}
 }
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/io"
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/z80"
+)
+
+func main() {
+	fmt.Println("Running a test ROM - roms/tests/zexdoc.com")
+	rom, err := os.ReadFile("cmd/zexdoc/roms/tests/zexdoc.com")
+
+	if err != nil {
+		log.Fatalln("Cannot read ROM", err)
+	}
+
+	fmt.Printf("%d bytes loaded\n", len(rom))
+
+	ioBus := io.NewIOBus(nil)
+	z80.RunCPM(z80.NewZ80(ioBus), rom, os.Stdout)
+	fmt.Println()
+}
@@ -0,0 +1,129 @@
+package cheat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Mode is how a cheat is applied.
+type Mode string
+
+const (
+	// Freeze writes the value every frame
+	Freeze Mode = "freeze"
+	// Patch writes the value once
+	Patch Mode = "patch"
+)
+
+// Address is written "0x20E9" in cheat files.
+type Address uint16
+
+func (a Address) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fmt.Sprintf("0x%04X", uint16(a)))
+}
+
+// UnmarshalJSON accepts numbers and strings in any base strconv accepts, e.g.
+// 8425, "0x20E9" or "$20E9".
+func (a *Address) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		s = string(data)
+	}
+
+	if strings.HasPrefix(s, "$") {
+		s = "0x" + s[1:]
+	}
+
+	n, err := strconv.ParseUint(s, 0, 16)
+	if err != nil {
+		return fmt.Errorf("invalid address %s", data)
+	}
+	*a = Address(n)
+	return nil
+}
+
+type Cheat struct {
+	Name  string  `json:"name"`
+	Addr  Address `json:"address"`
+	Value byte    `json:"value"`
+	Mode  Mode    `json:"mode"`
+
+	applied bool
+}
+
+// Memory is where cheats are applied, usually the CPU.
+type Memory interface {
+	WriteIntoMemory(addr uint16, b byte)
+}
+
+// List is a set of cheats, saved as a JSON array.
+type List struct {
+	cheats []Cheat
+}
+
+func Load(r io.Reader) (*List, error) {
+	var cheats []Cheat
+	if err := json.NewDecoder(r).Decode(&cheats); err != nil {
+		return nil, fmt.Errorf("cannot read cheats: %w", err)
+	}
+
+	l := &List{}
+	for _, c := range cheats {
+		if err := l.Add(c); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+func (l *List) Save(w io.Writer) error {
+	cheats := l.cheats
+	if cheats == nil {
+		cheats = []Cheat{}
+	}
+
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(cheats)
+}
+
+// Add adds c, which is applied from the next call to Apply.
+func (l *List) Add(c Cheat) error {
+	if c.Mode != Freeze && c.Mode != Patch {
+		return fmt.Errorf("invalid mode %q for cheat %q, expected freeze or patch", c.Mode, c.Name)
+	}
+
+	c.applied = false
+	l.cheats = append(l.cheats, c)
+	return nil
+}
+
+// Remove removes the cheats on addr. Patched memory is not restored.
+func (l *List) Remove(addr uint16) {
+	cheats := l.cheats[:0]
+	for _, c := range l.cheats {
+		if uint16(c.Addr) != addr {
+			cheats = append(cheats, c)
+		}
+	}
+	l.cheats = cheats
+}
+
+func (l *List) Cheats() []Cheat {
+	return append([]Cheat{}, l.cheats...)
+}
+
+// Apply writes frozen values, and patches that have not been written yet.
+// Hosts call it every frame.
+func (l *List) Apply(m Memory) {
+	for i := range l.cheats {
+		c := &l.cheats[i]
+		if c.Mode == Freeze || !c.applied {
+			m.WriteIntoMemory(uint16(c.Addr), c.Value)
+			c.applied = true
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package cheat
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type TestMemory map[uint16]int
+
+// Counts writes
+func (m TestMemory) WriteIntoMemory(addr uint16, b byte) {
+	m[addr]++
+}
+
+func TestApply(t *testing.T) {
+	l := &List{}
+	l.Add(Cheat{Name: "Lives", Addr: 0x21FF, Value: 9, Mode: Freeze})
+	l.Add(Cheat{Name: "Score", Addr: 0x20F8, Value: 0x99, Mode: Patch})
+
+	m := TestMemory{}
+	for i := 0; i < 3; i++ {
+		l.Apply(m)
+	}
+
+	if m[0x21FF] != 3 {
+		t.Errorf("Freeze was not applied every frame")
+	}
+
+	if m[0x20F8] != 1 {
+		t.Errorf("Patch was not applied once")
+	}
+
+	l.Remove(0x21FF)
+	l.Apply(m)
+
+	if m[0x21FF] != 3 || len(l.Cheats()) != 1 {
+		t.Errorf("Remove did not remove the cheat")
+	}
+}
+
+func TestLoadAndSave(t *testing.T) {
+	file := `[
+  {"name": "Lives", "address": "0x21FF", "value": 9, "mode": "freeze"},
+  {"name": "Score", "address": "$20F8", "value": 153, "mode": "patch"},
+  {"name": "Other", "address": 8192, "value": 1, "mode": "patch"}
+]`
+
+	l, err := Load(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cheats := l.Cheats()
+	if len(cheats) != 3 || cheats[0].Addr != 0x21FF || cheats[1].Addr != 0x20F8 || cheats[2].Addr != 0x2000 {
+		t.Errorf("Load did not read the cheats, got %+v", cheats)
+	}
+
+	var b bytes.Buffer
+	if err := l.Save(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(b.String(), `"address": "0x21FF"`) {
+		t.Errorf("Save did not write addresses in hex, got %s", b.String())
+	}
+
+	saved, err := Load(&b)
+	if err != nil || len(saved.Cheats()) != 3 || saved.Cheats()[1] != cheats[1] {
+		t.Errorf("Save did not write what Load reads, got %v", err)
+	}
+}
+
+func TestLoadRejectsInvalidCheats(t *testing.T) {
+	for _, file := range []string{
+		`[{"name": "Lives", "address": "0x21FF", "value": 9, "mode": "always"}]`,
+		`[{"name": "Lives", "address": "0x121FF", "value": 9, "mode": "freeze"}]`,
+		`{"name": "Lives"}`,
+	} {
+		if _, err := Load(strings.NewReader(file)); err == nil {
+			t.Errorf("Load did not reject %s", file)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package cheat
+
+import "fmt"
+
+// Condition narrows a search by comparing each candidate with its value when
+// the search was started or last narrowed.
+type Condition int
+
+const (
+	// Equal keeps the candidates holding the value searched for
+	Equal Condition = iota
+	Changed
+	Unchanged
+	Decreased
+	Increased
+)
+
+var conditionNames = map[string]Condition{
+	"equal":     Equal,
+	"changed":   Changed,
+	"unchanged": Unchanged,
+	"decreased": Decreased,
+	"increased": Increased,
+}
+
+func ParseCondition(s string) (Condition, error) {
+	c, ok := conditionNames[s]
+	if !ok {
+		return 0, fmt.Errorf("invalid condition %q, expected equal, changed, unchanged, decreased or increased", s)
+	}
+	return c, nil
+}
+
+func (c Condition) String() string {
+	for name, condition := range conditionNames {
+		if condition == c {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+func (c Condition) matches(previous, current, value byte) bool {
+	switch c {
+	case Equal:
+		return current == value
+	case Changed:
+		return current != previous
+	case Unchanged:
+		return current == previous
+	case Decreased:
+		return current < previous
+	case Increased:
+		return current > previous
+	}
+	return false
+}
+
+// Candidate is an address a search has not ruled out yet.
+type Candidate struct {
+	Addr  uint16 `json:"address"`
+	Value byte   `json:"value"`
+}
+
+// Search finds the address of a value, such as the lives counter, by
+// narrowing the candidates between frames as the value changes in game.
+type Search struct {
+	candidates []Candidate
+}
+
+// NewSearch starts a search over memory[start:end+1], memory being the
+// whole address space as returned by GetMemory.
+func NewSearch(memory []byte, start uint16, end uint16) *Search {
+	s := &Search{}
+	for addr := int(start); addr <= int(end); addr++ {
+		s.candidates = append(s.candidates, Candidate{Addr: uint16(addr), Value: memory[addr]})
+	}
+	return s
+}
+
+// Filter keeps the candidates matching c in memory. value is only used by
+// Equal.
+func (s *Search) Filter(memory []byte, c Condition, value byte) {
+	candidates := s.candidates[:0]
+	for _, candidate := range s.candidates {
+		current := memory[candidate.Addr]
+		if c.matches(candidate.Value, current, value) {
+			candidates = append(candidates, Candidate{Addr: candidate.Addr, Value: current})
+		}
+	}
+	s.candidates = candidates
+}
+
+func (s *Search) Count() int {
+	return len(s.candidates)
+}
+
+// Candidates returns up to limit candidates in address order, all of them if
+// limit is 0.
+func (s *Search) Candidates(limit int) []Candidate {
+	n := len(s.candidates)
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	return append([]Candidate{}, s.candidates[:n]...)
+}
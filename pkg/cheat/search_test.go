@@ -0,0 +1,75 @@
+package cheat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchFindsLivesCounter(t *testing.T) {
+	memory := make([]byte, 0x10000)
+	memory[0x21FF] = 3 // lives
+	memory[0x2100] = 3 // something else
+	memory[0x2200] = 9 // a timer going down
+
+	s := NewSearch(memory, 0x2000, 0x23FF)
+	if s.Count() != 0x400 {
+		t.Errorf("NewSearch did not start with the whole range, got %d candidates", s.Count())
+	}
+
+	s.Filter(memory, Equal, 3)
+	if s.Count() != 2 {
+		t.Errorf("Equal did not keep the candidates holding the value, got %d", s.Count())
+	}
+
+	memory[0x21FF] = 2
+	memory[0x2200] = 8
+	s.Filter(memory, Decreased, 0)
+
+	expected := []Candidate{{Addr: 0x21FF, Value: 2}}
+	if candidates := s.Candidates(0); !reflect.DeepEqual(candidates, expected) {
+		t.Errorf("Decreased did not narrow the search down, got %v", candidates)
+	}
+}
+
+func TestSearchConditions(t *testing.T) {
+	memory := []byte{5, 5, 5, 5}
+	s := NewSearch(memory, 0, 3)
+
+	memory[1], memory[2], memory[3] = 4, 6, 7
+	s.Filter(memory, Changed, 0)
+	if s.Count() != 3 {
+		t.Errorf("Changed did not drop the unchanged candidate")
+	}
+
+	memory[3] = 8
+	s.Filter(memory, Unchanged, 0)
+	if s.Count() != 2 {
+		t.Errorf("Unchanged did not compare with the last filter, got %d", s.Count())
+	}
+
+	s.Filter(memory, Increased, 0)
+	if candidates := s.Candidates(0); len(candidates) != 0 {
+		t.Errorf("Increased kept candidates that did not change, got %v", candidates)
+	}
+}
+
+func TestSearchCandidatesLimit(t *testing.T) {
+	s := NewSearch(make([]byte, 0x10000), 0, 0xFFFF)
+
+	if s.Count() != 0x10000 || len(s.Candidates(10)) != 10 {
+		t.Errorf("Candidates did not apply the limit")
+	}
+}
+
+func TestParseCondition(t *testing.T) {
+	for _, name := range []string{"equal", "changed", "unchanged", "decreased", "increased"} {
+		c, err := ParseCondition(name)
+		if err != nil || c.String() != name {
+			t.Errorf("ParseCondition did not parse %q", name)
+		}
+	}
+
+	if _, err := ParseCondition("bigger"); err == nil {
+		t.Errorf("ParseCondition did not reject an unknown condition")
+	}
+}
@@ -10,6 +10,10 @@
  * P - Parity Flag
  * 1 - Not used, always one
  * C - Carry Flag
+ *
+ * The 8085 also uses two of the unused bits (undocumented):
+ * bit 5 - K (also called X5 or UI), Underflow Indicator Flag
+ * bit 1 - V, Overflow Flag
  */
 
 package cpu
@@ -22,6 +26,10 @@ const (
 	AuxCarry Flag = 1 << 4
 	Parity   Flag = 1 << 2
 	Carry    Flag = 1 << 0
+
+	// 8085 only
+	UnderflowIndicator Flag = 1 << 5
+	Overflow           Flag = 1 << 1
 )
 
 type intel8080Flags struct {
@@ -0,0 +1,110 @@
+package cpu
+
+// Hooks let debuggers, profilers and other tools observe the CPU as it runs.
+// Any of the functions can be nil.
+//
+// Execute hooks are called for instructions fetched by Run, not for those an
+// interrupting device places on the bus. Memory hooks see every access made
+// by instructions, opcode and operand fetches included, but not host access
+// through ReadFromMemory, WriteIntoMemory, GetMemory or save states.
+type Hooks struct {
+	// BeforeExecute is called with the address and opcode of the instruction
+	// about to run.
+	BeforeExecute func(pc uint16, opcode byte)
+	// AfterExecute is called with the cycles the instruction took.
+	AfterExecute func(cycles uint)
+	MemoryRead   func(addr uint16, value byte)
+	// MemoryWrite is called with the value being replaced and the value
+	// written, before the write reaches memory. Memory may still ignore it,
+	// e.g. when addr is in ROM.
+	MemoryWrite func(addr uint16, oldValue byte, newValue byte)
+	// PortRead and PortWrite are called by IN and OUT once the value has
+	// been transferred.
+	PortRead  func(port byte, value byte)
+	PortWrite func(port byte, value byte)
+}
+
+// AddHooks registers h. Hooks are called in the order they were added.
+func (cpu *Intel8080) AddHooks(h *Hooks) {
+	cpu.hooks = append(cpu.hooks, h)
+	cpu.updateHooks()
+}
+
+// RemoveHooks unregisters h, which must be the pointer given to AddHooks.
+func (cpu *Intel8080) RemoveHooks(h *Hooks) {
+	for i, hooks := range cpu.hooks {
+		if hooks == h {
+			cpu.hooks = append(cpu.hooks[:i:i], cpu.hooks[i+1:]...)
+			break
+		}
+	}
+	cpu.updateHooks()
+}
+
+// updateHooks caches which kinds of hooks are registered, so instructions
+// only pay for a boolean check when there are none.
+func (cpu *Intel8080) updateHooks() {
+	cpu.hasBeforeExecute = false
+	cpu.hasAfterExecute = false
+	cpu.hasMemoryRead = false
+	cpu.hasMemoryWrite = false
+	cpu.hasPortRead = false
+	cpu.hasPortWrite = false
+
+	for _, h := range cpu.hooks {
+		cpu.hasBeforeExecute = cpu.hasBeforeExecute || h.BeforeExecute != nil
+		cpu.hasAfterExecute = cpu.hasAfterExecute || h.AfterExecute != nil
+		cpu.hasMemoryRead = cpu.hasMemoryRead || h.MemoryRead != nil
+		cpu.hasMemoryWrite = cpu.hasMemoryWrite || h.MemoryWrite != nil
+		cpu.hasPortRead = cpu.hasPortRead || h.PortRead != nil
+		cpu.hasPortWrite = cpu.hasPortWrite || h.PortWrite != nil
+	}
+}
+
+func (cpu *Intel8080) beforeExecute(pc uint16, opcode byte) {
+	for _, h := range cpu.hooks {
+		if h.BeforeExecute != nil {
+			h.BeforeExecute(pc, opcode)
+		}
+	}
+}
+
+func (cpu *Intel8080) afterExecute(cycles uint) {
+	for _, h := range cpu.hooks {
+		if h.AfterExecute != nil {
+			h.AfterExecute(cycles)
+		}
+	}
+}
+
+func (cpu *Intel8080) memoryRead(addr uint16, value byte) {
+	for _, h := range cpu.hooks {
+		if h.MemoryRead != nil {
+			h.MemoryRead(addr, value)
+		}
+	}
+}
+
+func (cpu *Intel8080) memoryWrite(addr uint16, oldValue byte, newValue byte) {
+	for _, h := range cpu.hooks {
+		if h.MemoryWrite != nil {
+			h.MemoryWrite(addr, oldValue, newValue)
+		}
+	}
+}
+
+func (cpu *Intel8080) portRead(port byte, value byte) {
+	for _, h := range cpu.hooks {
+		if h.PortRead != nil {
+			h.PortRead(port, value)
+		}
+	}
+}
+
+func (cpu *Intel8080) portWrite(port byte, value byte) {
+	for _, h := range cpu.hooks {
+		if h.PortWrite != nil {
+			h.PortWrite(port, value)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package cpu
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestExecuteHooks(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x00, 0x3e, 0x42, 0x76})
+
+	var calls []string
+	cpu.AddHooks(&Hooks{
+		BeforeExecute: func(pc uint16, opcode byte) {
+			calls = append(calls, fmt.Sprintf("before %04x %02x", pc, opcode))
+		},
+		AfterExecute: func(cycles uint) {
+			calls = append(calls, fmt.Sprintf("after %d", cycles))
+		},
+	})
+
+	for i := 0; i < 4; i++ {
+		cpu.Run()
+	}
+
+	expected := []string{
+		"before 0000 00", "after 4",
+		"before 0001 3e", "after 7",
+		"before 0003 76", "after 7",
+	}
+
+	if fmt.Sprint(calls) != fmt.Sprint(expected) {
+		t.Errorf("execute hooks were not called correctly, got %v", calls)
+	}
+}
+
+func TestMemoryHooks(t *testing.T) {
+	// LDA $0010; STA $0011
+	cpu := createCPUWithProgramLoaded([]byte{0x3a, 0x10, 0x00, 0x32, 0x11, 0x00, 0x10: 0x42, 0x24})
+
+	var reads, writes []string
+	cpu.AddHooks(&Hooks{
+		MemoryRead: func(addr uint16, value byte) {
+			reads = append(reads, fmt.Sprintf("%04x=%02x", addr, value))
+		},
+		MemoryWrite: func(addr uint16, oldValue byte, newValue byte) {
+			writes = append(writes, fmt.Sprintf("%04x:%02x->%02x", addr, oldValue, newValue))
+		},
+	})
+
+	cpu.Run()
+	cpu.Run()
+
+	expectedReads := []string{"0000=3a", "0001=10", "0002=00", "0010=42", "0003=32", "0004=11", "0005=00"}
+	if fmt.Sprint(reads) != fmt.Sprint(expectedReads) {
+		t.Errorf("memory read hook was not called correctly, got %v", reads)
+	}
+
+	if fmt.Sprint(writes) != "[0011:24->42]" {
+		t.Errorf("memory write hook was not called correctly, got %v", writes)
+	}
+}
+
+func TestHostAccessDoesNotCallHooks(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x00})
+
+	called := false
+	cpu.AddHooks(&Hooks{
+		MemoryRead:  func(addr uint16, value byte) { called = true },
+		MemoryWrite: func(addr uint16, oldValue byte, newValue byte) { called = true },
+	})
+
+	cpu.WriteIntoMemory(0x10, 0x42)
+	cpu.ReadFromMemory(0x10)
+	cpu.GetMemory()
+
+	if called {
+		t.Errorf("host memory access called the memory hooks")
+	}
+}
+
+func TestRemoveHooks(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x00, 0x00, 0x00})
+
+	first, second := 0, 0
+	h1 := &Hooks{AfterExecute: func(cycles uint) { first++ }}
+	h2 := &Hooks{AfterExecute: func(cycles uint) { second++ }}
+
+	cpu.AddHooks(h1)
+	cpu.AddHooks(h2)
+	cpu.Run()
+
+	cpu.RemoveHooks(h1)
+	cpu.Run()
+
+	cpu.RemoveHooks(h2)
+	cpu.Run()
+
+	if first != 1 || second != 2 {
+		t.Errorf("RemoveHooks did not unregister the hooks")
+	}
+
+	if cpu.hasAfterExecute {
+		t.Errorf("RemoveHooks did not turn the fast path back on")
+	}
+}
+
+func TestPortHooks(t *testing.T) {
+	// IN $07; MVI A, $42; OUT $03
+	cpu := createCPUWithProgramLoaded([]byte{0xdb, 0x07, 0x3e, 0x42, 0xd3, 0x03})
+
+	var calls []string
+	cpu.AddHooks(&Hooks{
+		PortRead: func(port byte, value byte) {
+			calls = append(calls, fmt.Sprintf("in %02x=%02x", port, value))
+		},
+		PortWrite: func(port byte, value byte) {
+			calls = append(calls, fmt.Sprintf("out %02x=%02x", port, value))
+		},
+	})
+
+	for i := 0; i < 3; i++ {
+		cpu.Run()
+	}
+
+	if fmt.Sprint(calls) != "[in 07=07 out 03=42]" {
+		t.Errorf("port hooks were not called correctly, got %v", calls)
+	}
+}
@@ -2,6 +2,8 @@ package cpu
 
 import (
 	"math/bits"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/opcode"
 )
 
 type Intel8080Instruction struct {
@@ -10,6 +12,9 @@ type Intel8080Instruction struct {
 	Size      uint16
 }
 
+// Cycles reported by Run for each step spent in the halted state
+const haltCycles = 4
+
 type IOBus interface {
 	Read(b byte) byte
 	Write(byte, byte)
@@ -30,11 +35,30 @@ type Intel8080 struct {
 	pc     uint16
 	cycles uint
 
-	memory       [0xFFFF]byte
+	memory       Memory
 	instructions [256]*Intel8080Instruction
+	// the instruction set instructions were built from
+	opcodes *[256]opcode.Opcode
 
 	InterruptEnabled        bool
 	enableInterruptDeferred bool
+	halted                  bool
+
+	// instruction placed on the data bus by an interrupting device
+	busInstruction []byte
+
+	// nil unless running as an Intel 8085
+	i8085 *intel8085State
+
+	tracer *Tracer
+
+	hooks            []*Hooks
+	hasBeforeExecute bool
+	hasAfterExecute  bool
+	hasMemoryRead    bool
+	hasMemoryWrite   bool
+	hasPortRead      bool
+	hasPortWrite     bool
 
 	// listeners
 	onInput  func(cpu *Intel8080)
@@ -44,314 +68,347 @@ type Intel8080 struct {
 }
 
 func NewIntel8080(bus IOBus) *Intel8080 {
+	return NewIntel8080WithMemory(bus, NewRAM())
+}
+
+func NewIntel8080WithMemory(bus IOBus, mem Memory) *Intel8080 {
 	cpu := &Intel8080{
-		flags: &intel8080Flags{},
-		ioBus: bus,
-	}
-
-	cpu.instructions = [256]*Intel8080Instruction{
-		0x00: {cpu._NOP, "NOP", 1},
-		0x01: {cpu._LXI_B, "LXI B", 3},
-		0x02: {cpu._STAX_B, "STAX B", 1},
-		0x03: {cpu._INX_B, "INX B", 1},
-		0x04: {cpu._INR_B, "INR B", 1},
-		0x05: {cpu._DCR_B, "DCR B", 1},
-		0x06: {cpu._MVI_B, "MVI B", 2},
-		0x07: {cpu._RLC, "RLC", 1},
-		0x08: {cpu._NOP, "*NOP", 1},
-		0x09: {cpu._DAD_B, "DAD B", 1},
-		0x0a: {cpu._LDAX_B, "LDAX B", 1},
-		0x0b: {cpu._DCX_B, "DCX B", 1},
-		0x0c: {cpu._INR_C, "INR C", 1},
-		0x0d: {cpu._DCR_C, "DCR C", 1},
-		0x0e: {cpu._MVI_C, "MVI C", 2},
-		0x0f: {cpu._RRC, "RRC", 1},
-
-		0x10: {cpu._NOP, "*NOP", 1},
-		0x11: {cpu._LXI_D, "LXI D", 3},
-		0x12: {cpu._STAX_D, "STAX D", 1},
-		0x13: {cpu._INX_D, "INX D", 1},
-		0x14: {cpu._INR_D, "INR D", 1},
-		0x15: {cpu._DCR_D, "DCR D", 1},
-		0x16: {cpu._MVI_D, "MVI D", 2},
-		0x17: {cpu._RAL, "RAL", 1},
-		0x18: {cpu._NOP, "*NOP", 1},
-		0x19: {cpu._DAD_D, "DAD D", 1},
-		0x1a: {cpu._LDAX_D, "LDAX D", 1},
-		0x1b: {cpu._DCX_D, "DCX D", 1},
-		0x1c: {cpu._INR_E, "INR E", 1},
-		0x1d: {cpu._DCR_E, "DCR E", 1},
-		0x1e: {cpu._MVI_E, "MVI E", 2},
-		0x1f: {cpu._RAR, "RAR", 1},
-
-		0x20: {cpu._NOP, "*NOP", 1},
-		0x21: {cpu._LXI_H, "LXI H", 3},
-		0x22: {cpu._SHLD, "SHLD", 3},
-		0x23: {cpu._INX_H, "INX H", 1},
-		0x24: {cpu._INR_H, "INR H", 1},
-		0x25: {cpu._DCR_H, "DCR H", 1},
-		0x26: {cpu._MVI_H, "MVI H", 2},
-		0x27: {cpu._DAA, "DAA", 1},
-		0x28: {cpu._NOP, "*NOP", 1},
-		0x29: {cpu._DAD_H, "DAD H", 1},
-		0x2a: {cpu._LHLD, "LHLD", 3},
-		0x2b: {cpu._DCX_H, "DCX H", 1},
-		0x2c: {cpu._INR_L, "INR L", 1},
-		0x2d: {cpu._DCR_L, "DCR L", 1},
-		0x2e: {cpu._MVI_L, "MVI L", 2},
-		0x2f: {cpu._CMA, "CMA", 1},
-
-		0x30: {cpu._NOP, "*NOP", 1},
-		0x31: {cpu._LXI_SP, "LXI SP", 3},
-		0x32: {cpu._STA, "STA", 3},
-		0x33: {cpu._INX_SP, "INX SP", 1},
-		0x34: {cpu._INR_M, "INR M", 1},
-		0x35: {cpu._DCR_M, "DCR M", 1},
-		0x36: {cpu._MVI_M, "MVI M", 2},
-		0x37: {cpu._STC, "STC", 1},
-		0x38: {cpu._NOP, "*NOP", 1},
-		0x39: {cpu._DAD_SP, "DAD SP", 1},
-		0x3a: {cpu._LDA, "LDA", 3},
-		0x3b: {cpu._DCX_SP, "DCX SP", 1},
-		0x3c: {cpu._INR_A, "INR A", 1},
-		0x3d: {cpu._DCR_A, "DCR A", 1},
-		0x3e: {cpu._MVI_A, "MVI A", 2},
-		0x3f: {cpu._CMC, "CMC", 1},
-
-		0x40: {cpu._MOV_BB, "MOV B,B", 1},
-		0x41: {cpu._MOV_BC, "MOV B,C", 1},
-		0x42: {cpu._MOV_BD, "MOV B,D", 1},
-		0x43: {cpu._MOV_BE, "MOV B,E", 1},
-		0x44: {cpu._MOV_BH, "MOV B,H", 1},
-		0x45: {cpu._MOV_BL, "MOV B,L", 1},
-		0x46: {cpu._MOV_BM, "MOV B,M", 1},
-		0x47: {cpu._MOV_BA, "MOV B,A", 1},
-		0x48: {cpu._MOV_CB, "MOV C,B", 1},
-		0x49: {cpu._MOV_CC, "MOV C,C", 1},
-		0x4a: {cpu._MOV_CD, "MOV C,D", 1},
-		0x4b: {cpu._MOV_CE, "MOV C,E", 1},
-		0x4c: {cpu._MOV_CH, "MOV C,H", 1},
-		0x4d: {cpu._MOV_CL, "MOV C,L", 1},
-		0x4e: {cpu._MOV_CM, "MOV C,M", 1},
-		0x4f: {cpu._MOV_CA, "MOV C,A", 1},
-
-		0x50: {cpu._MOV_DB, "MOV D,B", 1},
-		0x51: {cpu._MOV_DC, "MOV D,C", 1},
-		0x52: {cpu._MOV_DD, "MOV D,D", 1},
-		0x53: {cpu._MOV_DE, "MOV D,E", 1},
-		0x54: {cpu._MOV_DH, "MOV D,H", 1},
-		0x55: {cpu._MOV_DL, "MOV D,L", 1},
-		0x56: {cpu._MOV_DM, "MOV D,M", 1},
-		0x57: {cpu._MOV_DA, "MOV D,A", 1},
-		0x58: {cpu._MOV_EB, "MOV E,B", 1},
-		0x59: {cpu._MOV_EC, "MOV E,C", 1},
-		0x5a: {cpu._MOV_ED, "MOV E,D", 1},
-		0x5b: {cpu._MOV_EE, "MOV E,E", 1},
-		0x5c: {cpu._MOV_EH, "MOV E,H", 1},
-		0x5d: {cpu._MOV_EL, "MOV E,L", 1},
-		0x5e: {cpu._MOV_EM, "MOV E,M", 1},
-		0x5f: {cpu._MOV_EA, "MOV E,A", 1},
-
-		0x60: {cpu._MOV_HB, "MOV H,B", 1},
-		0x61: {cpu._MOV_HC, "MOV H,C", 1},
-		0x62: {cpu._MOV_HD, "MOV H,D", 1},
-		0x63: {cpu._MOV_HE, "MOV H,E", 1},
-		0x64: {cpu._MOV_HH, "MOV H,H", 1},
-		0x65: {cpu._MOV_HL, "MOV H,L", 1},
-		0x66: {cpu._MOV_HM, "MOV H,M", 1},
-		0x67: {cpu._MOV_HA, "MOV H,A", 1},
-		0x68: {cpu._MOV_LB, "MOV L,B", 1},
-		0x69: {cpu._MOV_LC, "MOV L,C", 1},
-		0x6a: {cpu._MOV_LD, "MOV L,D", 1},
-		0x6b: {cpu._MOV_LE, "MOV L,E", 1},
-		0x6c: {cpu._MOV_LH, "MOV L,H", 1},
-		0x6d: {cpu._MOV_LL, "MOV L,L", 1},
-		0x6e: {cpu._MOV_LM, "MOV L,M", 1},
-		0x6f: {cpu._MOV_LA, "MOV L,A", 1},
-
-		0x70: {cpu._MOV_MB, "MOV M,B", 1},
-		0x71: {cpu._MOV_MC, "MOV M,C", 1},
-		0x72: {cpu._MOV_MD, "MOV M,D", 1},
-		0x73: {cpu._MOV_ME, "MOV M,E", 1},
-		0x74: {cpu._MOV_MH, "MOV M,H", 1},
-		0x75: {cpu._MOV_ML, "MOV M,L", 1},
-		0x76: {cpu._HLT, "HLT", 1},
-		0x77: {cpu._MOV_MA, "MOV M,A", 1},
-		0x78: {cpu._MOV_AB, "MOV A,B", 1},
-		0x79: {cpu._MOV_AC, "MOV A,C", 1},
-		0x7a: {cpu._MOV_AD, "MOV A,D", 1},
-		0x7b: {cpu._MOV_AE, "MOV A,E", 1},
-		0x7c: {cpu._MOV_AH, "MOV A,H", 1},
-		0x7d: {cpu._MOV_AL, "MOV A,L", 1},
-		0x7e: {cpu._MOV_AM, "MOV A,M", 1},
-		0x7f: {cpu._MOV_AA, "MOV A,A", 1},
-
-		0x80: {cpu._ADD_B, "ADD B", 1},
-		0x81: {cpu._ADD_C, "ADD C", 1},
-		0x82: {cpu._ADD_D, "ADD D", 1},
-		0x83: {cpu._ADD_E, "ADD E", 1},
-		0x84: {cpu._ADD_H, "ADD H", 1},
-		0x85: {cpu._ADD_L, "ADD L", 1},
-		0x86: {cpu._ADD_M, "ADD M", 1},
-		0x87: {cpu._ADD_A, "ADD A", 1},
-		0x88: {cpu._ADC_B, "ADC B", 1},
-		0x89: {cpu._ADC_C, "ADC C", 1},
-		0x8a: {cpu._ADC_D, "ADC D", 1},
-		0x8b: {cpu._ADC_E, "ADC E", 1},
-		0x8c: {cpu._ADC_H, "ADC H", 1},
-		0x8d: {cpu._ADC_L, "ADC L", 1},
-		0x8e: {cpu._ADC_M, "ADC M", 1},
-		0x8f: {cpu._ADC_A, "ADC A", 1},
-
-		0x90: {cpu._SUB_B, "SUB B", 1},
-		0x91: {cpu._SUB_C, "SUB C", 1},
-		0x92: {cpu._SUB_D, "SUB D", 1},
-		0x93: {cpu._SUB_E, "SUB E", 1},
-		0x94: {cpu._SUB_H, "SUB H", 1},
-		0x95: {cpu._SUB_L, "SUB L", 1},
-		0x96: {cpu._SUB_M, "SUB M", 1},
-		0x97: {cpu._SUB_A, "SUB A", 1},
-		0x98: {cpu._SBB_B, "SBB B", 1},
-		0x99: {cpu._SBB_C, "SBB C", 1},
-		0x9a: {cpu._SBB_D, "SBB D", 1},
-		0x9b: {cpu._SBB_E, "SBB E", 1},
-		0x9c: {cpu._SBB_H, "SBB H", 1},
-		0x9d: {cpu._SBB_L, "SBB L", 1},
-		0x9e: {cpu._SBB_M, "SBB M", 1},
-		0x9f: {cpu._SBB_A, "SBB A", 1},
-
-		0xa0: {cpu._ANA_B, "ANA B", 1},
-		0xa1: {cpu._ANA_C, "ANA C", 1},
-		0xa2: {cpu._ANA_D, "ANA D", 1},
-		0xa3: {cpu._ANA_E, "ANA E", 1},
-		0xa4: {cpu._ANA_H, "ANA H", 1},
-		0xa5: {cpu._ANA_L, "ANA L", 1},
-		0xa6: {cpu._ANA_M, "ANA M", 1},
-		0xa7: {cpu._ANA_A, "ANA A", 1},
-		0xa8: {cpu._XRA_B, "XRA B", 1},
-		0xa9: {cpu._XRA_C, "XRA C", 1},
-		0xaa: {cpu._XRA_D, "XRA D", 1},
-		0xab: {cpu._XRA_E, "XRA E", 1},
-		0xac: {cpu._XRA_H, "XRA H", 1},
-		0xad: {cpu._XRA_L, "XRA L", 1},
-		0xae: {cpu._XRA_M, "XRA M", 1},
-		0xaf: {cpu._XRA_A, "XRA A", 1},
-
-		0xb0: {cpu._ORA_B, "ORA B", 1},
-		0xb1: {cpu._ORA_C, "ORA C", 1},
-		0xb2: {cpu._ORA_D, "ORA D", 1},
-		0xb3: {cpu._ORA_E, "ORA E", 1},
-		0xb4: {cpu._ORA_H, "ORA H", 1},
-		0xb5: {cpu._ORA_L, "ORA L", 1},
-		0xb6: {cpu._ORA_M, "ORA M", 1},
-		0xb7: {cpu._ORA_A, "ORA A", 1},
-		0xb8: {cpu._CMP_B, "CMP B", 1},
-		0xb9: {cpu._CMP_C, "CMP C", 1},
-		0xba: {cpu._CMP_D, "CMP D", 1},
-		0xbb: {cpu._CMP_E, "CMP E", 1},
-		0xbc: {cpu._CMP_H, "CMP H", 1},
-		0xbd: {cpu._CMP_L, "CMP L", 1},
-		0xbe: {cpu._CMP_M, "CMP M", 1},
-		0xbf: {cpu._CMP_A, "CMP A", 1},
-
-		0xc0: {cpu._RNZ, "RNZ", 1},
-		0xc1: {cpu._POP_B, "POP B", 1},
-		0xc2: {cpu._JNZ, "JNZ addr", 3},
-		0xc3: {cpu._JMP, "JMP addr", 3},
-		0xc4: {cpu._CNZ, "CNZ", 3},
-		0xc5: {cpu._PUSH_B, "PUSH B", 1},
-		0xc6: {cpu._ADI, "ADI", 2},
-		0xc7: {cpu._RST_0, "RST 0", 1},
-		0xc8: {cpu._RZ, "RZ", 1},
-		0xc9: {cpu._RET, "RET", 1},
-		0xca: {cpu._JZ, "JZ", 1},
-		0xcb: {cpu._JMP, "*JMP", 3},
-		0xcc: {cpu._CZ, "CZ", 3},
-		0xcd: {cpu._CALL, "CALL addr", 3},
-		0xce: {cpu._ACI, "ACI", 2},
-		0xcf: {cpu._RST_1, "RST 1", 1},
-
-		0xd0: {cpu._RNC, "RNC", 1},
-		0xd1: {cpu._POP_D, "POP D", 1},
-		0xd2: {cpu._JNC, "JNC", 3},
-		0xd3: {cpu._OUT, "OUT", 2},
-		0xd4: {cpu._CNC, "CNC", 3},
-		0xd5: {cpu._PUSH_D, "PUSH D", 1},
-		0xd6: {cpu._SUI, "SUI", 2},
-		0xd7: {cpu._RST_2, "RST 2", 1},
-		0xd8: {cpu._RC, "RC", 1},
-		0xd9: {cpu._RET, "*RET", 1},
-		0xda: {cpu._JC, "JC", 3},
-		0xdb: {cpu._IN, "IN", 2},
-		0xdc: {cpu._CC, "CC", 3},
-		0xdd: {cpu._CALL, "*CALL", 3},
-		0xde: {cpu._SBI, "SBI", 2},
-		0xdf: {cpu._RST_3, "RST 3", 1},
-
-		0xe0: {cpu._RPO, "RPO", 1},
-		0xe1: {cpu._POP_H, "POP H", 1},
-		0xe2: {cpu._JPO, "JPO", 3},
-		0xe3: {cpu._XTHL, "XTHL", 1},
-		0xe4: {cpu._CPO, "CPO", 3},
-		0xe5: {cpu._PUSH_H, "PUSH H", 1},
-		0xe6: {cpu._ANI, "ANI", 2},
-		0xe7: {cpu._RST_4, "RST 4", 1},
-		0xe8: {cpu._RPE, "RPE", 1},
-		0xe9: {cpu._PCHL, "PCHL", 1},
-		0xea: {cpu._JPE, "JPE", 3},
-		0xeb: {cpu._XCHG, "XCHG", 1},
-		0xec: {cpu._CPE, "CPE", 3},
-		0xed: {cpu._CALL, "*CALL", 3},
-		0xee: {cpu._XRI, "XRI", 2},
-		0xef: {cpu._RST_5, "RST 5", 1},
-
-		0xf0: {cpu._RP, "RP", 1},
-		0xf1: {cpu._POP_PSW, "POP PSW", 1},
-		0xf2: {cpu._JP, "JP", 3},
-		0xf3: {cpu._DI, "DI", 1},
-		0xf4: {cpu._CP, "CP", 3},
-		0xf5: {cpu._PUSH_PSW, "PUSH PSW", 1},
-		0xf6: {cpu._ORI, "ORI", 2},
-		0xf7: {cpu._RST_6, "RST 6", 1},
-		0xf8: {cpu._RM, "RM", 1},
-		0xf9: {cpu._SPHL, "SPHL", 1},
-		0xfa: {cpu._JM, "JM", 3},
-		0xfb: {cpu._EI, "EI", 1},
-		0xfc: {cpu._CM, "CM", 3},
-		0xfd: {cpu._CALL, "*CALL", 3},
-		0xfe: {cpu._CPI, "CPI", 2},
-		0xff: {cpu._RST_7, "RST 7", 1},
+		flags:  &intel8080Flags{},
+		memory: mem,
+		ioBus:  bus,
 	}
 
+	operations := [256]func() uint{
+		0x00: cpu._NOP,
+		0x01: cpu._LXI_B,
+		0x02: cpu._STAX_B,
+		0x03: cpu._INX_B,
+		0x04: cpu._INR_B,
+		0x05: cpu._DCR_B,
+		0x06: cpu._MVI_B,
+		0x07: cpu._RLC,
+		0x08: cpu._NOP,
+		0x09: cpu._DAD_B,
+		0x0a: cpu._LDAX_B,
+		0x0b: cpu._DCX_B,
+		0x0c: cpu._INR_C,
+		0x0d: cpu._DCR_C,
+		0x0e: cpu._MVI_C,
+		0x0f: cpu._RRC,
+
+		0x10: cpu._NOP,
+		0x11: cpu._LXI_D,
+		0x12: cpu._STAX_D,
+		0x13: cpu._INX_D,
+		0x14: cpu._INR_D,
+		0x15: cpu._DCR_D,
+		0x16: cpu._MVI_D,
+		0x17: cpu._RAL,
+		0x18: cpu._NOP,
+		0x19: cpu._DAD_D,
+		0x1a: cpu._LDAX_D,
+		0x1b: cpu._DCX_D,
+		0x1c: cpu._INR_E,
+		0x1d: cpu._DCR_E,
+		0x1e: cpu._MVI_E,
+		0x1f: cpu._RAR,
+
+		0x20: cpu._NOP,
+		0x21: cpu._LXI_H,
+		0x22: cpu._SHLD,
+		0x23: cpu._INX_H,
+		0x24: cpu._INR_H,
+		0x25: cpu._DCR_H,
+		0x26: cpu._MVI_H,
+		0x27: cpu._DAA,
+		0x28: cpu._NOP,
+		0x29: cpu._DAD_H,
+		0x2a: cpu._LHLD,
+		0x2b: cpu._DCX_H,
+		0x2c: cpu._INR_L,
+		0x2d: cpu._DCR_L,
+		0x2e: cpu._MVI_L,
+		0x2f: cpu._CMA,
+
+		0x30: cpu._NOP,
+		0x31: cpu._LXI_SP,
+		0x32: cpu._STA,
+		0x33: cpu._INX_SP,
+		0x34: cpu._INR_M,
+		0x35: cpu._DCR_M,
+		0x36: cpu._MVI_M,
+		0x37: cpu._STC,
+		0x38: cpu._NOP,
+		0x39: cpu._DAD_SP,
+		0x3a: cpu._LDA,
+		0x3b: cpu._DCX_SP,
+		0x3c: cpu._INR_A,
+		0x3d: cpu._DCR_A,
+		0x3e: cpu._MVI_A,
+		0x3f: cpu._CMC,
+
+		0x40: cpu._MOV_BB,
+		0x41: cpu._MOV_BC,
+		0x42: cpu._MOV_BD,
+		0x43: cpu._MOV_BE,
+		0x44: cpu._MOV_BH,
+		0x45: cpu._MOV_BL,
+		0x46: cpu._MOV_BM,
+		0x47: cpu._MOV_BA,
+		0x48: cpu._MOV_CB,
+		0x49: cpu._MOV_CC,
+		0x4a: cpu._MOV_CD,
+		0x4b: cpu._MOV_CE,
+		0x4c: cpu._MOV_CH,
+		0x4d: cpu._MOV_CL,
+		0x4e: cpu._MOV_CM,
+		0x4f: cpu._MOV_CA,
+
+		0x50: cpu._MOV_DB,
+		0x51: cpu._MOV_DC,
+		0x52: cpu._MOV_DD,
+		0x53: cpu._MOV_DE,
+		0x54: cpu._MOV_DH,
+		0x55: cpu._MOV_DL,
+		0x56: cpu._MOV_DM,
+		0x57: cpu._MOV_DA,
+		0x58: cpu._MOV_EB,
+		0x59: cpu._MOV_EC,
+		0x5a: cpu._MOV_ED,
+		0x5b: cpu._MOV_EE,
+		0x5c: cpu._MOV_EH,
+		0x5d: cpu._MOV_EL,
+		0x5e: cpu._MOV_EM,
+		0x5f: cpu._MOV_EA,
+
+		0x60: cpu._MOV_HB,
+		0x61: cpu._MOV_HC,
+		0x62: cpu._MOV_HD,
+		0x63: cpu._MOV_HE,
+		0x64: cpu._MOV_HH,
+		0x65: cpu._MOV_HL,
+		0x66: cpu._MOV_HM,
+		0x67: cpu._MOV_HA,
+		0x68: cpu._MOV_LB,
+		0x69: cpu._MOV_LC,
+		0x6a: cpu._MOV_LD,
+		0x6b: cpu._MOV_LE,
+		0x6c: cpu._MOV_LH,
+		0x6d: cpu._MOV_LL,
+		0x6e: cpu._MOV_LM,
+		0x6f: cpu._MOV_LA,
+
+		0x70: cpu._MOV_MB,
+		0x71: cpu._MOV_MC,
+		0x72: cpu._MOV_MD,
+		0x73: cpu._MOV_ME,
+		0x74: cpu._MOV_MH,
+		0x75: cpu._MOV_ML,
+		0x76: cpu._HLT,
+		0x77: cpu._MOV_MA,
+		0x78: cpu._MOV_AB,
+		0x79: cpu._MOV_AC,
+		0x7a: cpu._MOV_AD,
+		0x7b: cpu._MOV_AE,
+		0x7c: cpu._MOV_AH,
+		0x7d: cpu._MOV_AL,
+		0x7e: cpu._MOV_AM,
+		0x7f: cpu._MOV_AA,
+
+		0x80: cpu._ADD_B,
+		0x81: cpu._ADD_C,
+		0x82: cpu._ADD_D,
+		0x83: cpu._ADD_E,
+		0x84: cpu._ADD_H,
+		0x85: cpu._ADD_L,
+		0x86: cpu._ADD_M,
+		0x87: cpu._ADD_A,
+		0x88: cpu._ADC_B,
+		0x89: cpu._ADC_C,
+		0x8a: cpu._ADC_D,
+		0x8b: cpu._ADC_E,
+		0x8c: cpu._ADC_H,
+		0x8d: cpu._ADC_L,
+		0x8e: cpu._ADC_M,
+		0x8f: cpu._ADC_A,
+
+		0x90: cpu._SUB_B,
+		0x91: cpu._SUB_C,
+		0x92: cpu._SUB_D,
+		0x93: cpu._SUB_E,
+		0x94: cpu._SUB_H,
+		0x95: cpu._SUB_L,
+		0x96: cpu._SUB_M,
+		0x97: cpu._SUB_A,
+		0x98: cpu._SBB_B,
+		0x99: cpu._SBB_C,
+		0x9a: cpu._SBB_D,
+		0x9b: cpu._SBB_E,
+		0x9c: cpu._SBB_H,
+		0x9d: cpu._SBB_L,
+		0x9e: cpu._SBB_M,
+		0x9f: cpu._SBB_A,
+
+		0xa0: cpu._ANA_B,
+		0xa1: cpu._ANA_C,
+		0xa2: cpu._ANA_D,
+		0xa3: cpu._ANA_E,
+		0xa4: cpu._ANA_H,
+		0xa5: cpu._ANA_L,
+		0xa6: cpu._ANA_M,
+		0xa7: cpu._ANA_A,
+		0xa8: cpu._XRA_B,
+		0xa9: cpu._XRA_C,
+		0xaa: cpu._XRA_D,
+		0xab: cpu._XRA_E,
+		0xac: cpu._XRA_H,
+		0xad: cpu._XRA_L,
+		0xae: cpu._XRA_M,
+		0xaf: cpu._XRA_A,
+
+		0xb0: cpu._ORA_B,
+		0xb1: cpu._ORA_C,
+		0xb2: cpu._ORA_D,
+		0xb3: cpu._ORA_E,
+		0xb4: cpu._ORA_H,
+		0xb5: cpu._ORA_L,
+		0xb6: cpu._ORA_M,
+		0xb7: cpu._ORA_A,
+		0xb8: cpu._CMP_B,
+		0xb9: cpu._CMP_C,
+		0xba: cpu._CMP_D,
+		0xbb: cpu._CMP_E,
+		0xbc: cpu._CMP_H,
+		0xbd: cpu._CMP_L,
+		0xbe: cpu._CMP_M,
+		0xbf: cpu._CMP_A,
+
+		0xc0: cpu._RNZ,
+		0xc1: cpu._POP_B,
+		0xc2: cpu._JNZ,
+		0xc3: cpu._JMP,
+		0xc4: cpu._CNZ,
+		0xc5: cpu._PUSH_B,
+		0xc6: cpu._ADI,
+		0xc7: cpu._RST_0,
+		0xc8: cpu._RZ,
+		0xc9: cpu._RET,
+		0xca: cpu._JZ,
+		0xcb: cpu._JMP,
+		0xcc: cpu._CZ,
+		0xcd: cpu._CALL,
+		0xce: cpu._ACI,
+		0xcf: cpu._RST_1,
+
+		0xd0: cpu._RNC,
+		0xd1: cpu._POP_D,
+		0xd2: cpu._JNC,
+		0xd3: cpu._OUT,
+		0xd4: cpu._CNC,
+		0xd5: cpu._PUSH_D,
+		0xd6: cpu._SUI,
+		0xd7: cpu._RST_2,
+		0xd8: cpu._RC,
+		0xd9: cpu._RET,
+		0xda: cpu._JC,
+		0xdb: cpu._IN,
+		0xdc: cpu._CC,
+		0xdd: cpu._CALL,
+		0xde: cpu._SBI,
+		0xdf: cpu._RST_3,
+
+		0xe0: cpu._RPO,
+		0xe1: cpu._POP_H,
+		0xe2: cpu._JPO,
+		0xe3: cpu._XTHL,
+		0xe4: cpu._CPO,
+		0xe5: cpu._PUSH_H,
+		0xe6: cpu._ANI,
+		0xe7: cpu._RST_4,
+		0xe8: cpu._RPE,
+		0xe9: cpu._PCHL,
+		0xea: cpu._JPE,
+		0xeb: cpu._XCHG,
+		0xec: cpu._CPE,
+		0xed: cpu._CALL,
+		0xee: cpu._XRI,
+		0xef: cpu._RST_5,
+
+		0xf0: cpu._RP,
+		0xf1: cpu._POP_PSW,
+		0xf2: cpu._JP,
+		0xf3: cpu._DI,
+		0xf4: cpu._CP,
+		0xf5: cpu._PUSH_PSW,
+		0xf6: cpu._ORI,
+		0xf7: cpu._RST_6,
+		0xf8: cpu._RM,
+		0xf9: cpu._SPHL,
+		0xfa: cpu._JM,
+		0xfb: cpu._EI,
+		0xfc: cpu._CM,
+		0xfd: cpu._CALL,
+		0xfe: cpu._CPI,
+		0xff: cpu._RST_7,
+	}
+
+	cpu.setInstructions(&opcode.Intel8080, operations)
+
 	return cpu
 }
 
+// setInstructions pairs each operation with the mnemonic and size of its
+// opcode in table.
+func (cpu *Intel8080) setInstructions(table *[256]opcode.Opcode, operations [256]func() uint) {
+	for op, operation := range operations {
+		cpu.instructions[op] = &Intel8080Instruction{operation, table[op].Name(), table[op].Size}
+	}
+	cpu.opcodes = table
+}
+
+// Opcodes returns the instruction set the CPU runs, opcode.Intel8080 or
+// opcode.Intel8085.
+func (cpu *Intel8080) Opcodes() *[256]opcode.Opcode {
+	return cpu.opcodes
+}
+
+// GetMemory returns a copy of the whole 64 KiB address space as seen by the CPU
 func (cpu *Intel8080) GetMemory() []byte {
-	return cpu.memory[:]
+	return cpu.readRange(0x0000, 0x10000)
 }
 
 func (cpu *Intel8080) LoadProgram(program []byte, offset int) {
-	for i, b := range program {
-		cpu.memory[i+offset] = b
-	}
+	cpu.memory.Load(program, uint16(offset))
 }
 
 func (cpu *Intel8080) GetVRAM() []byte {
-	start := 0x2400
-	size := 0x1C00
-	return cpu.memory[start : start+size]
+	return cpu.readRange(0x2400, 0x1C00)
+}
+
+func (cpu *Intel8080) GetPC() uint16 {
+	return cpu.pc
 }
 
 func (cpu *Intel8080) SetPC(value uint16) {
 	cpu.pc = value
 }
 
+func (cpu *Intel8080) GetSP() uint16 {
+	return cpu.sp
+}
+
+// InstructionAt returns the instruction whose opcode is at addr, as the CPU
+// would decode it.
+func (cpu *Intel8080) InstructionAt(addr uint16) Intel8080Instruction {
+	return *cpu.instructions[cpu.memory.Read(addr)]
+}
+
 func (cpu *Intel8080) WriteIntoMemory(addr uint16, b byte) {
-	cpu.memory[addr] = b
+	cpu.memory.Write(addr, b)
 }
 
 func (cpu *Intel8080) ReadFromMemory(addr uint16) byte {
-	return cpu.memory[addr]
+	return cpu.memory.Read(addr)
 }
 
 func (cpu *Intel8080) SetInputListener(listener func(cpu *Intel8080)) {
@@ -374,6 +431,49 @@ func (cpu *Intel8080) GetRegisters() map[string]byte {
 	}
 }
 
+// SetRegisters sets the registers present in values, using the names
+// returned by GetRegisters.
+func (cpu *Intel8080) SetRegisters(values map[string]byte) {
+	registers := map[string]*byte{
+		"A": &cpu.a,
+		"B": &cpu.b,
+		"C": &cpu.c,
+		"D": &cpu.d,
+		"E": &cpu.e,
+		"H": &cpu.h,
+		"L": &cpu.l,
+	}
+
+	for name, value := range values {
+		if r, ok := registers[name]; ok {
+			*r = value
+		}
+	}
+}
+
+// GetFlags returns the flags byte as PUSH PSW stores it.
+func (cpu *Intel8080) GetFlags() byte {
+	return cpu.psw()
+}
+
+// SetFlags sets the flags from a byte laid out as PUSH PSW stores it.
+func (cpu *Intel8080) SetFlags(psw byte) {
+	if cpu.i8085 != nil {
+		cpu.flags.value = psw
+		return
+	}
+
+	cpu.flags.Set(Sign, (psw>>7&0b1) > 0)
+	cpu.flags.Set(Zero, (psw>>6&0b1) > 0)
+	cpu.flags.Set(AuxCarry, (psw>>4&0b1) > 0)
+	cpu.flags.Set(Parity, (psw>>2&0b1) > 0)
+	cpu.flags.Set(Carry, (psw>>0&0b1) > 0)
+}
+
+func (cpu *Intel8080) SetSP(value uint16) {
+	cpu.sp = value
+}
+
 func (cpu *Intel8080) GetPointers() map[string]uint16 {
 	return map[string]uint16{
 		"pc": cpu.pc,
@@ -381,8 +481,78 @@ func (cpu *Intel8080) GetPointers() map[string]uint16 {
 	}
 }
 
+// GetCycles returns the number of cycles executed so far.
+func (cpu *Intel8080) GetCycles() uint {
+	return cpu.cycles
+}
+
+// GetInterruptState reports whether interrupts are enabled, and whether an EI
+// has yet to take effect after the next instruction.
+func (cpu *Intel8080) GetInterruptState() (enabled bool, enablePending bool) {
+	return cpu.InterruptEnabled, cpu.enableInterruptDeferred
+}
+
+// SetInterruptState restores what GetInterruptState returns.
+func (cpu *Intel8080) SetInterruptState(enabled bool, enablePending bool) {
+	cpu.InterruptEnabled = enabled
+	cpu.enableInterruptDeferred = enablePending
+}
+
+// SetCycles sets the number of cycles executed so far.
+func (cpu *Intel8080) SetCycles(cycles uint) {
+	cpu.cycles = cycles
+}
+
+// Reset does what the RESET IN pin does: PC goes back to $0000, interrupts
+// are disabled and the CPU leaves HLT. Registers, flags, SP and memory are
+// left as they are. The 8085 also masks RST 5.5-7.5 and clears SOD.
+func (cpu *Intel8080) Reset() {
+	cpu.pc = 0
+	cpu.InterruptEnabled = false
+	cpu.enableInterruptDeferred = false
+	cpu.halted = false
+
+	if cpu.i8085 != nil {
+		*cpu.i8085 = intel8085State{
+			masks: maskRST55 | maskRST65 | maskRST75,
+			pins:  cpu.i8085.pins,
+			sid:   cpu.i8085.sid,
+		}
+	}
+}
+
+// IsHalted reports whether the CPU is stopped on a HLT instruction, waiting
+// for an interrupt.
+func (cpu *Intel8080) IsHalted() bool {
+	return cpu.halted
+}
+
+func (cpu *Intel8080) SetHalted(halted bool) {
+	cpu.halted = halted
+}
+
 func (cpu *Intel8080) Run() uint {
-	opcode := cpu.memory[cpu.pc]
+	if cpu.i8085 != nil {
+		if cycles := cpu.service8085Interrupts(); cycles > 0 {
+			cpu.cycles += cycles
+			return cycles
+		}
+	}
+
+	if cpu.halted {
+		// No instruction is fetched, the CPU just idles until an interrupt
+		cpu.cycles += haltCycles
+		return haltCycles
+	}
+
+	if cpu.tracer != nil {
+		cpu.tracer.trace(cpu)
+	}
+
+	opcode := cpu.readByte(cpu.pc)
+	if cpu.hasBeforeExecute {
+		cpu.beforeExecute(cpu.pc, opcode)
+	}
 	cpu.pc++
 
 	if cpu.enableInterruptDeferred {
@@ -395,13 +565,81 @@ func (cpu *Intel8080) Run() uint {
 
 	cpu.cycles += cycles
 
+	if cpu.hasAfterExecute {
+		cpu.afterExecute(cycles)
+	}
+
 	return cycles
 }
 
-func (cpu *Intel8080) Interrupt(interruptType int) {
-	cpu.push(byte(cpu.pc>>8), byte(cpu.pc&0xFF))
-	cpu.pc = uint16(interruptType) * 8
+// Interrupt requests an interrupt with RST interruptType on the data bus. See
+// InterruptWithInstruction.
+func (cpu *Intel8080) Interrupt(interruptType int) uint {
+	return cpu.InterruptWithInstruction(0xC7 | byte(interruptType&0x7)<<3)
+}
+
+// InterruptWithInstruction acknowledges an interrupt request by executing the
+// instruction the interrupting device places on the data bus, usually an RST
+// or a CALL with its operands. The request is ignored while interrupts are
+// disabled, including the instruction following EI. It returns the cycles
+// spent, or 0 if the interrupt was not accepted.
+func (cpu *Intel8080) InterruptWithInstruction(instruction ...byte) uint {
+	if len(instruction) == 0 || !cpu.InterruptEnabled || cpu.enableInterruptDeferred {
+		return 0
+	}
+
 	cpu.InterruptEnabled = false
+	// PC already points past a HLT, so that is where the handler returns to
+	cpu.halted = false
+
+	// Operands come from the bus instead of memory. PC is moved back by their
+	// size, so instructions stepping over their operands leave it where the
+	// program was interrupted.
+	cpu.busInstruction = instruction
+	cpu.pc -= uint16(len(instruction) - 1)
+
+	cycles := cpu.instructions[instruction[0]].operation()
+
+	cpu.busInstruction = nil
+	cpu.cycles += cycles
+
+	return cycles
+}
+
+func (cpu *Intel8080) readByte(addr uint16) byte {
+	value := cpu.memory.Read(addr)
+	if cpu.hasMemoryRead {
+		cpu.memoryRead(addr, value)
+	}
+	return value
+}
+
+func (cpu *Intel8080) writeByte(addr uint16, value byte) {
+	if cpu.hasMemoryWrite {
+		cpu.memoryWrite(addr, cpu.memory.Read(addr), value)
+	}
+	cpu.memory.Write(addr, value)
+}
+
+// operand returns the n-th byte following the opcode being executed
+func (cpu *Intel8080) operand(n uint16) byte {
+	if cpu.busInstruction != nil {
+		if int(n)+1 < len(cpu.busInstruction) {
+			return cpu.busInstruction[n+1]
+		}
+		// Nothing drives the bus, it floats high
+		return 0xFF
+	}
+
+	return cpu.readByte(cpu.pc + n)
+}
+
+func (cpu *Intel8080) readRange(start int, size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = cpu.memory.Read(uint16(start + i))
+	}
+	return data
 }
 
 func hasParity(b byte) bool {
@@ -414,6 +652,10 @@ func (cpu *Intel8080) add(value byte, carry uint16) {
 	cpu.flags.Set(Carry, result > 0xFF)
 	cpu.flags.Set(AuxCarry, ((cpu.a^uint8(result)^value)&0x10) > 0)
 
+	if cpu.i8085 != nil {
+		cpu.set8085Overflow((cpu.a^uint8(result))&(value^uint8(result))&0x80 != 0, uint8(result))
+	}
+
 	cpu.a = uint8(result & 0xFF)
 
 	cpu.flags.Set(Zero, cpu.a == 0)
@@ -436,6 +678,10 @@ func (cpu *Intel8080) sub(value byte, carry uint16) {
 	cpu.flags.Set(Carry, result>>8 > 0)
 	cpu.flags.Set(AuxCarry, ((cpu.a^uint8(result)^value)&0x10) > 0)
 
+	if cpu.i8085 != nil {
+		cpu.set8085Overflow((cpu.a^value)&(cpu.a^uint8(result))&0x80 != 0, uint8(result))
+	}
+
 	cpu.a = uint8(result & 0xFF)
 
 	cpu.flags.Set(Zero, cpu.a == 0)
@@ -455,7 +701,8 @@ func (cpu *Intel8080) sbb(value byte) {
 func (cpu *Intel8080) ana(value byte) {
 	result := cpu.a & value
 
-	cpu.flags.Set(AuxCarry, ((cpu.a|value)&0x08) != 0)
+	// The 8085 always sets AC on logical AND
+	cpu.flags.Set(AuxCarry, ((cpu.a|value)&0x08) != 0 || cpu.i8085 != nil)
 	cpu.flags.Set(Carry, false)
 
 	cpu.a = result
@@ -491,52 +738,56 @@ func (cpu *Intel8080) cmp(value byte) {
 	cpu.flags.Set(Carry, result>>8 > 0)
 	cpu.flags.Set(AuxCarry, ((cpu.a^uint8(result)^value)&0x10) > 0)
 
+	if cpu.i8085 != nil {
+		cpu.set8085Overflow((cpu.a^value)&(cpu.a^uint8(result))&0x80 != 0, uint8(result))
+	}
+
 	cpu.flags.Set(Zero, uint8(result) == 0)
 	cpu.flags.Set(Sign, uint8(result)&0x80 != 0)
 	cpu.flags.Set(Parity, hasParity(uint8(result)))
 }
 
 func (cpu *Intel8080) ret() {
-	lb, hb := uint16(cpu.memory[cpu.sp]), uint16(cpu.memory[cpu.sp+1])
+	lb, hb := uint16(cpu.readByte(cpu.sp)), uint16(cpu.readByte(cpu.sp+1))
 	cpu.sp += 2
 	cpu.pc = (hb << 8) | lb
 }
 
 func (cpu *Intel8080) pop() (hb byte, lb byte) {
-	lob, hib := cpu.memory[cpu.sp], cpu.memory[cpu.sp+1]
+	lob, hib := cpu.readByte(cpu.sp), cpu.readByte(cpu.sp+1)
 	cpu.sp += 2
 
 	return hib, lob
 }
 
 func (cpu *Intel8080) push(hb byte, lb byte) {
-	cpu.memory[cpu.sp-1] = hb
-	cpu.memory[cpu.sp-2] = lb
+	cpu.writeByte(cpu.sp-1, hb)
+	cpu.writeByte(cpu.sp-2, lb)
 	cpu.sp -= 2
 }
 
 func (cpu *Intel8080) call() {
-	lb, hb := uint16(cpu.memory[cpu.pc]), uint16(cpu.memory[cpu.pc+1])
+	lb, hb := uint16(cpu.operand(0)), uint16(cpu.operand(1))
 
 	ret := cpu.pc + 2
-	cpu.memory[cpu.sp-1] = uint8((ret >> 8) & 0xff)
-	cpu.memory[cpu.sp-2] = uint8(ret & 0xff)
+	cpu.writeByte(cpu.sp-1, uint8((ret>>8)&0xff))
+	cpu.writeByte(cpu.sp-2, uint8(ret&0xff))
 	cpu.sp -= 2
 
 	cpu.pc = (hb << 8) | lb
 }
 
 func (cpu *Intel8080) jump() {
-	lb := uint16(cpu.memory[cpu.pc])
-	hb := uint16(cpu.memory[cpu.pc+1])
+	lb := uint16(cpu.operand(0))
+	hb := uint16(cpu.operand(1))
 
 	cpu.pc = (hb << 8) | lb
 }
 
 func (cpu *Intel8080) rst(addr uint16) {
 	ret := cpu.pc
-	cpu.memory[cpu.sp-1] = uint8((ret >> 8) & 0xFF)
-	cpu.memory[cpu.sp-2] = uint8(ret & 0xFF)
+	cpu.writeByte(cpu.sp-1, uint8((ret>>8)&0xFF))
+	cpu.writeByte(cpu.sp-2, uint8(ret&0xFF))
 	cpu.sp -= 2
 
 	cpu.pc = addr
@@ -548,8 +799,8 @@ func (cpu *Intel8080) _NOP() uint {
 }
 
 func (cpu *Intel8080) _LXI_B() uint {
-	cpu.c = cpu.memory[cpu.pc]
-	cpu.b = cpu.memory[cpu.pc+1]
+	cpu.c = cpu.operand(0)
+	cpu.b = cpu.operand(1)
 	cpu.pc += 2
 
 	return 10
@@ -557,7 +808,7 @@ func (cpu *Intel8080) _LXI_B() uint {
 
 func (cpu *Intel8080) _STAX_B() uint {
 	addr := uint16(cpu.b)<<8 | uint16(cpu.c)
-	cpu.memory[addr] = cpu.a
+	cpu.writeByte(addr, cpu.a)
 
 	return 7
 }
@@ -594,7 +845,7 @@ func (cpu *Intel8080) _DCR_B() uint {
 }
 
 func (cpu *Intel8080) _MVI_B() uint {
-	cpu.b = cpu.memory[cpu.pc]
+	cpu.b = cpu.operand(0)
 	cpu.pc++
 
 	return 7
@@ -624,7 +875,7 @@ func (cpu *Intel8080) _DAD_B() uint {
 
 func (cpu *Intel8080) _LDAX_B() uint {
 	bc := (uint16(cpu.b) << 8) | uint16(cpu.c)
-	cpu.a = cpu.memory[bc]
+	cpu.a = cpu.readByte(bc)
 
 	return 7
 }
@@ -660,7 +911,7 @@ func (cpu *Intel8080) _DCR_C() uint {
 }
 
 func (cpu *Intel8080) _MVI_C() uint {
-	cpu.c = cpu.memory[cpu.pc]
+	cpu.c = cpu.operand(0)
 	cpu.pc++
 
 	return 7
@@ -675,8 +926,8 @@ func (cpu *Intel8080) _RRC() uint {
 }
 
 func (cpu *Intel8080) _LXI_D() uint {
-	cpu.e = cpu.memory[cpu.pc]
-	cpu.d = cpu.memory[cpu.pc+1]
+	cpu.e = cpu.operand(0)
+	cpu.d = cpu.operand(1)
 	cpu.pc += 2
 
 	return 10
@@ -684,7 +935,7 @@ func (cpu *Intel8080) _LXI_D() uint {
 
 func (cpu *Intel8080) _STAX_D() uint {
 	addr := uint16(cpu.d)<<8 | uint16(cpu.e)
-	cpu.memory[addr] = cpu.a
+	cpu.writeByte(addr, cpu.a)
 
 	return 7
 }
@@ -722,7 +973,7 @@ func (cpu *Intel8080) _DCR_D() uint {
 }
 
 func (cpu *Intel8080) _MVI_D() uint {
-	cpu.d = cpu.memory[cpu.pc]
+	cpu.d = cpu.operand(0)
 	cpu.pc++
 
 	return 7
@@ -757,7 +1008,7 @@ func (cpu *Intel8080) _DAD_D() uint {
 
 func (cpu *Intel8080) _LDAX_D() uint {
 	de := uint16(cpu.d)<<8 | uint16(cpu.e)
-	cpu.a = cpu.memory[de]
+	cpu.a = cpu.readByte(de)
 
 	return 7
 }
@@ -793,7 +1044,7 @@ func (cpu *Intel8080) _DCR_E() uint {
 }
 
 func (cpu *Intel8080) _MVI_E() uint {
-	cpu.e = cpu.memory[cpu.pc]
+	cpu.e = cpu.operand(0)
 	cpu.pc++
 
 	return 7
@@ -813,20 +1064,20 @@ func (cpu *Intel8080) _RAR() uint {
 }
 
 func (cpu *Intel8080) _LXI_H() uint {
-	cpu.l = cpu.memory[cpu.pc]
-	cpu.h = cpu.memory[cpu.pc+1]
+	cpu.l = cpu.operand(0)
+	cpu.h = cpu.operand(1)
 	cpu.pc += 2
 
 	return 10
 }
 
 func (cpu *Intel8080) _SHLD() uint {
-	lb := uint16(cpu.memory[cpu.pc])
-	hb := uint16(cpu.memory[cpu.pc+1])
+	lb := uint16(cpu.operand(0))
+	hb := uint16(cpu.operand(1))
 
 	addr := (hb << 8) | lb
-	cpu.memory[addr] = cpu.l
-	cpu.memory[addr+1] = cpu.h
+	cpu.writeByte(addr, cpu.l)
+	cpu.writeByte(addr+1, cpu.h)
 
 	cpu.pc += 2
 
@@ -866,7 +1117,7 @@ func (cpu *Intel8080) _DCR_H() uint {
 }
 
 func (cpu *Intel8080) _MVI_H() uint {
-	cpu.h = cpu.memory[cpu.pc]
+	cpu.h = cpu.operand(0)
 	cpu.pc++
 
 	return 7
@@ -913,13 +1164,13 @@ func (cpu *Intel8080) _DAD_H() uint {
 }
 
 func (cpu *Intel8080) _LHLD() uint {
-	lb := uint16(cpu.memory[cpu.pc])
-	hb := uint16(cpu.memory[cpu.pc+1])
+	lb := uint16(cpu.operand(0))
+	hb := uint16(cpu.operand(1))
 
 	addr := (hb << 8) | lb
 
-	cpu.l = cpu.memory[addr]
-	cpu.h = cpu.memory[addr+1]
+	cpu.l = cpu.readByte(addr)
+	cpu.h = cpu.readByte(addr + 1)
 
 	cpu.pc += 2
 
@@ -957,7 +1208,7 @@ func (cpu *Intel8080) _DCR_L() uint {
 }
 
 func (cpu *Intel8080) _MVI_L() uint {
-	cpu.l = cpu.memory[cpu.pc]
+	cpu.l = cpu.operand(0)
 	cpu.pc++
 
 	return 7
@@ -970,8 +1221,8 @@ func (cpu *Intel8080) _CMA() uint {
 }
 
 func (cpu *Intel8080) _LXI_SP() uint {
-	lb := uint16(cpu.memory[cpu.pc])
-	hb := uint16(cpu.memory[cpu.pc+1])
+	lb := uint16(cpu.operand(0))
+	hb := uint16(cpu.operand(1))
 
 	cpu.sp = (hb << 8) | lb
 
@@ -981,11 +1232,11 @@ func (cpu *Intel8080) _LXI_SP() uint {
 }
 
 func (cpu *Intel8080) _STA() uint {
-	lb := uint16(cpu.memory[cpu.pc])
-	hb := uint16(cpu.memory[cpu.pc+1])
+	lb := uint16(cpu.operand(0))
+	hb := uint16(cpu.operand(1))
 
 	addr := (hb << 8) | lb
-	cpu.memory[addr] = cpu.a
+	cpu.writeByte(addr, cpu.a)
 
 	cpu.pc += 2
 
@@ -1000,9 +1251,9 @@ func (cpu *Intel8080) _INX_SP() uint {
 
 func (cpu *Intel8080) _INR_M() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	value := cpu.memory[addr]
+	value := cpu.readByte(addr)
 	value++
-	cpu.memory[addr] = value
+	cpu.writeByte(addr, value)
 
 	cpu.flags.Set(Zero, value == 0)
 	cpu.flags.Set(Sign, value&0x80 != 0)
@@ -1014,9 +1265,9 @@ func (cpu *Intel8080) _INR_M() uint {
 
 func (cpu *Intel8080) _DCR_M() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	value := cpu.memory[addr]
+	value := cpu.readByte(addr)
 	value--
-	cpu.memory[addr] = value
+	cpu.writeByte(addr, value)
 
 	cpu.flags.Set(Zero, value == 0)
 	cpu.flags.Set(Sign, value&0x80 != 0)
@@ -1028,8 +1279,8 @@ func (cpu *Intel8080) _DCR_M() uint {
 
 func (cpu *Intel8080) _MVI_M() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	value := cpu.memory[cpu.pc]
-	cpu.memory[addr] = value
+	value := cpu.operand(0)
+	cpu.writeByte(addr, value)
 
 	cpu.pc++
 
@@ -1056,11 +1307,11 @@ func (cpu *Intel8080) _DAD_SP() uint {
 }
 
 func (cpu *Intel8080) _LDA() uint {
-	lb := uint16(cpu.memory[cpu.pc])
-	hb := uint16(cpu.memory[cpu.pc+1])
+	lb := uint16(cpu.operand(0))
+	hb := uint16(cpu.operand(1))
 
 	addr := hb<<8 | lb
-	cpu.a = cpu.memory[addr]
+	cpu.a = cpu.readByte(addr)
 
 	cpu.pc += 2
 
@@ -1096,7 +1347,7 @@ func (cpu *Intel8080) _DCR_A() uint {
 }
 
 func (cpu *Intel8080) _MVI_A() uint {
-	cpu.a = cpu.memory[cpu.pc]
+	cpu.a = cpu.operand(0)
 	cpu.pc++
 
 	return 7
@@ -1144,7 +1395,7 @@ func (cpu *Intel8080) _MOV_BL() uint {
 
 func (cpu *Intel8080) _MOV_BM() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.b = cpu.memory[addr]
+	cpu.b = cpu.readByte(addr)
 	return 7
 }
 
@@ -1185,7 +1436,7 @@ func (cpu *Intel8080) _MOV_CL() uint {
 
 func (cpu *Intel8080) _MOV_CM() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.c = cpu.memory[addr]
+	cpu.c = cpu.readByte(addr)
 	return 7
 }
 
@@ -1226,7 +1477,7 @@ func (cpu *Intel8080) _MOV_DL() uint {
 
 func (cpu *Intel8080) _MOV_DM() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.d = cpu.memory[addr]
+	cpu.d = cpu.readByte(addr)
 	return 7
 }
 
@@ -1267,7 +1518,7 @@ func (cpu *Intel8080) _MOV_EL() uint {
 
 func (cpu *Intel8080) _MOV_EM() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.e = cpu.memory[addr]
+	cpu.e = cpu.readByte(addr)
 	return 7
 }
 
@@ -1308,7 +1559,7 @@ func (cpu *Intel8080) _MOV_HL() uint {
 
 func (cpu *Intel8080) _MOV_HM() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.h = cpu.memory[addr]
+	cpu.h = cpu.readByte(addr)
 	return 7
 }
 
@@ -1349,7 +1600,7 @@ func (cpu *Intel8080) _MOV_LL() uint {
 
 func (cpu *Intel8080) _MOV_LM() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.l = cpu.memory[addr]
+	cpu.l = cpu.readByte(addr)
 	return 7
 }
 
@@ -1360,48 +1611,48 @@ func (cpu *Intel8080) _MOV_LA() uint {
 
 func (cpu *Intel8080) _MOV_MB() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.memory[addr] = cpu.b
+	cpu.writeByte(addr, cpu.b)
 	return 7
 }
 
 func (cpu *Intel8080) _MOV_MC() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.memory[addr] = cpu.c
+	cpu.writeByte(addr, cpu.c)
 	return 7
 }
 
 func (cpu *Intel8080) _MOV_MD() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.memory[addr] = cpu.d
+	cpu.writeByte(addr, cpu.d)
 	return 7
 }
 
 func (cpu *Intel8080) _MOV_ME() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.memory[addr] = cpu.e
+	cpu.writeByte(addr, cpu.e)
 	return 7
 }
 
 func (cpu *Intel8080) _MOV_MH() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.memory[addr] = cpu.h
+	cpu.writeByte(addr, cpu.h)
 	return 7
 }
 
 func (cpu *Intel8080) _MOV_ML() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.memory[addr] = cpu.l
+	cpu.writeByte(addr, cpu.l)
 	return 7
 }
 
 func (cpu *Intel8080) _HLT() uint {
-	// Note: It starts an infinite loop here
+	cpu.halted = true
 	return 7
 }
 
 func (cpu *Intel8080) _MOV_MA() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.memory[addr] = cpu.a
+	cpu.writeByte(addr, cpu.a)
 	return 7
 }
 
@@ -1437,7 +1688,7 @@ func (cpu *Intel8080) _MOV_AL() uint {
 
 func (cpu *Intel8080) _MOV_AM() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.a = cpu.memory[addr]
+	cpu.a = cpu.readByte(addr)
 	return 7
 }
 
@@ -1478,7 +1729,7 @@ func (cpu *Intel8080) _ADD_L() uint {
 
 func (cpu *Intel8080) _ADD_M() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.add(cpu.memory[addr], 0)
+	cpu.add(cpu.readByte(addr), 0)
 	return 7
 }
 
@@ -1519,7 +1770,7 @@ func (cpu *Intel8080) _ADC_L() uint {
 
 func (cpu *Intel8080) _ADC_M() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.adc(cpu.memory[addr])
+	cpu.adc(cpu.readByte(addr))
 	return 7
 }
 
@@ -1560,7 +1811,7 @@ func (cpu *Intel8080) _SUB_L() uint {
 
 func (cpu *Intel8080) _SUB_M() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.sub(cpu.memory[addr], 0)
+	cpu.sub(cpu.readByte(addr), 0)
 	return 7
 }
 
@@ -1601,7 +1852,7 @@ func (cpu *Intel8080) _SBB_L() uint {
 
 func (cpu *Intel8080) _SBB_M() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.sbb(cpu.memory[addr])
+	cpu.sbb(cpu.readByte(addr))
 	return 7
 }
 
@@ -1642,7 +1893,7 @@ func (cpu *Intel8080) _ANA_L() uint {
 
 func (cpu *Intel8080) _ANA_M() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.ana(cpu.memory[addr])
+	cpu.ana(cpu.readByte(addr))
 	return 7
 }
 
@@ -1683,7 +1934,7 @@ func (cpu *Intel8080) _XRA_L() uint {
 
 func (cpu *Intel8080) _XRA_M() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.xra(cpu.memory[addr])
+	cpu.xra(cpu.readByte(addr))
 	return 7
 }
 
@@ -1724,7 +1975,7 @@ func (cpu *Intel8080) _ORA_L() uint {
 
 func (cpu *Intel8080) _ORA_M() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.ora(cpu.memory[addr])
+	cpu.ora(cpu.readByte(addr))
 	return 7
 }
 
@@ -1765,7 +2016,7 @@ func (cpu *Intel8080) _CMP_L() uint {
 
 func (cpu *Intel8080) _CMP_M() uint {
 	addr := uint16(cpu.h)<<8 | uint16(cpu.l)
-	cpu.cmp(cpu.memory[addr])
+	cpu.cmp(cpu.readByte(addr))
 	return 7
 }
 
@@ -1817,7 +2068,7 @@ func (cpu *Intel8080) _PUSH_B() uint {
 }
 
 func (cpu *Intel8080) _ADI() uint {
-	value := cpu.memory[cpu.pc]
+	value := cpu.operand(0)
 	cpu.pc++
 	cpu.add(value, 0)
 	return 7
@@ -1861,7 +2112,7 @@ func (cpu *Intel8080) _CZ() uint {
 }
 
 func (cpu *Intel8080) _ACI() uint {
-	value := cpu.memory[cpu.pc]
+	value := cpu.operand(0)
 	cpu.pc++
 	cpu.adc(value)
 	return 7
@@ -1905,7 +2156,12 @@ func (cpu *Intel8080) _OUT() uint {
 		cpu.onOutput(cpu)
 	}
 
-	cpu.ioBus.Write(cpu.memory[cpu.pc], cpu.a)
+	port := cpu.operand(0)
+	cpu.ioBus.Write(port, cpu.a)
+	if cpu.hasPortWrite {
+		cpu.portWrite(port, cpu.a)
+	}
+
 	cpu.pc++
 	return 10
 }
@@ -1925,7 +2181,7 @@ func (cpu *Intel8080) _PUSH_D() uint {
 }
 
 func (cpu *Intel8080) _SUI() uint {
-	value := cpu.memory[cpu.pc]
+	value := cpu.operand(0)
 	cpu.pc++
 	cpu.sub(value, 0)
 	return 7
@@ -1959,7 +2215,12 @@ func (cpu *Intel8080) _IN() uint {
 		cpu.onInput(cpu)
 	}
 
-	cpu.a = cpu.ioBus.Read(cpu.memory[cpu.pc])
+	port := cpu.operand(0)
+	cpu.a = cpu.ioBus.Read(port)
+	if cpu.hasPortRead {
+		cpu.portRead(port, cpu.a)
+	}
+
 	cpu.pc++
 	return 10
 }
@@ -1974,7 +2235,7 @@ func (cpu *Intel8080) _CC() uint {
 }
 
 func (cpu *Intel8080) _SBI() uint {
-	value := cpu.memory[cpu.pc]
+	value := cpu.operand(0)
 	cpu.pc++
 	cpu.sbb(value)
 	return 7
@@ -2009,10 +2270,10 @@ func (cpu *Intel8080) _JPO() uint {
 }
 
 func (cpu *Intel8080) _XTHL() uint {
-	stackLb := cpu.memory[cpu.sp]
-	stackHb := cpu.memory[cpu.sp+1]
-	cpu.memory[cpu.sp] = cpu.l
-	cpu.memory[cpu.sp+1] = cpu.h
+	stackLb := cpu.readByte(cpu.sp)
+	stackHb := cpu.readByte(cpu.sp + 1)
+	cpu.writeByte(cpu.sp, cpu.l)
+	cpu.writeByte(cpu.sp+1, cpu.h)
 	cpu.l = stackLb
 	cpu.h = stackHb
 	return 18
@@ -2033,7 +2294,7 @@ func (cpu *Intel8080) _PUSH_H() uint {
 }
 
 func (cpu *Intel8080) _ANI() uint {
-	value := cpu.memory[cpu.pc]
+	value := cpu.operand(0)
 	cpu.pc++
 	cpu.ana(value)
 	return 7
@@ -2087,7 +2348,7 @@ func (cpu *Intel8080) _CPE() uint {
 }
 
 func (cpu *Intel8080) _XRI() uint {
-	value := cpu.memory[cpu.pc]
+	value := cpu.operand(0)
 	cpu.pc++
 	cpu.xra(value)
 	return 7
@@ -2109,11 +2370,7 @@ func (cpu *Intel8080) _RP() uint {
 func (cpu *Intel8080) _POP_PSW() uint {
 	hb, psw := cpu.pop()
 	cpu.a = hb
-	cpu.flags.Set(Sign, (psw>>7&0b1) > 0)
-	cpu.flags.Set(Zero, (psw>>6&0b1) > 0)
-	cpu.flags.Set(AuxCarry, (psw>>4&0b1) > 0)
-	cpu.flags.Set(Parity, (psw>>2&0b1) > 0)
-	cpu.flags.Set(Carry, (psw>>0&0b1) > 0)
+	cpu.SetFlags(psw)
 	return 10
 }
 
@@ -2142,6 +2399,18 @@ func (cpu *Intel8080) _CP() uint {
 }
 
 func (cpu *Intel8080) _PUSH_PSW() uint {
+	cpu.push(cpu.a, cpu.psw())
+
+	return 11
+}
+
+// psw returns the flags byte as PUSH PSW stores it
+func (cpu *Intel8080) psw() byte {
+	if cpu.i8085 != nil {
+		// S, Z, K, AC, 0, P, V, CY
+		return cpu.flags.value &^ 0x08
+	}
+
 	// S, Z, 0, AC, 0, P, 1, CY
 	status := uint8(0b00000010)
 	if cpu.flags.Get(Sign) {
@@ -2160,13 +2429,11 @@ func (cpu *Intel8080) _PUSH_PSW() uint {
 		status |= 1 << 0
 	}
 
-	cpu.push(cpu.a, status)
-
-	return 11
+	return status
 }
 
 func (cpu *Intel8080) _ORI() uint {
-	value := cpu.memory[cpu.pc]
+	value := cpu.operand(0)
 	cpu.pc++
 	cpu.ora(value)
 	return 7
@@ -2216,7 +2483,7 @@ func (cpu *Intel8080) _CM() uint {
 }
 
 func (cpu *Intel8080) _CPI() uint {
-	value := cpu.memory[cpu.pc]
+	value := cpu.operand(0)
 	cpu.pc++
 	cpu.cmp(value)
 	return 7
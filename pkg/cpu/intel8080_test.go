@@ -36,12 +36,33 @@ func TestLoadProgram(t *testing.T) {
 	cpu := createCPUWithProgramLoaded(program)
 
 	for i, v := range program {
-		if cpu.memory[i] != v {
+		if cpu.memory.Read(uint16(i)) != v {
 			t.Errorf("LoadProgram did not load the program correctly")
 		}
 	}
 }
 
+func TestSetRegisters(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{})
+
+	cpu.SetRegisters(map[string]byte{"A": 0x01, "B": 0x02, "L": 0x07, "X": 0x08})
+	cpu.SetFlags(0xff)
+	cpu.SetSP(0x2400)
+
+	if cpu.a != 0x01 || cpu.b != 0x02 || cpu.c != 0x00 || cpu.l != 0x07 {
+		t.Errorf("SetRegisters did not set the registers correctly")
+	}
+
+	// Bits 3 and 5 always read as 0 and bit 1 as 1
+	if cpu.GetFlags() != 0xd7 {
+		t.Errorf("SetFlags did not set the flags correctly")
+	}
+
+	if cpu.sp != 0x2400 {
+		t.Errorf("SetSP did not set the stack pointer")
+	}
+}
+
 func Test_LXI_B(t *testing.T) {
 	cpu := createCPUWithProgramLoaded([]byte{0x01, 0x02, 0x03})
 
@@ -67,7 +88,7 @@ func Test_STAX_B(t *testing.T) {
 
 	cpu.Run()
 
-	if cpu.memory[0x0301] != 0x08 {
+	if cpu.memory.Read(0x0301) != 0x08 {
 		t.Errorf("STAX B did not store the program correctly")
 	}
 
@@ -360,7 +381,7 @@ func Test_STAX_D(t *testing.T) {
 
 	cpu.Run()
 
-	if cpu.memory[0x0301] != 0x08 {
+	if cpu.memory.Read(0x0301) != 0x08 {
 		t.Errorf("STAX D did not store the program correctly")
 	}
 
@@ -687,7 +708,7 @@ func Test_SHLD(t *testing.T) {
 
 	cpu.Run()
 
-	if cpu.memory[0x0003] != 0x55 || cpu.memory[0x0004] != 0x66 {
+	if cpu.memory.Read(0x0003) != 0x55 || cpu.memory.Read(0x0004) != 0x66 {
 		t.Errorf("SHLD did not write into memory correctly")
 	}
 
@@ -960,7 +981,7 @@ func Test_STA(t *testing.T) {
 
 	cpu.Run()
 
-	if cpu.memory[0x0003] != 0x99 {
+	if cpu.memory.Read(0x0003) != 0x99 {
 		t.Errorf("STA did not write A into memory correctly")
 	}
 
@@ -1004,7 +1025,7 @@ func Fuzz_INR_M(f *testing.F) {
 
 		cpu.Run()
 
-		if cpu.memory[0x0003] != d.value+1 {
+		if cpu.memory.Read(0x0003) != d.value+1 {
 			t.Errorf("INR M did not increment value in memory correctly")
 		}
 
@@ -1036,7 +1057,7 @@ func Fuzz_DCR_M(f *testing.F) {
 
 		cpu.Run()
 
-		if cpu.memory[0x0004] != d.value-1 {
+		if cpu.memory.Read(0x0004) != d.value-1 {
 			t.Errorf("INR M did not decrement value in memory correctly")
 		}
 
@@ -1055,7 +1076,7 @@ func Test_MVI_M(t *testing.T) {
 
 	cpu.Run()
 
-	if cpu.memory[0x01FF] != 0xED {
+	if cpu.memory.Read(0x01FF) != 0xED {
 		t.Errorf("MVI M did not store the correct value to memory")
 	}
 
@@ -1914,7 +1935,7 @@ func Test_MOV_MB(t *testing.T) {
 
 	cpu.Run()
 
-	if cpu.memory[0x2233] != cpu.b {
+	if cpu.memory.Read(0x2233) != cpu.b {
 		t.Errorf("MOV M,B did not move register correctly")
 	}
 
@@ -1929,7 +1950,7 @@ func Test_MOV_MC(t *testing.T) {
 
 	cpu.Run()
 
-	if cpu.memory[0x2233] != cpu.c {
+	if cpu.memory.Read(0x2233) != cpu.c {
 		t.Errorf("MOV M,C did not move register correctly")
 	}
 
@@ -1944,7 +1965,7 @@ func Test_MOV_MD(t *testing.T) {
 
 	cpu.Run()
 
-	if cpu.memory[0x2233] != cpu.d {
+	if cpu.memory.Read(0x2233) != cpu.d {
 		t.Errorf("MOV M,D did not move register correctly")
 	}
 
@@ -1959,7 +1980,7 @@ func Test_MOV_ME(t *testing.T) {
 
 	cpu.Run()
 
-	if cpu.memory[0x2233] != cpu.e {
+	if cpu.memory.Read(0x2233) != cpu.e {
 		t.Errorf("MOV M,E did not move register correctly")
 	}
 
@@ -1973,7 +1994,7 @@ func Test_MOV_MH(t *testing.T) {
 
 	cpu.Run()
 
-	if cpu.memory[0x2233] != cpu.h {
+	if cpu.memory.Read(0x2233) != cpu.h {
 		t.Errorf("MOV M,H did not move register correctly")
 	}
 
@@ -1987,13 +2008,38 @@ func Test_MOV_ML(t *testing.T) {
 
 	cpu.Run()
 
-	if cpu.memory[0x2233] != cpu.l {
+	if cpu.memory.Read(0x2233) != cpu.l {
 		t.Errorf("MOV M,L did not move register correctly")
 	}
 
 	assertCycles(t, cpu, 7)
 }
 
+func Test_HLT(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x76, 0x3c})
+
+	cpu.Run()
+
+	if !cpu.IsHalted() {
+		t.Errorf("HLT did not halt the CPU")
+	}
+
+	if cpu.pc != 1 {
+		t.Errorf("HLT did not increment PC correctly")
+	}
+
+	assertCycles(t, cpu, 7)
+
+	cpu.Run()
+	cpu.Run()
+
+	if cpu.pc != 1 || cpu.a != 0 {
+		t.Errorf("halted CPU kept executing instructions")
+	}
+
+	assertCycles(t, cpu, 7+2*haltCycles)
+}
+
 func Test_MOV_MA(t *testing.T) {
 	cpu := createCPUWithProgramLoaded([]byte{0x77, 0x01, 0x2233: 0x00})
 	cpu.h = 0x22
@@ -2002,7 +2048,7 @@ func Test_MOV_MA(t *testing.T) {
 
 	cpu.Run()
 
-	if cpu.memory[0x2233] != cpu.a {
+	if cpu.memory.Read(0x2233) != cpu.a {
 		t.Errorf("MOV M,A did not move register correctly")
 	}
 
@@ -4056,7 +4102,7 @@ func Test_CNZWithZeroUnset(t *testing.T) {
 		t.Errorf("CNZ did not set SP correctly when Zero flag was not set")
 	}
 
-	if cpu.memory[1] != 0x03 || cpu.memory[2] != 0x00 {
+	if cpu.memory.Read(1) != 0x03 || cpu.memory.Read(2) != 0x00 {
 		t.Errorf("CNZ did not store return address correctly")
 	}
 	assertCycles(t, cpu, 17)
@@ -4086,10 +4132,10 @@ func Test_PUSH_B(t *testing.T) {
 
 	cpu.Run()
 
-	if cpu.memory[2] != 0x34 {
+	if cpu.memory.Read(2) != 0x34 {
 		t.Errorf("PUSH B did not store C correctly")
 	}
-	if cpu.memory[3] != 0x12 {
+	if cpu.memory.Read(3) != 0x12 {
 		t.Errorf("PUSH B did not store B correctly")
 	}
 	if cpu.sp != 2 {
@@ -4141,7 +4187,7 @@ func Test_RST_0(t *testing.T) {
 		t.Errorf("RST 0 did not set PC to 0x0000, got 0x%04x", cpu.pc)
 	}
 
-	if cpu.memory[cpu.sp] != 0x34 || cpu.memory[cpu.sp+1] != 0x12 {
+	if cpu.memory.Read(cpu.sp) != 0x34 || cpu.memory.Read(cpu.sp+1) != 0x12 {
 		t.Errorf("RST 0 did not save return address correctly")
 	}
 
@@ -4243,7 +4289,7 @@ func Test_CZWithZeroSet(t *testing.T) {
 		t.Errorf("CZ did not set SP correctly when Zero flag was not set")
 	}
 
-	if cpu.memory[1] != 0x03 || cpu.memory[2] != 0x00 {
+	if cpu.memory.Read(1) != 0x03 || cpu.memory.Read(2) != 0x00 {
 		t.Errorf("CZ did not store return address correctly")
 	}
 	assertCycles(t, cpu, 17)
@@ -4279,7 +4325,7 @@ func Test_CALL(t *testing.T) {
 		t.Errorf("CALL dit not set PC correctly")
 	}
 
-	if cpu.memory[cpu.sp+1] != 0x00 || cpu.memory[cpu.sp] != 0x03 {
+	if cpu.memory.Read(cpu.sp+1) != 0x00 || cpu.memory.Read(cpu.sp) != 0x03 {
 		t.Errorf("CALL dit not write correctly to memory")
 	}
 
@@ -4330,7 +4376,7 @@ func Test_RST_1(t *testing.T) {
 		t.Errorf("RST 1 did not set PC to 0x0008, got 0x%04x", cpu.pc)
 	}
 
-	if cpu.memory[cpu.sp] != 0x34 || cpu.memory[cpu.sp+1] != 0x12 {
+	if cpu.memory.Read(cpu.sp) != 0x34 || cpu.memory.Read(cpu.sp+1) != 0x12 {
 		t.Errorf("RST 1 did not save return address correctly")
 	}
 
@@ -4441,7 +4487,7 @@ func Test_CNCWithCarryUnset(t *testing.T) {
 		t.Errorf("CNC did not set SP correctly when Carry flag was not set")
 	}
 
-	if cpu.memory[1] != 0x03 || cpu.memory[2] != 0x00 {
+	if cpu.memory.Read(1) != 0x03 || cpu.memory.Read(2) != 0x00 {
 		t.Errorf("CNC did not store return address correctly")
 	}
 	assertCycles(t, cpu, 17)
@@ -4471,10 +4517,10 @@ func Test_PUSH_D(t *testing.T) {
 
 	cpu.Run()
 
-	if cpu.memory[2] != 0x34 {
+	if cpu.memory.Read(2) != 0x34 {
 		t.Errorf("PUSH D did not store E correctly")
 	}
-	if cpu.memory[3] != 0x12 {
+	if cpu.memory.Read(3) != 0x12 {
 		t.Errorf("PUSH D did not store D correctly")
 	}
 	if cpu.sp != 2 {
@@ -4526,7 +4572,7 @@ func Test_RST_2(t *testing.T) {
 		t.Errorf("RST 2 did not set PC to 0x0010, got 0x%04x", cpu.pc)
 	}
 
-	if cpu.memory[cpu.sp] != 0x34 || cpu.memory[cpu.sp+1] != 0x12 {
+	if cpu.memory.Read(cpu.sp) != 0x34 || cpu.memory.Read(cpu.sp+1) != 0x12 {
 		t.Errorf("RST 2 did not save return address correctly")
 	}
 
@@ -4619,7 +4665,7 @@ func Test_CCWithCarrySet(t *testing.T) {
 		t.Errorf("CC did not set SP correctly when Carry flag was not set")
 	}
 
-	if cpu.memory[1] != 0x03 || cpu.memory[2] != 0x00 {
+	if cpu.memory.Read(1) != 0x03 || cpu.memory.Read(2) != 0x00 {
 		t.Errorf("CC did not store return address correctly")
 	}
 	assertCycles(t, cpu, 17)
@@ -4685,7 +4731,7 @@ func Test_RST_3(t *testing.T) {
 		t.Errorf("RST 3 did not set PC to 0x0018, got 0x%04x", cpu.pc)
 	}
 
-	if cpu.memory[cpu.sp] != 0x34 || cpu.memory[cpu.sp+1] != 0x12 {
+	if cpu.memory.Read(cpu.sp) != 0x34 || cpu.memory.Read(cpu.sp+1) != 0x12 {
 		t.Errorf("RST 3 did not save return address correctly")
 	}
 
@@ -4786,7 +4832,7 @@ func Test_XTHL(t *testing.T) {
 		t.Errorf("XTHL dit not set L register correctly")
 	}
 
-	if cpu.memory[cpu.sp] != 0x05 || cpu.memory[cpu.sp+1] != 0x99 {
+	if cpu.memory.Read(cpu.sp) != 0x05 || cpu.memory.Read(cpu.sp+1) != 0x99 {
 		t.Errorf("XTHL dit not write HL values into memory correctly")
 	}
 	assertCycles(t, cpu, 18)
@@ -4806,7 +4852,7 @@ func Test_CPOWithParityUnset(t *testing.T) {
 		t.Errorf("CPO did not set SP correctly when Parity flag was not set")
 	}
 
-	if cpu.memory[1] != 0x03 || cpu.memory[2] != 0x00 {
+	if cpu.memory.Read(1) != 0x03 || cpu.memory.Read(2) != 0x00 {
 		t.Errorf("CPO did not store return address correctly")
 	}
 	assertCycles(t, cpu, 17)
@@ -4836,10 +4882,10 @@ func Test_PUSH_H(t *testing.T) {
 
 	cpu.Run()
 
-	if cpu.memory[2] != 0x34 {
+	if cpu.memory.Read(2) != 0x34 {
 		t.Errorf("PUSH H did not store L correctly")
 	}
-	if cpu.memory[3] != 0x12 {
+	if cpu.memory.Read(3) != 0x12 {
 		t.Errorf("PUSH H did not store H correctly")
 	}
 	if cpu.sp != 2 {
@@ -4890,7 +4936,7 @@ func Test_RST_4(t *testing.T) {
 		t.Errorf("RST 4 did not set PC to 0x0020, got 0x%04x", cpu.pc)
 	}
 
-	if cpu.memory[cpu.sp] != 0x34 || cpu.memory[cpu.sp+1] != 0x12 {
+	if cpu.memory.Read(cpu.sp) != 0x34 || cpu.memory.Read(cpu.sp+1) != 0x12 {
 		t.Errorf("RST 4 did not save return address correctly")
 	}
 
@@ -5004,7 +5050,7 @@ func Test_CPEWithParitySet(t *testing.T) {
 		t.Errorf("CPE did not set SP correctly when Parity flag was not set")
 	}
 
-	if cpu.memory[1] != 0x03 || cpu.memory[2] != 0x00 {
+	if cpu.memory.Read(1) != 0x03 || cpu.memory.Read(2) != 0x00 {
 		t.Errorf("CPE did not store return address correctly")
 	}
 	assertCycles(t, cpu, 17)
@@ -5058,7 +5104,7 @@ func Test_RST_5(t *testing.T) {
 		t.Errorf("RST 5 did not set PC to 0x0028, got 0x%04x", cpu.pc)
 	}
 
-	if cpu.memory[cpu.sp] != 0x34 || cpu.memory[cpu.sp+1] != 0x12 {
+	if cpu.memory.Read(cpu.sp) != 0x34 || cpu.memory.Read(cpu.sp+1) != 0x12 {
 		t.Errorf("RST 5 did not save return address correctly")
 	}
 
@@ -5188,7 +5234,7 @@ func Test_CPWithSignUnset(t *testing.T) {
 		t.Errorf("CP did not set SP correctly when Sign flag was not set")
 	}
 
-	if cpu.memory[1] != 0x03 || cpu.memory[2] != 0x00 {
+	if cpu.memory.Read(1) != 0x03 || cpu.memory.Read(2) != 0x00 {
 		t.Errorf("CP did not store return address correctly")
 	}
 	assertCycles(t, cpu, 17)
@@ -5226,11 +5272,11 @@ func Test_PUSH_PSW(t *testing.T) {
 		t.Errorf("PUSH PSW did not set sp correctly")
 	}
 
-	if cpu.memory[2] != 0x5 {
+	if cpu.memory.Read(2) != 0x5 {
 		t.Errorf("PUSH PSW did not store A register correctly")
 	}
 
-	if cpu.memory[1] != 0xD7 {
+	if cpu.memory.Read(1) != 0xD7 {
 		t.Errorf("PUSH PSW did not store program status correctly")
 	}
 
@@ -5269,7 +5315,7 @@ func Test_RST_6(t *testing.T) {
 		t.Errorf("RST 6 did not set PC to 0x0030, got 0x%04x", cpu.pc)
 	}
 
-	if cpu.memory[cpu.sp] != 0x34 || cpu.memory[cpu.sp+1] != 0x12 {
+	if cpu.memory.Read(cpu.sp) != 0x34 || cpu.memory.Read(cpu.sp+1) != 0x12 {
 		t.Errorf("RST 6 did not save return address correctly")
 	}
 
@@ -5383,7 +5429,7 @@ func Test_CMWithSignSet(t *testing.T) {
 		t.Errorf("CM did not set SP correctly when Sign flag was not set")
 	}
 
-	if cpu.memory[1] != 0x03 || cpu.memory[2] != 0x00 {
+	if cpu.memory.Read(1) != 0x03 || cpu.memory.Read(2) != 0x00 {
 		t.Errorf("CM did not store return address correctly")
 	}
 	assertCycles(t, cpu, 17)
@@ -5448,7 +5494,7 @@ func Test_RST_7(t *testing.T) {
 		t.Errorf("RST 7 did not set PC to 0x0038, got 0x%04x", cpu.pc)
 	}
 
-	if cpu.memory[cpu.sp] != 0x34 || cpu.memory[cpu.sp+1] != 0x12 {
+	if cpu.memory.Read(cpu.sp) != 0x34 || cpu.memory.Read(cpu.sp+1) != 0x12 {
 		t.Errorf("RST 7 did not save return address correctly")
 	}
 
@@ -5458,3 +5504,155 @@ func Test_RST_7(t *testing.T) {
 
 	assertCycles(t, cpu, 11)
 }
+
+func TestInterruptWithRST(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x1233: 0x00})
+	cpu.sp = 0x04
+	cpu.pc = 0x1234
+	cpu.InterruptEnabled = true
+
+	cycles := cpu.InterruptWithInstruction(0xd7) // RST 2
+
+	if cpu.pc != 0x0010 {
+		t.Errorf("Interrupt did not set PC to 0x0010, got 0x%04x", cpu.pc)
+	}
+
+	if cpu.memory.Read(cpu.sp) != 0x34 || cpu.memory.Read(cpu.sp+1) != 0x12 {
+		t.Errorf("Interrupt did not save return address correctly")
+	}
+
+	if cpu.InterruptEnabled {
+		t.Errorf("Interrupt did not disable interrupts")
+	}
+
+	if cycles != 11 {
+		t.Errorf("Interrupt returned %d cycles, expected 11", cycles)
+	}
+
+	assertCycles(t, cpu, 11)
+}
+
+func TestInterruptWithCALL(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x1233: 0x00})
+	cpu.sp = 0x04
+	cpu.pc = 0x1234
+	cpu.InterruptEnabled = true
+
+	cycles := cpu.InterruptWithInstruction(0xcd, 0x00, 0x20) // CALL $2000
+
+	if cpu.pc != 0x2000 {
+		t.Errorf("Interrupt did not set PC to 0x2000, got 0x%04x", cpu.pc)
+	}
+
+	if cpu.memory.Read(cpu.sp) != 0x34 || cpu.memory.Read(cpu.sp+1) != 0x12 {
+		t.Errorf("Interrupt did not save return address correctly")
+	}
+
+	if cycles != 17 {
+		t.Errorf("Interrupt returned %d cycles, expected 17", cycles)
+	}
+
+	assertCycles(t, cpu, 17)
+}
+
+func TestInterruptIsIgnoredWhenDisabled(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x00, 0x00, 0x00, 0x00, 0x00})
+	cpu.sp = 0x04
+	cpu.pc = 0x02
+
+	if cycles := cpu.Interrupt(1); cycles != 0 {
+		t.Errorf("Interrupt was accepted while interrupts were disabled")
+	}
+
+	if cpu.pc != 0x02 || cpu.sp != 0x04 {
+		t.Errorf("ignored Interrupt changed PC or SP")
+	}
+}
+
+func TestInterruptRespectsEIDelay(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xfb, 0x00, 0x00, 0x00, 0x00})
+	cpu.sp = 0x04
+
+	cpu.Run()
+
+	if cycles := cpu.Interrupt(1); cycles != 0 {
+		t.Errorf("Interrupt was accepted right after EI")
+	}
+
+	cpu.Run()
+
+	if cycles := cpu.Interrupt(1); cycles != 11 || cpu.pc != 0x0008 {
+		t.Errorf("Interrupt was not accepted after the instruction following EI")
+	}
+}
+
+func TestInterruptWakesHaltedCPU(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xfb, 0x76, 0x00, 0x00, 0x00})
+	cpu.sp = 0x04
+
+	cpu.Run()
+	cpu.Run()
+	cpu.Run()
+
+	cpu.Interrupt(1)
+
+	if cpu.IsHalted() {
+		t.Errorf("Interrupt did not wake the CPU up")
+	}
+
+	if cpu.pc != 0x0008 {
+		t.Errorf("Interrupt did not set PC to 0x0008, got 0x%04x", cpu.pc)
+	}
+
+	if cpu.memory.Read(cpu.sp) != 0x02 || cpu.memory.Read(cpu.sp+1) != 0x00 {
+		t.Errorf("Interrupt did not save the address after HLT")
+	}
+}
+
+func TestInstructionAt(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x00, 0x31, 0x00, 0x24})
+
+	if i := cpu.InstructionAt(1); i.Mnemonic != "LXI SP" || i.Size != 3 {
+		t.Errorf("InstructionAt did not decode the instruction, got %s/%d", i.Mnemonic, i.Size)
+	}
+}
+
+func TestGetInterruptState(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xfb, 0x00})
+
+	cpu.Run()
+	if enabled, pending := cpu.GetInterruptState(); enabled || !pending {
+		t.Errorf("EI was not reported as pending")
+	}
+
+	cpu.Run()
+	if enabled, pending := cpu.GetInterruptState(); !enabled || pending {
+		t.Errorf("interrupts were not reported as enabled")
+	}
+
+	if cpu.GetCycles() != 8 {
+		t.Errorf("GetCycles did not return the cycles executed")
+	}
+}
+
+func TestReset(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xfb, 0x3e, 0x05, 0x76})
+
+	for i := 0; i < 3; i++ {
+		cpu.Run()
+	}
+
+	cpu.Reset()
+
+	if cpu.GetPC() != 0 || cpu.IsHalted() {
+		t.Errorf("Reset did not restart the CPU at $0000")
+	}
+
+	if enabled, pending := cpu.GetInterruptState(); enabled || pending {
+		t.Errorf("Reset did not disable interrupts")
+	}
+
+	if cpu.GetRegisters()["A"] != 0x05 {
+		t.Errorf("Reset did not keep the registers")
+	}
+}
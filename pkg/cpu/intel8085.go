@@ -0,0 +1,402 @@
+package cpu
+
+import "github.com/gaoliveira21/intel8080-space-invaders/pkg/opcode"
+
+// InterruptPin is one of the dedicated interrupt inputs of the Intel 8085
+type InterruptPin int
+
+const (
+	RST55 InterruptPin = iota
+	RST65
+	RST75
+	TRAP
+)
+
+// Interrupt vectors of the 8085 interrupt inputs
+var intel8085Vectors = [4]uint16{
+	RST55: 0x002C,
+	RST65: 0x0034,
+	RST75: 0x003C,
+	TRAP:  0x0024,
+}
+
+const (
+	maskRST55 byte = 1 << 0
+	maskRST65 byte = 1 << 1
+	maskRST75 byte = 1 << 2
+)
+
+type intel8085State struct {
+	// M7.5, M6.5 and M5.5 as set by SIM. A set bit masks the interrupt.
+	masks byte
+	pins  [4]bool
+
+	// RST 7.5 and TRAP are edge triggered and latched until serviced
+	rst75Pending bool
+	trapPending  bool
+
+	sid bool
+	sod bool
+}
+
+func NewIntel8085(bus IOBus) *Intel8080 {
+	return NewIntel8085WithMemory(bus, NewRAM())
+}
+
+// NewIntel8085WithMemory returns a CPU running in Intel 8085 mode: RIM/SIM,
+// the TRAP/RST 5.5/6.5/7.5 inputs, 8085 cycle timings and the undocumented
+// 8085 instructions replace the 8080 behaviour where they differ.
+func NewIntel8085WithMemory(bus IOBus, mem Memory) *Intel8080 {
+	cpu := NewIntel8080WithMemory(bus, mem)
+	cpu.i8085 = &intel8085State{
+		masks: maskRST55 | maskRST65 | maskRST75,
+	}
+
+	var operations [256]func() uint
+	for op, instruction := range cpu.instructions {
+		operations[op] = instruction.operation
+	}
+
+	for _, op := range []byte{0x03, 0x0b, 0x13, 0x1b, 0x23, 0x2b, 0x33, 0x3b} {
+		operations[op] = cpu.withUnderflowIndicator(operations[op], op) // INX, DCX
+	}
+
+	// Documented instructions only differ in timing
+	for op := range operations {
+		o, o8080 := opcode.Intel8085[op], opcode.Intel8080[op]
+		if o.Undocumented || o.Mnemonic != o8080.Mnemonic || (o.Cycles == o8080.Cycles && o.CyclesTaken == o8080.CyclesTaken) {
+			continue
+		}
+
+		if o.Conditional {
+			operations[op] = cpu.withConditional8085Cycles(operations[op], byte(op), o.CyclesTaken, o.Cycles)
+		} else {
+			operations[op] = cpu.with8085Cycles(operations[op], o.Cycles)
+		}
+	}
+
+	operations[0x20] = cpu._RIM
+	operations[0x30] = cpu._SIM
+	operations[0xf1] = cpu._POP_PSW_8085
+	operations[0xf5] = cpu._PUSH_PSW_8085
+
+	// Undocumented
+	operations[0x08] = cpu._DSUB
+	operations[0x10] = cpu._ARHL
+	operations[0x18] = cpu._RDEL
+	operations[0x28] = cpu._LDHI
+	operations[0x38] = cpu._LDSI
+	operations[0xcb] = cpu._RSTV
+	operations[0xd9] = cpu._SHLX
+	operations[0xdd] = cpu._JNK
+	operations[0xed] = cpu._LHLX
+	operations[0xfd] = cpu._JK
+
+	cpu.setInstructions(&opcode.Intel8085, operations)
+
+	return cpu
+}
+
+// SetInterruptPin drives one of the 8085 interrupt inputs. RST 5.5 and 6.5
+// are level triggered; RST 7.5 and TRAP latch on the rising edge.
+func (cpu *Intel8080) SetInterruptPin(pin InterruptPin, active bool) {
+	if cpu.i8085 == nil {
+		return
+	}
+
+	state := cpu.i8085
+	if active && !state.pins[pin] {
+		switch pin {
+		case RST75:
+			state.rst75Pending = true
+		case TRAP:
+			state.trapPending = true
+		}
+	}
+	state.pins[pin] = active
+}
+
+// SetSID sets the serial input data line read by RIM
+func (cpu *Intel8080) SetSID(value bool) {
+	if cpu.i8085 != nil {
+		cpu.i8085.sid = value
+	}
+}
+
+// SOD returns the serial output data line written by SIM
+func (cpu *Intel8080) SOD() bool {
+	return cpu.i8085 != nil && cpu.i8085.sod
+}
+
+func (cpu *Intel8080) service8085Interrupts() uint {
+	state := cpu.i8085
+
+	pin := -1
+	switch {
+	case state.trapPending:
+		state.trapPending = false
+		pin = int(TRAP)
+	case !cpu.InterruptEnabled || cpu.enableInterruptDeferred:
+		return 0
+	case state.rst75Pending && state.masks&maskRST75 == 0:
+		state.rst75Pending = false
+		pin = int(RST75)
+	case state.pins[RST65] && state.masks&maskRST65 == 0:
+		pin = int(RST65)
+	case state.pins[RST55] && state.masks&maskRST55 == 0:
+		pin = int(RST55)
+	default:
+		return 0
+	}
+
+	cpu.InterruptEnabled = false
+	cpu.halted = false
+	cpu.rst(intel8085Vectors[pin])
+
+	return 12
+}
+
+func (cpu *Intel8080) with8085Cycles(operation func() uint, cycles uint) func() uint {
+	return func() uint {
+		operation()
+		return cycles
+	}
+}
+
+func (cpu *Intel8080) withConditional8085Cycles(operation func() uint, opcode byte, taken uint, notTaken uint) func() uint {
+	return func() uint {
+		met := cpu.condition(opcode)
+		operation()
+		if met {
+			return taken
+		}
+		return notTaken
+	}
+}
+
+// withUnderflowIndicator sets K when INX overflows to $0000 or DCX underflows
+// to $FFFF
+func (cpu *Intel8080) withUnderflowIndicator(operation func() uint, opcode byte) func() uint {
+	rp := (opcode >> 4) & 0x3
+	wraps := uint16(0x0000)
+	if opcode&0x08 != 0 {
+		wraps = 0xFFFF
+	}
+
+	return func() uint {
+		cycles := operation()
+		cpu.flags.Set(UnderflowIndicator, cpu.registerPair(rp) == wraps)
+		return cycles
+	}
+}
+
+// condition evaluates the condition encoded in bits 3-5 of a conditional
+// jump, call or return opcode
+func (cpu *Intel8080) condition(opcode byte) bool {
+	switch (opcode >> 3) & 0x7 {
+	case 0:
+		return !cpu.flags.Get(Zero)
+	case 1:
+		return cpu.flags.Get(Zero)
+	case 2:
+		return !cpu.flags.Get(Carry)
+	case 3:
+		return cpu.flags.Get(Carry)
+	case 4:
+		return !cpu.flags.Get(Parity)
+	case 5:
+		return cpu.flags.Get(Parity)
+	case 6:
+		return !cpu.flags.Get(Sign)
+	default:
+		return cpu.flags.Get(Sign)
+	}
+}
+
+func (cpu *Intel8080) registerPair(rp byte) uint16 {
+	switch rp {
+	case 0:
+		return uint16(cpu.b)<<8 | uint16(cpu.c)
+	case 1:
+		return uint16(cpu.d)<<8 | uint16(cpu.e)
+	case 2:
+		return uint16(cpu.h)<<8 | uint16(cpu.l)
+	default:
+		return cpu.sp
+	}
+}
+
+// set8085Overflow sets V and K after an 8 bit addition or subtraction. K
+// follows S XOR V, so JK/JNK act as signed comparisons.
+func (cpu *Intel8080) set8085Overflow(overflow bool, result byte) {
+	cpu.flags.Set(Overflow, overflow)
+	cpu.flags.Set(UnderflowIndicator, overflow != (result&0x80 != 0))
+}
+
+func (cpu *Intel8080) _RIM() uint {
+	state := cpu.i8085
+
+	value := state.masks
+	if cpu.InterruptEnabled {
+		value |= 1 << 3
+	}
+	if state.pins[RST55] {
+		value |= 1 << 4
+	}
+	if state.pins[RST65] {
+		value |= 1 << 5
+	}
+	if state.rst75Pending {
+		value |= 1 << 6
+	}
+	if state.sid {
+		value |= 1 << 7
+	}
+
+	cpu.a = value
+	return 4
+}
+
+func (cpu *Intel8080) _SIM() uint {
+	state := cpu.i8085
+
+	// Bit 3: mask set enable
+	if cpu.a&0x08 != 0 {
+		state.masks = cpu.a & 0x07
+	}
+	// Bit 4: reset the RST 7.5 latch
+	if cpu.a&0x10 != 0 {
+		state.rst75Pending = false
+	}
+	// Bit 6: serial output enable, bit 7: serial output data
+	if cpu.a&0x40 != 0 {
+		state.sod = cpu.a&0x80 != 0
+	}
+
+	return 4
+}
+
+func (cpu *Intel8080) _PUSH_PSW_8085() uint {
+	cpu.push(cpu.a, cpu.psw())
+	return 12
+}
+
+func (cpu *Intel8080) _POP_PSW_8085() uint {
+	cpu.a, cpu.flags.value = cpu.pop()
+	return 10
+}
+
+// HL <- HL - BC
+func (cpu *Intel8080) _DSUB() uint {
+	hl := uint16(cpu.h)<<8 | uint16(cpu.l)
+	bc := uint16(cpu.b)<<8 | uint16(cpu.c)
+	result := uint32(hl) - uint32(bc)
+	value := uint16(result)
+
+	overflow := (hl^bc)&(hl^value)&0x8000 != 0
+
+	cpu.flags.Set(Carry, result>>16 != 0)
+	cpu.flags.Set(AuxCarry, ((hl^bc^value)&0x1000) != 0)
+	cpu.flags.Set(Zero, value == 0)
+	cpu.flags.Set(Sign, value&0x8000 != 0)
+	cpu.flags.Set(Parity, hasParity(byte(value)))
+	cpu.flags.Set(Overflow, overflow)
+	cpu.flags.Set(UnderflowIndicator, overflow != (value&0x8000 != 0))
+
+	cpu.h, cpu.l = byte(value>>8), byte(value)
+
+	return 10
+}
+
+// Arithmetic shift right of HL, bit 0 goes to carry
+func (cpu *Intel8080) _ARHL() uint {
+	hl := uint16(cpu.h)<<8 | uint16(cpu.l)
+
+	cpu.flags.Set(Carry, hl&0x1 != 0)
+	hl = hl>>1 | hl&0x8000
+
+	cpu.h, cpu.l = byte(hl>>8), byte(hl)
+
+	return 7
+}
+
+// Rotate DE left through carry
+func (cpu *Intel8080) _RDEL() uint {
+	de := uint16(cpu.d)<<8 | uint16(cpu.e)
+
+	var c uint16 = 0
+	if cpu.flags.Get(Carry) {
+		c = 1
+	}
+
+	cpu.flags.Set(Carry, de&0x8000 != 0)
+	cpu.flags.Set(Overflow, (de^de<<1)&0x8000 != 0)
+	de = de<<1 | c
+
+	cpu.d, cpu.e = byte(de>>8), byte(de)
+
+	return 10
+}
+
+// DE <- HL + immediate
+func (cpu *Intel8080) _LDHI() uint {
+	de := (uint16(cpu.h)<<8 | uint16(cpu.l)) + uint16(cpu.operand(0))
+	cpu.d, cpu.e = byte(de>>8), byte(de)
+	cpu.pc++
+
+	return 10
+}
+
+// DE <- SP + immediate
+func (cpu *Intel8080) _LDSI() uint {
+	de := cpu.sp + uint16(cpu.operand(0))
+	cpu.d, cpu.e = byte(de>>8), byte(de)
+	cpu.pc++
+
+	return 10
+}
+
+// Restart at $0040 on overflow
+func (cpu *Intel8080) _RSTV() uint {
+	if cpu.flags.Get(Overflow) {
+		cpu.rst(0x0040)
+		return 12
+	}
+	return 6
+}
+
+// (DE) <- L, (DE+1) <- H
+func (cpu *Intel8080) _SHLX() uint {
+	addr := uint16(cpu.d)<<8 | uint16(cpu.e)
+	cpu.writeByte(addr, cpu.l)
+	cpu.writeByte(addr+1, cpu.h)
+
+	return 10
+}
+
+// L <- (DE), H <- (DE+1)
+func (cpu *Intel8080) _LHLX() uint {
+	addr := uint16(cpu.d)<<8 | uint16(cpu.e)
+	cpu.l = cpu.readByte(addr)
+	cpu.h = cpu.readByte(addr + 1)
+
+	return 10
+}
+
+func (cpu *Intel8080) _JNK() uint {
+	if !cpu.flags.Get(UnderflowIndicator) {
+		cpu.jump()
+		return 10
+	}
+	cpu.pc += 2
+	return 7
+}
+
+func (cpu *Intel8080) _JK() uint {
+	if cpu.flags.Get(UnderflowIndicator) {
+		cpu.jump()
+		return 10
+	}
+	cpu.pc += 2
+	return 7
+}
@@ -0,0 +1,414 @@
+package cpu
+
+import (
+	"testing"
+)
+
+func createIntel8085WithProgramLoaded(p []byte) *Intel8080 {
+	cpu := NewIntel8085(&TestIOBus{})
+	cpu.LoadProgram(p, 0)
+
+	return cpu
+}
+
+func Test8085Cycles(t *testing.T) {
+	tData := []struct {
+		name    string
+		program []byte
+		cycles  uint
+	}{
+		{"MOV B,C", []byte{0x41}, 4},
+		{"MOV B,M", []byte{0x46}, 7},
+		{"INR B", []byte{0x04}, 4},
+		{"INX B", []byte{0x03}, 6},
+		{"PUSH B", []byte{0xc5}, 12},
+		{"PUSH PSW", []byte{0xf5}, 12},
+		{"CALL", []byte{0xcd, 0x00, 0x10}, 18},
+		{"CNZ taken", []byte{0xc4, 0x00, 0x10}, 18},
+		{"CZ not taken", []byte{0xcc, 0x00, 0x10}, 9},
+		{"RNZ taken", []byte{0xc0}, 12},
+		{"RZ not taken", []byte{0xc8}, 6},
+		{"JNZ taken", []byte{0xc2, 0x00, 0x10}, 10},
+		{"JZ not taken", []byte{0xca, 0x00, 0x10}, 7},
+		{"RST 1", []byte{0xcf}, 12},
+		{"XTHL", []byte{0xe3}, 16},
+		{"PCHL", []byte{0xe9}, 6},
+		{"SPHL", []byte{0xf9}, 6},
+		{"HLT", []byte{0x76}, 5},
+	}
+
+	for _, d := range tData {
+		cpu := createIntel8085WithProgramLoaded(d.program)
+		cpu.sp = 0x2000
+
+		cpu.Run()
+
+		if cpu.cycles != d.cycles {
+			t.Errorf("%s took %d cycles, expected %d", d.name, cpu.cycles, d.cycles)
+		}
+	}
+}
+
+func Test8080KeepsItsCycles(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x41, 0x03, 0xcd, 0x00, 0x10})
+	cpu.sp = 0x2000
+
+	cpu.Run()
+	cpu.Run()
+	cpu.Run()
+
+	assertCycles(t, cpu, 5+5+17)
+}
+
+func Test_SIM_RIM(t *testing.T) {
+	cpu := createIntel8085WithProgramLoaded([]byte{0x30, 0x3e, 0x00, 0x20})
+	cpu.a = 0b11001111 // SOD = 1, SOE, MSE and all masks set
+	cpu.InterruptEnabled = true
+	cpu.SetSID(true)
+	cpu.SetInterruptPin(RST65, true)
+
+	cpu.Run()
+
+	if !cpu.SOD() {
+		t.Errorf("SIM did not set the serial output")
+	}
+
+	cpu.Run() // MVI A,0
+	cpu.Run()
+
+	if cpu.a != 0b10101111 {
+		t.Errorf("RIM did not read masks, pending interrupts, IE and SID correctly, got %08b", cpu.a)
+	}
+
+	assertCycles(t, cpu, 4+7+4)
+}
+
+func Test_SIMWithoutMaskSetEnable(t *testing.T) {
+	cpu := createIntel8085WithProgramLoaded([]byte{0x30})
+	cpu.a = 0b00000000
+
+	cpu.Run()
+
+	if cpu.i8085.masks != 0x07 {
+		t.Errorf("SIM changed the masks without MSE")
+	}
+}
+
+func TestRST75IsLatched(t *testing.T) {
+	cpu := createIntel8085WithProgramLoaded([]byte{0x00, 0x00, 0x00, 0x00})
+	cpu.sp = 0x2000
+	cpu.i8085.masks = 0
+	cpu.InterruptEnabled = true
+
+	cpu.SetInterruptPin(RST75, true)
+	cpu.SetInterruptPin(RST75, false)
+
+	cycles := cpu.Run()
+
+	if cpu.pc != 0x003C {
+		t.Errorf("RST 7.5 did not jump to 0x003C, got 0x%04x", cpu.pc)
+	}
+
+	if cpu.memory.Read(cpu.sp) != 0x00 || cpu.memory.Read(cpu.sp+1) != 0x00 {
+		t.Errorf("RST 7.5 did not save return address correctly")
+	}
+
+	if cycles != 12 || cpu.InterruptEnabled {
+		t.Errorf("RST 7.5 was not acknowledged correctly")
+	}
+
+	if cpu.i8085.rst75Pending {
+		t.Errorf("RST 7.5 latch was not cleared")
+	}
+}
+
+func TestRST55And65AreLevelTriggered(t *testing.T) {
+	cpu := createIntel8085WithProgramLoaded([]byte{0x00, 0x00, 0x00, 0x00})
+	cpu.sp = 0x2000
+	cpu.i8085.masks = 0
+	cpu.InterruptEnabled = true
+
+	cpu.SetInterruptPin(RST55, true)
+	cpu.SetInterruptPin(RST55, false)
+	cpu.Run()
+
+	if cpu.pc != 0x0001 {
+		t.Errorf("released RST 5.5 was serviced")
+	}
+
+	cpu.SetInterruptPin(RST55, true)
+	cpu.SetInterruptPin(RST65, true)
+	cpu.Run()
+
+	if cpu.pc != 0x0034 {
+		t.Errorf("RST 6.5 was not serviced before RST 5.5, got 0x%04x", cpu.pc)
+	}
+}
+
+func TestMaskedInterruptsAreNotServiced(t *testing.T) {
+	cpu := createIntel8085WithProgramLoaded([]byte{0x00, 0x00, 0x00, 0x00})
+	cpu.InterruptEnabled = true
+
+	cpu.SetInterruptPin(RST55, true)
+	cpu.SetInterruptPin(RST65, true)
+	cpu.SetInterruptPin(RST75, true)
+	cpu.Run()
+
+	if cpu.pc != 0x0001 {
+		t.Errorf("masked interrupt was serviced")
+	}
+}
+
+func TestTRAPIsNonMaskable(t *testing.T) {
+	cpu := createIntel8085WithProgramLoaded([]byte{0x76, 0x00, 0x00, 0x00})
+	cpu.sp = 0x2000
+
+	cpu.Run()
+	cpu.SetInterruptPin(TRAP, true)
+	cpu.Run()
+
+	if cpu.pc != 0x0024 {
+		t.Errorf("TRAP did not jump to 0x0024, got 0x%04x", cpu.pc)
+	}
+
+	if cpu.IsHalted() {
+		t.Errorf("TRAP did not wake the CPU up")
+	}
+
+	if cpu.memory.Read(cpu.sp) != 0x01 || cpu.memory.Read(cpu.sp+1) != 0x00 {
+		t.Errorf("TRAP did not save return address correctly")
+	}
+}
+
+func Test_PUSH_PSW_8085(t *testing.T) {
+	cpu := createIntel8085WithProgramLoaded([]byte{0xf5, 0x00, 0x00, 0x00})
+	cpu.sp = 3
+	cpu.a = 0x5
+	cpu.flags.Set(Sign, true)
+	cpu.flags.Set(Overflow, true)
+	cpu.flags.Set(UnderflowIndicator, true)
+	cpu.flags.Set(Carry, true)
+
+	cpu.Run()
+
+	if cpu.memory.Read(1) != 0xA3 {
+		t.Errorf("PUSH PSW did not store V and K, got 0x%02x", cpu.memory.Read(1))
+	}
+}
+
+func TestOverflowFlag(t *testing.T) {
+	cpu := createIntel8085WithProgramLoaded([]byte{0xc6, 0x01, 0xd6, 0x01})
+	cpu.a = 0x7f
+
+	cpu.Run()
+
+	if !cpu.flags.Get(Overflow) || cpu.flags.Get(UnderflowIndicator) {
+		t.Errorf("ADI did not set V and K correctly")
+	}
+
+	cpu.Run()
+
+	if !cpu.flags.Get(Overflow) || !cpu.flags.Get(UnderflowIndicator) {
+		t.Errorf("SUI did not set V and K correctly")
+	}
+}
+
+func Test_INX_DCX_UnderflowIndicator(t *testing.T) {
+	cpu := createIntel8085WithProgramLoaded([]byte{0x13, 0x1b, 0x1b})
+	cpu.d = 0xff
+	cpu.e = 0xff
+
+	cpu.Run()
+
+	if !cpu.flags.Get(UnderflowIndicator) || cpu.d != 0 || cpu.e != 0 {
+		t.Errorf("INX D did not set K on overflow")
+	}
+
+	cpu.Run()
+
+	if !cpu.flags.Get(UnderflowIndicator) || cpu.d != 0xff || cpu.e != 0xff {
+		t.Errorf("DCX D did not set K on underflow")
+	}
+
+	cpu.Run()
+
+	if cpu.flags.Get(UnderflowIndicator) {
+		t.Errorf("DCX D did not clear K")
+	}
+}
+
+func Test_DSUB(t *testing.T) {
+	cpu := createIntel8085WithProgramLoaded([]byte{0x08})
+	cpu.h, cpu.l = 0x12, 0x34
+	cpu.b, cpu.c = 0x02, 0x35
+
+	cpu.Run()
+
+	if cpu.h != 0x0f || cpu.l != 0xff {
+		t.Errorf("DSUB did not subtract BC from HL, got 0x%02x%02x", cpu.h, cpu.l)
+	}
+
+	if cpu.flags.Get(Carry) || cpu.flags.Get(Zero) || cpu.flags.Get(Overflow) {
+		t.Errorf("DSUB did not set flags correctly")
+	}
+
+	assertCycles(t, cpu, 10)
+}
+
+func Test_DSUBWithBorrow(t *testing.T) {
+	cpu := createIntel8085WithProgramLoaded([]byte{0x08})
+	cpu.h, cpu.l = 0x80, 0x00
+	cpu.b, cpu.c = 0x00, 0x01
+
+	cpu.Run()
+
+	if cpu.h != 0x7f || cpu.l != 0xff {
+		t.Errorf("DSUB did not subtract BC from HL")
+	}
+
+	if !cpu.flags.Get(Overflow) || !cpu.flags.Get(UnderflowIndicator) || cpu.flags.Get(Carry) {
+		t.Errorf("DSUB did not set V and K on signed overflow")
+	}
+}
+
+func Test_ARHL(t *testing.T) {
+	cpu := createIntel8085WithProgramLoaded([]byte{0x10})
+	cpu.h, cpu.l = 0x80, 0x03
+
+	cpu.Run()
+
+	if cpu.h != 0xc0 || cpu.l != 0x01 {
+		t.Errorf("ARHL did not shift HL correctly, got 0x%02x%02x", cpu.h, cpu.l)
+	}
+
+	if !cpu.flags.Get(Carry) {
+		t.Errorf("ARHL did not set carry flag")
+	}
+
+	assertCycles(t, cpu, 7)
+}
+
+func Test_RDEL(t *testing.T) {
+	cpu := createIntel8085WithProgramLoaded([]byte{0x18})
+	cpu.d, cpu.e = 0x80, 0x01
+	cpu.flags.Set(Carry, true)
+
+	cpu.Run()
+
+	if cpu.d != 0x00 || cpu.e != 0x03 {
+		t.Errorf("RDEL did not rotate DE correctly, got 0x%02x%02x", cpu.d, cpu.e)
+	}
+
+	if !cpu.flags.Get(Carry) || !cpu.flags.Get(Overflow) {
+		t.Errorf("RDEL did not set flags correctly")
+	}
+
+	assertCycles(t, cpu, 10)
+}
+
+func Test_LDHI(t *testing.T) {
+	cpu := createIntel8085WithProgramLoaded([]byte{0x28, 0x10})
+	cpu.h, cpu.l = 0x12, 0xf8
+
+	cpu.Run()
+
+	if cpu.d != 0x13 || cpu.e != 0x08 {
+		t.Errorf("LDHI did not load DE correctly")
+	}
+
+	if cpu.pc != 2 {
+		t.Errorf("LDHI did not increment PC correctly")
+	}
+
+	assertCycles(t, cpu, 10)
+}
+
+func Test_LDSI(t *testing.T) {
+	cpu := createIntel8085WithProgramLoaded([]byte{0x38, 0x02})
+	cpu.sp = 0x2400
+
+	cpu.Run()
+
+	if cpu.d != 0x24 || cpu.e != 0x02 {
+		t.Errorf("LDSI did not load DE correctly")
+	}
+
+	assertCycles(t, cpu, 10)
+}
+
+func Test_SHLX(t *testing.T) {
+	cpu := createIntel8085WithProgramLoaded([]byte{0xd9})
+	cpu.h, cpu.l = 0x12, 0x34
+	cpu.d, cpu.e = 0x20, 0x00
+
+	cpu.Run()
+
+	if cpu.memory.Read(0x2000) != 0x34 || cpu.memory.Read(0x2001) != 0x12 {
+		t.Errorf("SHLX did not store HL correctly")
+	}
+
+	assertCycles(t, cpu, 10)
+}
+
+func Test_LHLX(t *testing.T) {
+	cpu := createIntel8085WithProgramLoaded([]byte{0xed, 0x2000: 0x34, 0x12})
+	cpu.d, cpu.e = 0x20, 0x00
+
+	cpu.Run()
+
+	if cpu.h != 0x12 || cpu.l != 0x34 {
+		t.Errorf("LHLX did not load HL correctly")
+	}
+
+	assertCycles(t, cpu, 10)
+}
+
+func Test_JNK_JK(t *testing.T) {
+	cpu := createIntel8085WithProgramLoaded([]byte{0xdd, 0x00, 0x10, 0x1000: 0xfd, 0x00, 0x20})
+
+	cpu.Run()
+
+	if cpu.pc != 0x1000 {
+		t.Errorf("JNK did not jump when K is clear")
+	}
+
+	cpu.Run()
+
+	if cpu.pc != 0x1003 {
+		t.Errorf("JK jumped when K is clear")
+	}
+
+	assertCycles(t, cpu, 10+7)
+}
+
+func Test_RSTV(t *testing.T) {
+	cpu := createIntel8085WithProgramLoaded([]byte{0xcb, 0xcb})
+	cpu.sp = 0x2000
+
+	cpu.Run()
+
+	if cpu.pc != 1 {
+		t.Errorf("RSTV restarted without overflow")
+	}
+
+	cpu.flags.Set(Overflow, true)
+	cpu.Run()
+
+	if cpu.pc != 0x0040 {
+		t.Errorf("RSTV did not restart at 0x0040 on overflow")
+	}
+
+	assertCycles(t, cpu, 6+12)
+}
+
+func Test8080KeepsUndocumentedAliases(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x08, 0xdd, 0x00, 0x10})
+	cpu.sp = 0x2000
+
+	cpu.Run()
+	cpu.Run()
+
+	if cpu.pc != 0x1000 {
+		t.Errorf("8080 did not execute *NOP and *CALL")
+	}
+}
@@ -0,0 +1,33 @@
+package cpu
+
+// Memory is the address space seen by the CPU. Every instruction reads and
+// writes through it, so a machine can supply its own memory map.
+type Memory interface {
+	Read(addr uint16) byte
+	Write(addr uint16, value byte)
+	// Load copies data starting at offset, bypassing any write protection.
+	Load(data []byte, offset uint16)
+}
+
+// RAM is a flat, fully writable 64 KiB address space.
+type RAM struct {
+	data [0x10000]byte
+}
+
+func NewRAM() *RAM {
+	return &RAM{}
+}
+
+func (ram *RAM) Read(addr uint16) byte {
+	return ram.data[addr]
+}
+
+func (ram *RAM) Write(addr uint16, value byte) {
+	ram.data[addr] = value
+}
+
+func (ram *RAM) Load(data []byte, offset uint16) {
+	for i, b := range data {
+		ram.data[offset+uint16(i)] = b
+	}
+}
@@ -0,0 +1,40 @@
+package cpu
+
+import "testing"
+
+func TestRAMCoversFullAddressSpace(t *testing.T) {
+	ram := NewRAM()
+	ram.Write(0xFFFF, 0x42)
+
+	if ram.Read(0xFFFF) != 0x42 {
+		t.Errorf("address $FFFF is not reachable")
+	}
+}
+
+func TestRAMLoad(t *testing.T) {
+	ram := NewRAM()
+	ram.Load([]byte{0x01, 0x02, 0x03}, 0xFFFE)
+
+	if ram.Read(0xFFFE) != 0x01 || ram.Read(0xFFFF) != 0x02 || ram.Read(0x0000) != 0x03 {
+		t.Errorf("Load did not copy data correctly")
+	}
+}
+
+type readOnlyMemory struct {
+	RAM
+}
+
+func (mem *readOnlyMemory) Write(addr uint16, value byte) {}
+
+func TestCPUUsesSuppliedMemory(t *testing.T) {
+	mem := &readOnlyMemory{}
+	cpu := NewIntel8080WithMemory(&TestIOBus{}, mem)
+	cpu.LoadProgram([]byte{0x3E, 0x42, 0x32, 0x00, 0x20}, 0) // MVI A,$42; STA $2000
+
+	cpu.Run()
+	cpu.Run()
+
+	if cpu.ReadFromMemory(0x2000) != 0x00 {
+		t.Errorf("CPU did not write through the supplied memory")
+	}
+}
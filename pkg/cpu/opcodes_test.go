@@ -0,0 +1,145 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/disasm"
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/opcode"
+)
+
+// Flags the 8080 defines, the other bits are fixed or 8085 only
+const definedFlags = byte(opcode.All)
+
+// runOpcode runs op with the operand bytes $10 $10, all flags cleared or all
+// set and $1234 on top of the stack, and returns the cycles it took, the
+// flags it changed, where it left PC and SP and the bytes of the operand
+// address, $1010 and $1011, it read and wrote.
+func runOpcode(newCPU func(IOBus) *Intel8080, op byte, flagsSet bool) (cycles uint, changed byte, pc uint16, sp uint16, reads int, writes int) {
+	cpu := newCPU(&TestIOBus{})
+	cpu.AddHooks(&Hooks{
+		MemoryRead: func(addr uint16, _ byte) {
+			if addr == 0x1010 || addr == 0x1011 {
+				reads++
+			}
+		},
+		MemoryWrite: func(addr uint16, _ byte, _ byte) {
+			if addr == 0x1010 || addr == 0x1011 {
+				writes++
+			}
+		},
+	})
+	cpu.LoadProgram([]byte{op, 0x10, 0x10}, 0)
+	cpu.LoadProgram([]byte{0x34, 0x12}, 0x2000)
+	cpu.SetSP(0x2000)
+
+	psw := byte(0x00)
+	if flagsSet {
+		psw = 0xFF
+	}
+	cpu.SetFlags(psw)
+	before := cpu.GetFlags()
+
+	cycles = cpu.Run()
+	return cycles, (before ^ cpu.GetFlags()) & definedFlags, cpu.GetPC(), cpu.GetSP(), reads, writes
+}
+
+// movesSP reports whether o sets SP or uses the stack other than to call and
+// return.
+func movesSP(o opcode.Opcode) bool {
+	switch o.Mnemonic {
+	case "PUSH", "POP", "SPHL":
+		return true
+	case "LXI", "INX", "DCX":
+		return o.Name() == o.Mnemonic+" SP"
+	}
+	return false
+}
+
+// The opcode tables drive the CPU and the disassembler. Their metadata must
+// match what the CPU actually does.
+func TestOpcodeTablesMatchCPU(t *testing.T) {
+	sets := []struct {
+		name   string
+		table  *[256]opcode.Opcode
+		newCPU func(IOBus) *Intel8080
+	}{
+		{"8080", &opcode.Intel8080, NewIntel8080},
+		{"8085", &opcode.Intel8085, NewIntel8085},
+	}
+
+	for _, set := range sets {
+		for op, o := range set.table {
+			cpu := set.newCPU(&TestIOBus{})
+			instruction := cpu.instructions[op]
+
+			if instruction.Mnemonic != o.Name() || instruction.Size != o.Size || cpu.Opcodes() != set.table {
+				t.Errorf("%s %02X: CPU decodes %s/%d, the table %s/%d", set.name, op, instruction.Mnemonic, instruction.Size, o.Name(), o.Size)
+			}
+
+			cpu.LoadProgram([]byte{byte(op)}, 0)
+			if i := disasm.DisassembleWith(set.table, cpu, 0); i.Mnemonic != o.Mnemonic || i.Size != o.Size || i.Cycles != o.Cycles {
+				t.Errorf("%s %02X: disassembler decodes %s, the table %s", set.name, op, i.Mnemonic, o.Mnemonic)
+			}
+
+			cyclesCleared, changedCleared, pcCleared, spCleared, reads, writes := runOpcode(set.newCPU, byte(op), false)
+			cyclesSet, changedSet, pcSet, spSet, _, _ := runOpcode(set.newCPU, byte(op), true)
+
+			// Every condition depends on a single flag, so exactly one run
+			// meets it
+			if (cyclesCleared != o.Cycles || cyclesSet != o.CyclesTaken) && (cyclesCleared != o.CyclesTaken || cyclesSet != o.Cycles) {
+				t.Errorf("%s %02X: CPU takes %d/%d cycles, the table %d/%d", set.name, op, cyclesCleared, cyclesSet, o.Cycles, o.CyclesTaken)
+			}
+
+			if changed := (changedCleared | changedSet) &^ byte(o.Flags); changed != 0 {
+				t.Errorf("%s %02X: CPU changes flags %08b the table leaves alone", set.name, op, changed)
+			}
+
+			if o.Flow == opcode.Next && (pcCleared != o.Size || pcSet != o.Size) {
+				t.Errorf("%s %02X: CPU moves PC to $%04X/$%04X, not past the %d bytes of the instruction", set.name, op, pcCleared, pcSet, o.Size)
+			}
+
+			expectedReads, expectedWrites := 0, 0
+			switch o.Access {
+			case opcode.Read:
+				expectedReads = int(o.AccessSize)
+			case opcode.Write:
+				expectedWrites = int(o.AccessSize)
+			}
+			if reads != expectedReads || writes != expectedWrites {
+				t.Errorf("%s %02X: CPU reads %d and writes %d bytes at its address, the table %d and %d", set.name, op, reads, writes, expectedReads, expectedWrites)
+			}
+
+			if o.Flow == opcode.Jump || o.Flow == opcode.Call || o.Flow == opcode.Return {
+				target := uint16(0x1010)
+				switch {
+				case o.Mnemonic == "PCHL":
+					target = 0x0000
+				case o.Flow == opcode.Return:
+					target = 0x1234
+				case o.HasVector:
+					target = o.Vector
+				}
+
+				if pcCleared != target && pcSet != target {
+					t.Errorf("%s %02X: CPU does not branch to $%04X, PC is $%04X/$%04X", set.name, op, target, pcCleared, pcSet)
+				}
+
+				if o.Conditional && pcCleared != o.Size && pcSet != o.Size {
+					t.Errorf("%s %02X: CPU always branches", set.name, op)
+				}
+			}
+
+			// The debugger tells calls and returns apart by their flow, e.g.
+			// to step over calls
+			switch {
+			case o.Flow == opcode.Call && spCleared != 0x1FFE && spSet != 0x1FFE:
+				t.Errorf("%s %02X: CPU does not push a return address, SP is $%04X/$%04X", set.name, op, spCleared, spSet)
+			case o.Flow == opcode.Return && spCleared != 0x2002 && spSet != 0x2002:
+				t.Errorf("%s %02X: CPU does not pop a return address, SP is $%04X/$%04X", set.name, op, spCleared, spSet)
+			case o.Flow != opcode.Call && o.Flow != opcode.Return && !movesSP(o) && (spCleared != 0x2000 || spSet != 0x2000):
+				t.Errorf("%s %02X: CPU moves SP to $%04X/$%04X, the table has no call or return", set.name, op, spCleared, spSet)
+			}
+
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package cpu
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+type intel8080State struct {
+	A, B, C, D, E, H, L byte
+	Flags               byte
+	SP, PC              uint16
+	Cycles              uint64
+
+	InterruptEnabled        bool
+	EnableInterruptDeferred bool
+	Halted                  bool
+
+	// 8085 only
+	InterruptMasks byte
+	InterruptPins  [4]bool
+	RST75Pending   bool
+	TrapPending    bool
+	SID, SOD       bool
+
+	Memory [0x10000]byte
+}
+
+// SaveState writes registers, flags, pointers, cycle count, interrupt state
+// and the whole address space to w.
+func (cpu *Intel8080) SaveState(w io.Writer) error {
+	state := &intel8080State{
+		A:                       cpu.a,
+		B:                       cpu.b,
+		C:                       cpu.c,
+		D:                       cpu.d,
+		E:                       cpu.e,
+		H:                       cpu.h,
+		L:                       cpu.l,
+		Flags:                   cpu.flags.value,
+		SP:                      cpu.sp,
+		PC:                      cpu.pc,
+		Cycles:                  uint64(cpu.cycles),
+		InterruptEnabled:        cpu.InterruptEnabled,
+		EnableInterruptDeferred: cpu.enableInterruptDeferred,
+		Halted:                  cpu.halted,
+	}
+	if cpu.i8085 != nil {
+		state.InterruptMasks = cpu.i8085.masks
+		state.InterruptPins = cpu.i8085.pins
+		state.RST75Pending = cpu.i8085.rst75Pending
+		state.TrapPending = cpu.i8085.trapPending
+		state.SID = cpu.i8085.sid
+		state.SOD = cpu.i8085.sod
+	}
+	copy(state.Memory[:], cpu.GetMemory())
+
+	return binary.Write(w, binary.LittleEndian, state)
+}
+
+// ReadState decodes a state written by SaveState and returns a function that
+// restores it, so it can be read along with other state before anything is
+// changed. Memory is restored with Memory.Load, so read-only regions are
+// overwritten as well.
+func (cpu *Intel8080) ReadState(r io.Reader) (apply func(), err error) {
+	state := &intel8080State{}
+	if err := binary.Read(r, binary.LittleEndian, state); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		cpu.a = state.A
+		cpu.b = state.B
+		cpu.c = state.C
+		cpu.d = state.D
+		cpu.e = state.E
+		cpu.h = state.H
+		cpu.l = state.L
+		cpu.flags.value = state.Flags
+		cpu.sp = state.SP
+		cpu.pc = state.PC
+		cpu.cycles = uint(state.Cycles)
+		cpu.InterruptEnabled = state.InterruptEnabled
+		cpu.enableInterruptDeferred = state.EnableInterruptDeferred
+		cpu.halted = state.Halted
+		if cpu.i8085 != nil {
+			cpu.i8085.masks = state.InterruptMasks
+			cpu.i8085.pins = state.InterruptPins
+			cpu.i8085.rst75Pending = state.RST75Pending
+			cpu.i8085.trapPending = state.TrapPending
+			cpu.i8085.sid = state.SID
+			cpu.i8085.sod = state.SOD
+		}
+		cpu.memory.Load(state.Memory[:], 0)
+	}, nil
+}
+
+// LoadState restores a state written by SaveState.
+func (cpu *Intel8080) LoadState(r io.Reader) error {
+	apply, err := cpu.ReadState(r)
+	if err != nil {
+		return err
+	}
+
+	apply()
+	return nil
+}
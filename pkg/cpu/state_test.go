@@ -0,0 +1,56 @@
+package cpu
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSaveAndLoadState(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x3E, 0x42, 0xFB, 0x76}) // MVI A,$42; EI; HLT
+	cpu.Run()
+	cpu.Run()
+	cpu.Run()
+	cpu.b, cpu.c, cpu.d, cpu.e, cpu.h, cpu.l = 1, 2, 3, 4, 5, 6
+	cpu.sp = 0x2400
+	cpu.flags.Set(Carry, true)
+	cpu.memory.Write(0xFFFF, 0x24)
+
+	var buf bytes.Buffer
+	if err := cpu.SaveState(&buf); err != nil {
+		t.Fatalf("SaveState returned an error: %s", err)
+	}
+
+	restored := NewIntel8080(&TestIOBus{})
+	if err := restored.LoadState(&buf); err != nil {
+		t.Fatalf("LoadState returned an error: %s", err)
+	}
+
+	if restored.a != 0x42 || restored.b != 1 || restored.c != 2 || restored.d != 3 ||
+		restored.e != 4 || restored.h != 5 || restored.l != 6 {
+		t.Errorf("LoadState did not restore registers correctly")
+	}
+
+	if restored.sp != 0x2400 || restored.pc != 4 {
+		t.Errorf("LoadState did not restore pointers correctly")
+	}
+
+	if !restored.flags.Get(Carry) {
+		t.Errorf("LoadState did not restore flags correctly")
+	}
+
+	if restored.cycles != cpu.cycles {
+		t.Errorf("LoadState did not restore cycle count correctly")
+	}
+
+	if restored.enableInterruptDeferred || !restored.InterruptEnabled {
+		t.Errorf("LoadState did not restore interrupt state correctly")
+	}
+
+	if !restored.IsHalted() {
+		t.Errorf("LoadState did not restore halted state correctly")
+	}
+
+	if restored.memory.Read(0x0000) != 0x3E || restored.memory.Read(0xFFFF) != 0x24 {
+		t.Errorf("LoadState did not restore memory correctly")
+	}
+}
@@ -0,0 +1,145 @@
+package cpu
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// TraceCondition tells a Tracer when to start or stop, given the PC and
+// cycle count before the next instruction runs.
+type TraceCondition func(pc uint16, cycles uint) bool
+
+// AtPC holds when the instruction at pc is about to run.
+func AtPC(pc uint16) TraceCondition {
+	return func(p uint16, _ uint) bool {
+		return p == pc
+	}
+}
+
+// AtCycle holds once at least cycles have been executed.
+func AtCycle(cycles uint) TraceCondition {
+	return func(_ uint16, c uint) bool {
+		return c >= cycles
+	}
+}
+
+// ParseTraceCondition parses "pc=<address>" or "cycle=<count>". Numbers may
+// be written in any base strconv accepts, e.g. pc=0x0100.
+func ParseTraceCondition(s string) (TraceCondition, error) {
+	kind, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return nil, fmt.Errorf("invalid trace condition %q, expected pc=<address> or cycle=<count>", s)
+	}
+
+	switch kind {
+	case "pc":
+		pc, err := strconv.ParseUint(value, 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trace condition %q: %w", s, err)
+		}
+		return AtPC(uint16(pc)), nil
+	case "cycle":
+		cycles, err := strconv.ParseUint(value, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trace condition %q: %w", s, err)
+		}
+		return AtCycle(uint(cycles)), nil
+	}
+
+	return nil, fmt.Errorf("invalid trace condition %q, expected pc=<address> or cycle=<count>", s)
+}
+
+// Tracer writes one line per executed instruction, showing the state before
+// it runs:
+//
+//	PC    BYTES     MNEMONIC    A  F  B  C  D  E  H  L  SP   CYCLES
+//	0100  31 00 24  LXI SP      A:00 F:02 B:00 C:00 D:00 E:00 H:00 L:00 SP:0000 CYC:0
+//
+// F is the flags byte as PUSH PSW stores it. With labels set, instructions at
+// a labelled address end with ; and the label. Tracing covers a single window:
+// it begins the first time the start condition holds and ends for good the
+// first time the stop condition holds after that.
+type Tracer struct {
+	w      io.Writer
+	start  TraceCondition
+	stop   TraceCondition
+	labels func(addr uint16) (string, bool)
+
+	started bool
+	stopped bool
+	line    []byte
+	err     error
+}
+
+// NewTracer returns a tracer writing to w from the first instruction on.
+func NewTracer(w io.Writer) *Tracer {
+	return &Tracer{w: w}
+}
+
+func (t *Tracer) StartAt(condition TraceCondition) {
+	t.start = condition
+}
+
+func (t *Tracer) StopAt(condition TraceCondition) {
+	t.stop = condition
+}
+
+// SetLabels names the addresses of instructions, e.g. with a symbol file.
+func (t *Tracer) SetLabels(labels func(addr uint16) (string, bool)) {
+	t.labels = labels
+}
+
+// Err returns the first error returned by the writer. No more lines are
+// written after it.
+func (t *Tracer) Err() error {
+	return t.err
+}
+
+func (t *Tracer) trace(cpu *Intel8080) {
+	if t.stopped || t.err != nil {
+		return
+	}
+
+	if !t.started {
+		if t.start != nil && !t.start(cpu.pc, cpu.cycles) {
+			return
+		}
+		t.started = true
+	}
+
+	if t.stop != nil && t.stop(cpu.pc, cpu.cycles) {
+		t.stopped = true
+		return
+	}
+
+	instruction := cpu.instructions[cpu.memory.Read(cpu.pc)]
+
+	var bytes [3]string
+	for i := range bytes {
+		if uint16(i) < instruction.Size {
+			bytes[i] = fmt.Sprintf("%02X", cpu.memory.Read(cpu.pc+uint16(i)))
+		}
+	}
+
+	t.line = fmt.Appendf(t.line[:0],
+		"%04X  %-2s %-2s %-2s  %-10s  A:%02X F:%02X B:%02X C:%02X D:%02X E:%02X H:%02X L:%02X SP:%04X CYC:%d",
+		cpu.pc, bytes[0], bytes[1], bytes[2], instruction.Mnemonic,
+		cpu.a, cpu.psw(), cpu.b, cpu.c, cpu.d, cpu.e, cpu.h, cpu.l, cpu.sp, cpu.cycles,
+	)
+	if t.labels != nil {
+		if label, ok := t.labels(cpu.pc); ok {
+			t.line = fmt.Appendf(t.line, " ; %s", label)
+		}
+	}
+	t.line = append(t.line, '\n')
+
+	_, t.err = t.w.Write(t.line)
+}
+
+// SetTracer makes Run trace every instruction to t. A nil tracer turns
+// tracing off.
+func (cpu *Intel8080) SetTracer(t *Tracer) {
+	cpu.tracer = t
+}
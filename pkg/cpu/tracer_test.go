@@ -0,0 +1,145 @@
+package cpu
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func runTraced(p []byte, steps int, configure func(t *Tracer)) string {
+	var out strings.Builder
+
+	cpu := createCPUWithProgramLoaded(p)
+	tracer := NewTracer(&out)
+	if configure != nil {
+		configure(tracer)
+	}
+	cpu.SetTracer(tracer)
+
+	for i := 0; i < steps; i++ {
+		cpu.Run()
+	}
+
+	return out.String()
+}
+
+func TestTracerLineFormat(t *testing.T) {
+	trace := runTraced([]byte{0x31, 0x00, 0x24, 0x3e, 0x42, 0x00}, 3, nil)
+
+	expected := "" +
+		"0000  31 00 24  LXI SP      A:00 F:02 B:00 C:00 D:00 E:00 H:00 L:00 SP:0000 CYC:0\n" +
+		"0003  3E 42     MVI A       A:00 F:02 B:00 C:00 D:00 E:00 H:00 L:00 SP:2400 CYC:10\n" +
+		"0005  00        NOP         A:42 F:02 B:00 C:00 D:00 E:00 H:00 L:00 SP:2400 CYC:17\n"
+
+	if trace != expected {
+		t.Errorf("Tracer did not write the expected lines, got:\n%s", trace)
+	}
+}
+
+func TestTracerLabels(t *testing.T) {
+	trace := runTraced([]byte{0x00, 0x00}, 2, func(t *Tracer) {
+		t.SetLabels(func(addr uint16) (string, bool) {
+			return "Start", addr == 0x0001
+		})
+	})
+
+	lines := strings.Split(strings.TrimSpace(trace), "\n")
+
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "CYC:0") || !strings.HasSuffix(lines[1], "CYC:4 ; Start") {
+		t.Errorf("Tracer did not label the instructions, got:\n%s", trace)
+	}
+}
+
+func TestTracerStartAndStopAtPC(t *testing.T) {
+	trace := runTraced([]byte{0x00, 0x00, 0x00, 0x00, 0x00}, 5, func(t *Tracer) {
+		t.StartAt(AtPC(1))
+		t.StopAt(AtPC(3))
+	})
+
+	lines := strings.Split(strings.TrimSpace(trace), "\n")
+
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "0001") || !strings.HasPrefix(lines[1], "0002") {
+		t.Errorf("Tracer did not trace between the start and stop PCs, got:\n%s", trace)
+	}
+}
+
+func TestTracerStartAndStopAtCycle(t *testing.T) {
+	trace := runTraced([]byte{0x00, 0x00, 0x00, 0x00, 0x00}, 5, func(t *Tracer) {
+		t.StartAt(AtCycle(8))
+		t.StopAt(AtCycle(16))
+	})
+
+	lines := strings.Split(strings.TrimSpace(trace), "\n")
+
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "CYC:8") || !strings.HasSuffix(lines[1], "CYC:12") {
+		t.Errorf("Tracer did not trace between the start and stop cycles, got:\n%s", trace)
+	}
+}
+
+func TestTracerDoesNotRestart(t *testing.T) {
+	// JMP $0000
+	trace := runTraced([]byte{0x00, 0xc3, 0x00, 0x00}, 6, func(t *Tracer) {
+		t.StartAt(AtPC(0))
+		t.StopAt(AtPC(1))
+	})
+
+	if strings.Count(trace, "\n") != 1 {
+		t.Errorf("Tracer restarted after being stopped, got:\n%s", trace)
+	}
+}
+
+type failingWriter struct {
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, errors.New("disk full")
+}
+
+func TestTracerStopsOnWriteError(t *testing.T) {
+	w := &failingWriter{}
+	cpu := createCPUWithProgramLoaded([]byte{0x00, 0x00, 0x00})
+	tracer := NewTracer(w)
+	cpu.SetTracer(tracer)
+
+	cpu.Run()
+	cpu.Run()
+
+	if tracer.Err() == nil || w.writes != 1 {
+		t.Errorf("Tracer did not stop on the first write error")
+	}
+}
+
+func TestParseTraceCondition(t *testing.T) {
+	tData := []struct {
+		value  string
+		pc     uint16
+		cycles uint
+		holds  bool
+	}{
+		{"pc=0x0100", 0x0100, 0, true},
+		{"pc=256", 0x0100, 0, true},
+		{"pc=0x0100", 0x0101, 0, false},
+		{"cycle=1000", 0, 1000, true},
+		{"cycle=1000", 0, 999, false},
+	}
+
+	for _, d := range tData {
+		condition, err := ParseTraceCondition(d.value)
+		if err != nil {
+			t.Errorf("ParseTraceCondition(%q) returned %v", d.value, err)
+			continue
+		}
+
+		if condition(d.pc, d.cycles) != d.holds {
+			t.Errorf("ParseTraceCondition(%q) did not parse the condition correctly", d.value)
+		}
+	}
+
+	for _, value := range []string{"", "pc", "pc=0x10000", "cycle=-1", "sp=0"} {
+		if _, err := ParseTraceCondition(value); err == nil {
+			t.Errorf("ParseTraceCondition(%q) did not return an error", value)
+		}
+	}
+}
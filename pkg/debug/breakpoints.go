@@ -0,0 +1,505 @@
+package debug
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/cpu"
+)
+
+// Access selects which accesses trigger a watchpoint or port breakpoint.
+type Access uint8
+
+const (
+	Read Access = 1 << iota
+	Write
+	ReadWrite = Read | Write
+)
+
+func (a Access) String() string {
+	switch a {
+	case Read:
+		return "read"
+	case Write:
+		return "write"
+	case ReadWrite:
+		return "access"
+	}
+	return "none"
+}
+
+// Watchpoint pauses execution when an instruction accesses Addr. With
+// MatchValue set, it only triggers when the value read or written is Value.
+//
+// Opcode and operand fetches are reads too, so a read watchpoint on code
+// triggers when the code runs.
+type Watchpoint struct {
+	Addr       uint16
+	Access     Access
+	MatchValue bool
+	Value      byte
+}
+
+func (w Watchpoint) matches(addr uint16, access Access, value byte) bool {
+	return w.Addr == addr && w.Access&access != 0 && (!w.MatchValue || w.Value == value)
+}
+
+// PortBreakpoint pauses execution when IN or OUT access Port.
+type PortBreakpoint struct {
+	Port   byte
+	Access Access
+}
+
+type StopKind int
+
+const (
+	StopBreakpoint StopKind = iota
+	StopWatchpoint
+	StopPort
+	StopStep
+	StopPause
+)
+
+// Stop tells why execution was paused. Addr and Value are the address or port
+// and the value of the access that triggered a watchpoint or port breakpoint.
+type Stop struct {
+	Kind   StopKind
+	PC     uint16
+	Access Access
+	Addr   uint16
+	Value  byte
+}
+
+func (s Stop) String() string {
+	switch s.Kind {
+	case StopBreakpoint:
+		return fmt.Sprintf("breakpoint at $%04X", s.PC)
+	case StopWatchpoint:
+		return fmt.Sprintf("watchpoint: %s $%04X = $%02X, pc $%04X", s.Access, s.Addr, s.Value, s.PC)
+	case StopPort:
+		return fmt.Sprintf("port breakpoint: %s port $%02X = $%02X, pc $%04X", s.Access, s.Addr, s.Value, s.PC)
+	case StopStep:
+		return fmt.Sprintf("step to $%04X", s.PC)
+	}
+	return fmt.Sprintf("paused at $%04X", s.PC)
+}
+
+type stepMode int
+
+const (
+	stepNone stepMode = iota
+	stepInstruction
+	stepOver
+	stepOut
+)
+
+func (d *Debugger) AddBreakpoint(addr uint16) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.breakpoints[addr] = true
+}
+
+func (d *Debugger) RemoveBreakpoint(addr uint16) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.breakpoints, addr)
+}
+
+func (d *Debugger) AddWatchpoint(w Watchpoint) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.watchpoints = append(d.watchpoints, w)
+	d.updateHooks()
+}
+
+// RemoveWatchpoint removes a watchpoint equal to w.
+func (d *Debugger) RemoveWatchpoint(w Watchpoint) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i, watchpoint := range d.watchpoints {
+		if watchpoint == w {
+			d.watchpoints = append(d.watchpoints[:i], d.watchpoints[i+1:]...)
+			break
+		}
+	}
+	d.updateHooks()
+}
+
+// RemoveWatchpoints removes every watchpoint on addr.
+func (d *Debugger) RemoveWatchpoints(addr uint16) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	watchpoints := d.watchpoints[:0]
+	for _, w := range d.watchpoints {
+		if w.Addr != addr {
+			watchpoints = append(watchpoints, w)
+		}
+	}
+	d.watchpoints = watchpoints
+	d.updateHooks()
+}
+
+func (d *Debugger) AddPortBreakpoint(p PortBreakpoint) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.portBreakpoints = append(d.portBreakpoints, p)
+	d.updateHooks()
+}
+
+// Breakpoints returns the addresses of the PC breakpoints in ascending order.
+func (d *Debugger) Breakpoints() []uint16 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	addrs := make([]uint16, 0, len(d.breakpoints))
+	for addr := range d.breakpoints {
+		addrs = append(addrs, addr)
+	}
+	slices.Sort(addrs)
+	return addrs
+}
+
+func (d *Debugger) Watchpoints() []Watchpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return slices.Clone(d.watchpoints)
+}
+
+func (d *Debugger) PortBreakpoints() []PortBreakpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return slices.Clone(d.portBreakpoints)
+}
+
+// RemovePortBreakpoints removes every breakpoint on port.
+func (d *Debugger) RemovePortBreakpoints(port byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	portBreakpoints := d.portBreakpoints[:0]
+	for _, p := range d.portBreakpoints {
+		if p.Port != port {
+			portBreakpoints = append(portBreakpoints, p)
+		}
+	}
+	d.portBreakpoints = portBreakpoints
+	d.updateHooks()
+}
+
+// AddBreakpointSpec adds the breakpoint described by spec:
+//
+//	0x1234 or pc=0x1234  break before the instruction at $1234 runs
+//	read=0x2000          break after $2000 is read
+//	write=0x2000:0x05    break after $05 is written to $2000
+//	access=0x2000        break after $2000 is read or written
+//	in=1, out=3          break after IN or OUT access the port
+//
+// Numbers may be written in any base strconv accepts. Addresses may also be
+// symbol names, e.g. DrawSprite or write=alienGrid+3.
+func (d *Debugger) AddBreakpointSpec(spec string) error {
+	kind, value, ok := strings.Cut(spec, "=")
+	if !ok {
+		kind, value = "pc", spec
+	}
+
+	switch kind {
+	case "pc":
+		addr, err := d.ParseAddress(value)
+		if err != nil {
+			return fmt.Errorf("invalid breakpoint %q: %w", spec, err)
+		}
+		d.AddBreakpoint(addr)
+		return nil
+	case "read", "write", "access":
+		addr, match, hasMatch := strings.Cut(value, ":")
+		w := Watchpoint{Access: map[string]Access{"read": Read, "write": Write, "access": ReadWrite}[kind]}
+
+		a, err := d.ParseAddress(addr)
+		if err != nil {
+			return fmt.Errorf("invalid watchpoint %q: %w", spec, err)
+		}
+		w.Addr = a
+
+		if hasMatch {
+			v, err := strconv.ParseUint(match, 0, 8)
+			if err != nil {
+				return fmt.Errorf("invalid watchpoint %q: %w", spec, err)
+			}
+			w.MatchValue = true
+			w.Value = byte(v)
+		}
+
+		d.AddWatchpoint(w)
+		return nil
+	case "in", "out":
+		port, err := strconv.ParseUint(value, 0, 8)
+		if err != nil {
+			return fmt.Errorf("invalid port breakpoint %q: %w", spec, err)
+		}
+
+		access := Read
+		if kind == "out" {
+			access = Write
+		}
+		d.AddPortBreakpoint(PortBreakpoint{Port: byte(port), Access: access})
+		return nil
+	}
+
+	return fmt.Errorf("invalid breakpoint %q, expected pc=, read=, write=, access=, in= or out=", spec)
+}
+
+// CanExecute tells the host whether the next instruction may run. Hosts call
+// it before every instruction and stop running instructions while it returns
+// false. Breakpoints and step-over are checked here, before the instruction
+// at PC runs.
+//
+// Hosts that share the debugger with another goroutine, e.g. a GDB client,
+// must call CanExecute and run instructions inside Exec.
+func (d *Debugger) CanExecute() bool {
+	if d.paused {
+		return false
+	}
+
+	pc := d.cpu.GetPC()
+
+	skip := d.skipBreakpoint
+	d.skipBreakpoint = false
+
+	if !skip && d.breakpoints[pc] {
+		d.pause(Stop{Kind: StopBreakpoint, PC: pc})
+		return false
+	}
+
+	if d.step == stepOver && pc == d.stepTarget && d.cpu.GetSP() >= d.stepSP {
+		d.pause(Stop{Kind: StopStep, PC: pc})
+		return false
+	}
+
+	return true
+}
+
+func (d *Debugger) Paused() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.paused
+}
+
+// LastStop returns why execution was last paused.
+func (d *Debugger) LastStop() Stop {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.stop
+}
+
+// Pause stops execution before the next instruction.
+func (d *Debugger) Pause() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.paused {
+		d.pause(Stop{Kind: StopPause, PC: d.cpu.GetPC()})
+	}
+}
+
+// Resume runs until the next breakpoint or watchpoint. The breakpoint
+// execution is paused on, if any, does not trigger again straight away.
+func (d *Debugger) Resume() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.run(stepNone)
+}
+
+// Step runs a single instruction.
+func (d *Debugger) Step() {
+	d.StepN(1)
+}
+
+// StepN runs n instructions, stopping early at breakpoints and watchpoints.
+func (d *Debugger) StepN(n int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.steps = n
+	d.run(stepInstruction)
+}
+
+// ExecSteps has step run n instructions inside Exec, stopping early at
+// breakpoints and watchpoints like StepN, and leaves execution paused. It is
+// for hosts carrying out a step while the rest of the machine waits.
+func (d *Debugger) ExecSteps(n int, step func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.steps = n
+	d.run(stepInstruction)
+
+	// A halted CPU runs no instructions the step count would go down with
+	for i := 0; i < n && d.CanExecute(); i++ {
+		step()
+	}
+	if !d.paused {
+		d.pause(Stop{Kind: StopStep, PC: d.cpu.GetPC()})
+	}
+}
+
+// StepOver runs a single instruction, running CALLs and RSTs through to the
+// instruction after them.
+func (d *Debugger) StepOver() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	pc := d.cpu.GetPC()
+	if !d.isCall(d.cpu.ReadFromMemory(pc)) {
+		d.steps = 1
+		d.run(stepInstruction)
+		return
+	}
+
+	d.stepTarget = pc + d.cpu.InstructionAt(pc).Size
+	d.stepSP = d.cpu.GetSP()
+	d.run(stepOver)
+}
+
+// StepOut runs until the current subroutine returns to its caller.
+func (d *Debugger) StepOut() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.stepSP = d.cpu.GetSP()
+	d.run(stepOut)
+}
+
+func (d *Debugger) run(mode stepMode) {
+	d.step = mode
+	d.skipBreakpoint = true
+	d.paused = false
+	d.updateHooks()
+}
+
+func (d *Debugger) pause(stop Stop) {
+	d.paused = true
+	d.stop = stop
+	d.step = stepNone
+	d.updateHooks()
+
+	for _, c := range d.subscribers {
+		select {
+		case c <- stop:
+		default:
+		}
+	}
+
+	log.Printf("Paused: %s\n", stop)
+}
+
+// updateHooks registers only the CPU hooks the current breakpoints and step
+// mode need, so the CPU runs at full speed when there are none. Port hooks
+// are always registered to record port activity; they only cost IN and OUT.
+func (d *Debugger) updateHooks() {
+	if d.hooks != nil {
+		d.cpu.RemoveHooks(d.hooks)
+	}
+
+	h := &cpu.Hooks{
+		PortRead:  func(port byte, value byte) { d.portAccess(port, Read, value) },
+		PortWrite: func(port byte, value byte) { d.portAccess(port, Write, value) },
+	}
+	execute := len(d.portBreakpoints) > 0
+
+	if len(d.watchpoints) > 0 {
+		h.MemoryRead = func(addr uint16, value byte) { d.watch(addr, Read, value) }
+		h.MemoryWrite = func(addr uint16, _ byte, value byte) { d.watch(addr, Write, value) }
+		execute = true
+	}
+
+	if d.step == stepInstruction || d.step == stepOut || d.trackingCalls > 0 {
+		h.AfterExecute = d.afterExecute
+		execute = true
+	}
+
+	// Watchpoints report the instruction that made the access, and step-out
+	// and call tracking need to know which instruction just ran.
+	if execute {
+		h.BeforeExecute = d.beforeExecute
+	}
+
+	d.hooks = h
+	d.cpu.AddHooks(h)
+}
+
+func (d *Debugger) beforeExecute(pc uint16, opcode byte) {
+	d.instructionPC = pc
+	d.instructionSP = d.cpu.GetSP()
+	d.opcode = opcode
+}
+
+func (d *Debugger) afterExecute(uint) {
+	if d.trackingCalls > 0 {
+		d.trackCall()
+	}
+
+	if d.paused {
+		return
+	}
+
+	switch d.step {
+	case stepInstruction:
+		d.steps--
+		if d.steps <= 0 {
+			d.pause(Stop{Kind: StopStep, PC: d.cpu.GetPC()})
+		}
+	case stepOut:
+		if d.isReturn(d.opcode) && d.cpu.GetSP() > d.stepSP {
+			d.pause(Stop{Kind: StopStep, PC: d.cpu.GetPC()})
+		}
+	}
+}
+
+func (d *Debugger) watch(addr uint16, access Access, value byte) {
+	if d.paused {
+		return
+	}
+
+	for _, w := range d.watchpoints {
+		if w.matches(addr, access, value) {
+			d.pause(Stop{Kind: StopWatchpoint, PC: d.instructionPC, Access: access, Addr: addr, Value: value})
+			return
+		}
+	}
+}
+
+func (d *Debugger) portAccess(port byte, access Access, value byte) {
+	activity := &d.ports[port]
+	if access == Read {
+		activity.Reads++
+		activity.LastRead = value
+	} else {
+		activity.Writes++
+		activity.LastWritten = value
+	}
+
+	if d.paused {
+		return
+	}
+
+	for _, p := range d.portBreakpoints {
+		if p.Port == port && p.Access&access != 0 {
+			d.pause(Stop{Kind: StopPort, PC: d.instructionPC, Access: access, Addr: uint16(port), Value: value})
+			return
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package debug
+
+import (
+	"testing"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/cpu"
+)
+
+type TestIOBus struct{}
+
+func (tb *TestIOBus) Read(b byte) byte {
+	return b
+}
+
+func (tb *TestIOBus) Write(b1 byte, b2 byte) {}
+
+var subroutineProgram = []byte{
+	0x31, 0x00, 0x24, // LXI SP,$2400
+	0xCD, 0x10, 0x00, // CALL $0010
+	0x3E, 0x01, // MVI A,$01
+	0x76, // HLT
+	0x10: 0x06, 0x02, // $0010: MVI B,$02
+	0xCD, 0x20, 0x00, // CALL $0020
+	0xC9, // RET
+	0x20: 0x0E, 0x03, // $0020: MVI C,$03
+	0xC9, // RET
+}
+
+func createDebuggerWithProgramLoaded(p []byte) (*Debugger, *cpu.Intel8080) {
+	c := cpu.NewIntel8080(&TestIOBus{})
+	c.LoadProgram(p, 0)
+
+	return NewDebugger(c), c
+}
+
+// Runs the way hosts do until the debugger pauses or the CPU halts
+func run(d *Debugger, c *cpu.Intel8080) {
+	for i := 0; i < 1000 && !c.IsHalted() && d.CanExecute(); i++ {
+		c.Run()
+	}
+}
+
+func TestBreakpoint(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(subroutineProgram)
+	d.AddBreakpoint(0x0010)
+
+	run(d, c)
+
+	if !d.Paused() || c.GetPC() != 0x0010 || d.LastStop().Kind != StopBreakpoint {
+		t.Errorf("breakpoint did not pause before the instruction at $0010")
+	}
+
+	if c.GetRegisters()["B"] != 0 {
+		t.Errorf("instruction at the breakpoint was executed")
+	}
+
+	d.Resume()
+	run(d, c)
+
+	if d.Paused() || !c.IsHalted() {
+		t.Errorf("Resume did not run past the breakpoint")
+	}
+}
+
+func TestRemoveBreakpoint(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(subroutineProgram)
+	d.AddBreakpoint(0x0010)
+	d.RemoveBreakpoint(0x0010)
+
+	run(d, c)
+
+	if d.Paused() {
+		t.Errorf("removed breakpoint paused execution")
+	}
+}
+
+func TestStep(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(subroutineProgram)
+	d.AddBreakpoint(0x0010)
+	run(d, c)
+
+	d.Step()
+	run(d, c)
+
+	if !d.Paused() || c.GetPC() != 0x0012 || d.LastStop().Kind != StopStep {
+		t.Errorf("Step did not run a single instruction")
+	}
+
+	d.Step()
+	run(d, c)
+
+	if c.GetPC() != 0x0020 {
+		t.Errorf("Step did not step into the CALL")
+	}
+}
+
+func TestExecSteps(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(subroutineProgram)
+	d.AddBreakpoint(0x0010)
+
+	d.ExecSteps(10, func() { c.Run() })
+	if !d.Paused() || c.GetPC() != 0x0010 || d.LastStop().Kind != StopBreakpoint {
+		t.Errorf("ExecSteps did not stop at the breakpoint, PC is $%04X", c.GetPC())
+	}
+
+	d.ExecSteps(2, func() { c.Run() })
+	if !d.Paused() || c.GetPC() != 0x0020 || d.LastStop().Kind != StopStep {
+		t.Errorf("ExecSteps did not run two instructions, PC is $%04X", c.GetPC())
+	}
+
+	// Runs into HLT, after which the count no longer goes down
+	c.SetPC(0x0006)
+	d.ExecSteps(5, func() { c.Run() })
+	if !d.Paused() || !c.IsHalted() {
+		t.Errorf("ExecSteps did not return once the CPU halted")
+	}
+}
+
+func TestStepOver(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(subroutineProgram)
+	d.AddBreakpoint(0x0012)
+	run(d, c)
+
+	d.StepOver()
+	run(d, c)
+
+	if !d.Paused() || c.GetPC() != 0x0015 {
+		t.Errorf("StepOver did not stop after the CALL")
+	}
+
+	if c.GetRegisters()["C"] != 0x03 {
+		t.Errorf("StepOver did not run the subroutine")
+	}
+
+	d.StepOver()
+	run(d, c)
+
+	if c.GetPC() != 0x0006 {
+		t.Errorf("StepOver did not run a single instruction")
+	}
+}
+
+func TestStepOverStopsAtBreakpointInSubroutine(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(subroutineProgram)
+	d.AddBreakpoint(0x0012)
+	d.AddBreakpoint(0x0022)
+	run(d, c)
+
+	d.StepOver()
+	run(d, c)
+
+	if c.GetPC() != 0x0022 || d.LastStop().Kind != StopBreakpoint {
+		t.Errorf("StepOver did not stop at the breakpoint in the subroutine")
+	}
+}
+
+func TestStepOut(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(subroutineProgram)
+	d.AddBreakpoint(0x0010)
+	run(d, c)
+
+	// The nested CALL $0020 returns first and must not stop the step
+	d.StepOut()
+	run(d, c)
+
+	if !d.Paused() || c.GetPC() != 0x0006 || d.LastStop().Kind != StopStep {
+		t.Errorf("StepOut did not stop at the caller")
+	}
+}
+
+func TestStepOverUndocumentedCall(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded([]byte{
+		0x31, 0x00, 0x24, // LXI SP,$2400
+		0xDD, 0x10, 0x00, // *CALL $0010
+		0x76, // HLT
+		0x10: 0x06, 0x02, // $0010: MVI B,$02
+		0xC9, // RET
+	})
+	d.AddBreakpoint(0x0003)
+	run(d, c)
+
+	d.StepOver()
+	run(d, c)
+
+	if !d.Paused() || c.GetPC() != 0x0006 || c.GetRegisters()["B"] != 0x02 {
+		t.Errorf("StepOver did not run the undocumented CALL through, PC is $%04X", c.GetPC())
+	}
+}
+
+func TestStepOutIgnoresSHLX(t *testing.T) {
+	c := cpu.NewIntel8085(&TestIOBus{})
+	c.LoadProgram([]byte{
+		0x31, 0x00, 0x24, // LXI SP,$2400
+		0xCD, 0x10, 0x00, // CALL $0010
+		0x76, // HLT
+		0x10: 0xC5, // $0010: PUSH B
+		0xC1, // POP B
+		0xD9, // SHLX
+		0xC9, // RET
+	}, 0)
+	d := NewDebugger(c)
+	d.AddBreakpoint(0x0011)
+	run(d, c)
+
+	// SHLX, $D9, is RET on the 8080 but not on the 8085
+	d.StepOut()
+	run(d, c)
+
+	if !d.Paused() || c.GetPC() != 0x0006 {
+		t.Errorf("StepOut did not stop at the caller, PC is $%04X", c.GetPC())
+	}
+}
+
+func TestWriteWatchpointWithValue(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded([]byte{
+		0x3E, 0x01, // MVI A,$01
+		0x32, 0x00, 0x20, // STA $2000
+		0x3E, 0x02, // MVI A,$02
+		0x32, 0x00, 0x20, // STA $2000
+		0x76, // HLT
+	})
+	d.AddWatchpoint(Watchpoint{Addr: 0x2000, Access: Write, MatchValue: true, Value: 0x02})
+
+	run(d, c)
+
+	stop := d.LastStop()
+	if !d.Paused() || stop.Kind != StopWatchpoint || stop.PC != 0x0007 || stop.Value != 0x02 {
+		t.Errorf("watchpoint did not pause on the write of $02, got %s", stop)
+	}
+
+	if c.GetPC() != 0x000A {
+		t.Errorf("watchpoint did not pause after the writing instruction")
+	}
+}
+
+func TestReadWatchpoint(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded([]byte{
+		0x32, 0x00, 0x20, // STA $2000
+		0x3A, 0x00, 0x20, // LDA $2000
+		0x76, // HLT
+	})
+	d.AddWatchpoint(Watchpoint{Addr: 0x2000, Access: Read})
+
+	run(d, c)
+
+	if !d.Paused() || d.LastStop().PC != 0x0003 || c.GetPC() != 0x0006 {
+		t.Errorf("read watchpoint did not pause on LDA, got %s", d.LastStop())
+	}
+}
+
+func TestRemoveWatchpoints(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded([]byte{0x32, 0x00, 0x20, 0x76}) // STA $2000; HLT
+	d.AddWatchpoint(Watchpoint{Addr: 0x2000, Access: ReadWrite})
+	d.RemoveWatchpoints(0x2000)
+
+	run(d, c)
+
+	if d.Paused() {
+		t.Errorf("removed watchpoint paused execution")
+	}
+}
+
+func TestPortBreakpoints(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded([]byte{
+		0xDB, 0x01, // IN $01
+		0xD3, 0x03, // OUT $03
+		0x76, // HLT
+	})
+	d.AddPortBreakpoint(PortBreakpoint{Port: 0x01, Access: Read})
+	d.AddPortBreakpoint(PortBreakpoint{Port: 0x03, Access: Write})
+
+	run(d, c)
+
+	stop := d.LastStop()
+	if !d.Paused() || stop.Kind != StopPort || stop.Access != Read || stop.Addr != 0x01 || c.GetPC() != 0x0002 {
+		t.Errorf("port breakpoint did not pause on IN, got %s", stop)
+	}
+
+	d.Resume()
+	run(d, c)
+
+	stop = d.LastStop()
+	if !d.Paused() || stop.Access != Write || stop.Addr != 0x03 || stop.PC != 0x0002 {
+		t.Errorf("port breakpoint did not pause on OUT, got %s", stop)
+	}
+}
+
+func TestPause(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(subroutineProgram)
+	d.Pause()
+
+	run(d, c)
+
+	if c.GetPC() != 0 || d.LastStop().Kind != StopPause {
+		t.Errorf("Pause did not stop execution")
+	}
+}
+
+func TestAddBreakpointSpec(t *testing.T) {
+	d, _ := createDebuggerWithProgramLoaded(nil)
+
+	for _, spec := range []string{"0x10", "pc=16", "read=0x2000", "write=0x2000:0x05", "access=0x2001", "in=1", "out=0x03"} {
+		if err := d.AddBreakpointSpec(spec); err != nil {
+			t.Errorf("AddBreakpointSpec(%q) returned %v", spec, err)
+		}
+	}
+
+	if !d.breakpoints[0x10] || len(d.breakpoints) != 1 {
+		t.Errorf("AddBreakpointSpec did not add the PC breakpoint")
+	}
+
+	expected := Watchpoint{Addr: 0x2000, Access: Write, MatchValue: true, Value: 0x05}
+	if len(d.watchpoints) != 3 || d.watchpoints[1] != expected || d.watchpoints[2].Access != ReadWrite {
+		t.Errorf("AddBreakpointSpec did not add the watchpoints correctly, got %v", d.watchpoints)
+	}
+
+	if len(d.portBreakpoints) != 2 || d.portBreakpoints[1] != (PortBreakpoint{Port: 0x03, Access: Write}) {
+		t.Errorf("AddBreakpointSpec did not add the port breakpoints correctly, got %v", d.portBreakpoints)
+	}
+
+	for _, spec := range []string{"", "pc=0x10000", "write=0x2000:0x100", "in=256", "sp=0"} {
+		if err := d.AddBreakpointSpec(spec); err == nil {
+			t.Errorf("AddBreakpointSpec(%q) did not return an error", spec)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package debug
+
+import "github.com/gaoliveira21/intel8080-space-invaders/pkg/opcode"
+
+// CallFrame is a subroutine call that has not returned yet.
+type CallFrame struct {
+	// Addr is the address of the subroutine, Caller that of the CALL or RST
+	// that entered it.
+	Addr   uint16
+	Caller uint16
+	// SP points at the return address pushed by the call.
+	SP uint16
+}
+
+// TrackCalls starts recording the call stack until stop is called. Calls
+// made before, and interrupt routines, are not seen; a call whose return
+// address is popped or dropped by moving SP is treated as returned.
+func (d *Debugger) TrackCalls() (stop func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.trackingCalls++
+	d.updateHooks()
+
+	return func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		d.trackingCalls--
+		if d.trackingCalls == 0 {
+			d.callStack = nil
+		}
+		d.updateHooks()
+	}
+}
+
+// CallStack returns the calls that have not returned yet, innermost first.
+func (d *Debugger) CallStack() []CallFrame {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	frames := make([]CallFrame, len(d.callStack))
+	for i, f := range d.callStack {
+		frames[len(frames)-1-i] = f
+	}
+	return frames
+}
+
+func (d *Debugger) trackCall() {
+	sp := d.cpu.GetSP()
+
+	for len(d.callStack) > 0 && d.callStack[len(d.callStack)-1].SP < sp {
+		d.callStack = d.callStack[:len(d.callStack)-1]
+	}
+
+	// Conditional calls that are not taken leave SP alone
+	if d.isCall(d.opcode) && sp == d.instructionSP-2 {
+		d.callStack = append(d.callStack, CallFrame{Addr: d.cpu.GetPC(), Caller: d.instructionPC, SP: sp})
+	}
+}
+
+// isCall reports whether op is a CALL, Ccc or RST on the CPU, including the
+// undocumented aliases and RSTV.
+func (d *Debugger) isCall(op byte) bool {
+	return d.cpu.Opcodes()[op].Flow == opcode.Call
+}
+
+// isReturn reports whether op is a RET or Rcc on the CPU.
+func (d *Debugger) isReturn(op byte) bool {
+	return d.cpu.Opcodes()[op].Flow == opcode.Return
+}
@@ -0,0 +1,84 @@
+package debug
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/cpu"
+)
+
+func TestCallStack(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(subroutineProgram)
+	stop := d.TrackCalls()
+	d.AddBreakpoint(0x0020)
+
+	run(d, c)
+
+	expected := []CallFrame{
+		{Addr: 0x0020, Caller: 0x0012, SP: 0x23FC},
+		{Addr: 0x0010, Caller: 0x0003, SP: 0x23FE},
+	}
+	if fmt.Sprint(d.CallStack()) != fmt.Sprint(expected) {
+		t.Errorf("CallStack did not record the calls, got %v", d.CallStack())
+	}
+
+	d.StepOut()
+	run(d, c)
+
+	if fmt.Sprint(d.CallStack()) != fmt.Sprint(expected[1:]) {
+		t.Errorf("CallStack did not drop the returned call, got %v", d.CallStack())
+	}
+
+	stop()
+	if len(d.CallStack()) != 0 {
+		t.Errorf("CallStack was not cleared when tracking stopped")
+	}
+}
+
+func TestCallStackUndocumentedCallAndRSTV(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded([]byte{
+		0x31, 0x00, 0x24, // LXI SP,$2400
+		0xDD, 0x10, 0x00, // *CALL $0010
+		0x76, // HLT
+		0x10: 0x76, // $0010: HLT
+	})
+	d.TrackCalls()
+	run(d, c)
+
+	if frames := d.CallStack(); len(frames) != 1 || frames[0].Addr != 0x0010 || frames[0].Caller != 0x0003 {
+		t.Errorf("CallStack did not record the undocumented CALL, got %v", frames)
+	}
+
+	c8085 := cpu.NewIntel8085(&TestIOBus{})
+	c8085.LoadProgram([]byte{
+		0x31, 0x00, 0x24, // LXI SP,$2400
+		0x21, 0x02, 0x00, // LXI H,$0002
+		0xE5, // PUSH H
+		0xF1, // POP PSW, sets V
+		0xCB, // RSTV
+		0x40: 0x76, // $0040: HLT
+	}, 0)
+	d = NewDebugger(c8085)
+	d.TrackCalls()
+	run(d, c8085)
+
+	if frames := d.CallStack(); len(frames) != 1 || frames[0].Addr != 0x0040 || frames[0].Caller != 0x0008 {
+		t.Errorf("CallStack did not record RSTV, got %v", frames)
+	}
+}
+
+func TestCallStackIgnoresCallsNotTaken(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded([]byte{
+		0x31, 0x00, 0x24, // LXI SP,$2400
+		0xB7,             // ORA A
+		0xC4, 0x10, 0x00, // CNZ $0010
+		0x76, // HLT
+	})
+	d.TrackCalls()
+
+	run(d, c)
+
+	if len(d.CallStack()) != 0 {
+		t.Errorf("CallStack recorded a call that was not taken")
+	}
+}
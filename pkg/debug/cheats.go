@@ -0,0 +1,194 @@
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/cheat"
+)
+
+// Candidates returned with a search, the count is always complete
+const searchCandidatesLimit = 256
+
+type SearchResponse struct {
+	ID         int               `json:"id"`
+	Count      int               `json:"count"`
+	Candidates []cheat.Candidate `json:"candidates"`
+}
+
+// SetCheats lets clients of the debug HTTP API change the cheats the host
+// applies. The host must apply them inside Exec.
+func (d *Debugger) SetCheats(l *cheat.List) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.cheats = l
+}
+
+func searchResponse(id int, s *cheat.Search) *SearchResponse {
+	return &SearchResponse{ID: id, Count: s.Count(), Candidates: s.Candidates(searchCandidatesLimit)}
+}
+
+// Parses an optional query parameter in any base strconv accepts
+func queryNumber(r *http.Request, name string, def uint64, bitSize int) (uint64, error) {
+	if !r.URL.Query().Has(name) {
+		return def, nil
+	}
+
+	n, err := strconv.ParseUint(r.URL.Query().Get(name), 0, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", name, r.URL.Query().Get(name))
+	}
+	return n, nil
+}
+
+// Parses an optional address query parameter, a number or a symbol name
+func (d *Debugger) queryAddress(r *http.Request, name string, def uint16) (uint16, error) {
+	if !r.URL.Query().Has(name) {
+		return def, nil
+	}
+	return d.ParseAddress(r.URL.Query().Get(name))
+}
+
+func (d *Debugger) startSearch(w http.ResponseWriter, r *http.Request) {
+	start, err := d.queryAddress(r, "start", 0x0000)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	end, err := d.queryAddress(r, "end", 0xFFFF)
+	if err != nil || end < start {
+		writeError(w, http.StatusBadRequest, "end must be an address after start")
+		return
+	}
+
+	d.mu.Lock()
+	s := cheat.NewSearch(d.cpu.GetMemory(), start, end)
+	d.nextSearch++
+	id := d.nextSearch
+	d.searches[id] = s
+	res := searchResponse(id, s)
+	d.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, res)
+}
+
+// Looks the search up, replying with an error if there is none
+func (d *Debugger) search(w http.ResponseWriter, r *http.Request) (int, *cheat.Search) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if s, ok := d.searches[id]; err == nil && ok {
+		return id, s
+	}
+
+	writeError(w, http.StatusNotFound, fmt.Sprintf("no search %s", r.PathValue("id")))
+	return 0, nil
+}
+
+func (d *Debugger) getSearch(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if id, s := d.search(w, r); s != nil {
+		writeJSON(w, http.StatusOK, searchResponse(id, s))
+	}
+}
+
+// filterSearch narrows a search with the condition query parameter, compared
+// to the value parameter for equal.
+func (d *Debugger) filterSearch(w http.ResponseWriter, r *http.Request) {
+	condition, err := cheat.ParseCondition(r.URL.Query().Get("condition"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if condition == cheat.Equal && !r.URL.Query().Has("value") {
+		writeError(w, http.StatusBadRequest, "equal needs a value")
+		return
+	}
+
+	value, err := queryNumber(r, "value", 0, 8)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if id, s := d.search(w, r); s != nil {
+		s.Filter(d.cpu.GetMemory(), condition, byte(value))
+		writeJSON(w, http.StatusOK, searchResponse(id, s))
+	}
+}
+
+func (d *Debugger) deleteSearch(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if id, s := d.search(w, r); s != nil {
+		delete(d.searches, id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Looks the cheat list up, replying with an error if the host has none
+func (d *Debugger) cheatList(w http.ResponseWriter) *cheat.List {
+	if d.cheats == nil {
+		writeError(w, http.StatusNotFound, "cheats are not supported by this host")
+	}
+	return d.cheats
+}
+
+func (d *Debugger) getCheats(w http.ResponseWriter, _ *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if l := d.cheatList(w); l != nil {
+		w.Header().Set("Content-Type", "application/json")
+		l.Save(w)
+	}
+}
+
+// addCheat adds the cheat in the body, e.g. {"name": "Lives", "address":
+// "0x21FF", "value": 9, "mode": "freeze"}.
+func (d *Debugger) addCheat(w http.ResponseWriter, r *http.Request) {
+	var c cheat.Cheat
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if l := d.cheatList(w); l != nil {
+		if err := l.Add(c); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		l.Save(w)
+	}
+}
+
+func (d *Debugger) removeCheat(w http.ResponseWriter, r *http.Request) {
+	addr, err := d.ParseAddress(r.PathValue("addr"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if l := d.cheatList(w); l != nil {
+		l.Remove(addr)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
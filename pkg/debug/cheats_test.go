@@ -0,0 +1,127 @@
+package debug
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/cheat"
+)
+
+func request(h http.Handler, method string, path string, body string) *httptest.ResponseRecorder {
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, r))
+	return w
+}
+
+func decodeSearch(t *testing.T, w *httptest.ResponseRecorder, statusCode int) SearchResponse {
+	if w.Code != statusCode {
+		t.Fatalf("Search request did not reply with %d, got %d %s", statusCode, w.Code, w.Body.String())
+	}
+
+	var res SearchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestSearchSession(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(portsProgram)
+	h := d.Handler()
+	c.WriteIntoMemory(0x21FF, 3)
+	c.WriteIntoMemory(0x2100, 3)
+
+	res := decodeSearch(t, request(h, http.MethodPost, "/cheats/searches?start=0x2000&end=0x23FF", ""), http.StatusCreated)
+	if res.ID != 1 || res.Count != 0x400 || len(res.Candidates) != searchCandidatesLimit {
+		t.Errorf("startSearch did not start a search over the range, got %d %d", res.ID, res.Count)
+	}
+
+	res = decodeSearch(t, request(h, http.MethodPost, "/cheats/searches/1/filter?condition=equal&value=3", ""), http.StatusOK)
+	if res.Count != 2 {
+		t.Errorf("filterSearch did not keep the addresses holding 3, got %d", res.Count)
+	}
+
+	c.WriteIntoMemory(0x21FF, 2)
+	res = decodeSearch(t, request(h, http.MethodPost, "/cheats/searches/1/filter?condition=decreased", ""), http.StatusOK)
+	if res.Count != 1 || res.Candidates[0] != (cheat.Candidate{Addr: 0x21FF, Value: 2}) {
+		t.Errorf("filterSearch did not find the decreased value, got %+v", res.Candidates)
+	}
+
+	res = decodeSearch(t, request(h, http.MethodGet, "/cheats/searches/1", ""), http.StatusOK)
+	if res.Count != 1 {
+		t.Errorf("getSearch did not return the search, got %+v", res)
+	}
+
+	if w := request(h, http.MethodDelete, "/cheats/searches/1", ""); w.Code != http.StatusNoContent {
+		t.Errorf("deleteSearch did not delete the search, got %d", w.Code)
+	}
+
+	if w := request(h, http.MethodGet, "/cheats/searches/1", ""); w.Code != http.StatusNotFound {
+		t.Errorf("getSearch found a deleted search, got %d", w.Code)
+	}
+}
+
+func TestSearchRejectsBadRequests(t *testing.T) {
+	d, _ := createDebuggerWithProgramLoaded(portsProgram)
+	h := d.Handler()
+	request(h, http.MethodPost, "/cheats/searches", "")
+
+	for _, r := range []struct {
+		path       string
+		statusCode int
+	}{
+		{"/cheats/searches?start=0x3000&end=0x2000", http.StatusBadRequest},
+		{"/cheats/searches?start=0x10000", http.StatusBadRequest},
+		{"/cheats/searches/1/filter?condition=bigger", http.StatusBadRequest},
+		{"/cheats/searches/1/filter?condition=equal", http.StatusBadRequest},
+		{"/cheats/searches/1/filter?condition=equal&value=256", http.StatusBadRequest},
+		{"/cheats/searches/2/filter?condition=changed", http.StatusNotFound},
+	} {
+		if w := request(h, http.MethodPost, r.path, ""); w.Code != r.statusCode {
+			t.Errorf("POST %s did not reply with %d, got %d", r.path, r.statusCode, w.Code)
+		}
+	}
+}
+
+func TestCheats(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(portsProgram)
+	h := d.Handler()
+
+	if w := request(h, http.MethodGet, "/cheats", ""); w.Code != http.StatusNotFound {
+		t.Errorf("getCheats did not report that the host has no cheats, got %d", w.Code)
+	}
+
+	l := &cheat.List{}
+	d.SetCheats(l)
+
+	w := request(h, http.MethodPost, "/cheats", `{"name": "Lives", "address": "0x21FF", "value": 9, "mode": "freeze"}`)
+	if w.Code != http.StatusCreated || len(l.Cheats()) != 1 {
+		t.Errorf("addCheat did not add the cheat, got %d %s", w.Code, w.Body.String())
+	}
+
+	l.Apply(c)
+	if c.ReadFromMemory(0x21FF) != 9 {
+		t.Errorf("the cheat added was not applied")
+	}
+
+	if w := request(h, http.MethodPost, "/cheats", `{"name": "Lives", "address": "0x21FF", "value": 9}`); w.Code != http.StatusBadRequest {
+		t.Errorf("addCheat did not reject a cheat without a mode, got %d", w.Code)
+	}
+
+	if w := request(h, http.MethodGet, "/cheats", ""); !strings.Contains(w.Body.String(), `"address": "0x21FF"`) {
+		t.Errorf("getCheats did not list the cheats, got %s", w.Body.String())
+	}
+
+	if w := request(h, http.MethodDelete, "/cheats/0x21FF", ""); w.Code != http.StatusNoContent || len(l.Cheats()) != 0 {
+		t.Errorf("removeCheat did not remove the cheat, got %d", w.Code)
+	}
+}
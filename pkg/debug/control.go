@@ -0,0 +1,74 @@
+package debug
+
+import "context"
+
+// CommandKind is an execution control request from a debug client.
+type CommandKind int
+
+const (
+	CommandPause CommandKind = iota
+	CommandResume
+	CommandStep
+	CommandStepFrame
+	CommandReset
+	CommandInput
+)
+
+func (k CommandKind) String() string {
+	switch k {
+	case CommandPause:
+		return "pause"
+	case CommandResume:
+		return "resume"
+	case CommandStep:
+		return "step"
+	case CommandStepFrame:
+		return "step-frame"
+	case CommandReset:
+		return "reset"
+	case CommandInput:
+		return "input"
+	}
+	return "unknown"
+}
+
+// Command is handed to the host, which owns the machine and carries it out
+// between frames. The host must call Done once it has.
+type Command struct {
+	Kind CommandKind
+	// Instructions to run for CommandStep
+	Count int
+	// Cabinet control pressed or released for CommandInput, e.g. "coin"
+	Key     string
+	Pressed bool
+
+	done chan struct{}
+}
+
+func (c Command) Done() {
+	close(c.done)
+}
+
+// Commands returns the channel control commands are sent on. Hosts that do
+// not receive from it do not support remote execution control.
+func (d *Debugger) Commands() <-chan Command {
+	return d.commands
+}
+
+// send hands a command to the host and waits until it has been carried out.
+func (d *Debugger) send(ctx context.Context, kind CommandKind, count int) error {
+	cmd := Command{Kind: kind, Count: count, done: make(chan struct{})}
+
+	select {
+	case d.commands <- cmd:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-cmd.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
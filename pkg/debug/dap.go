@@ -0,0 +1,535 @@
+package debug
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"strconv"
+)
+
+// The Debug Adapter Protocol, see
+// https://microsoft.github.io/debug-adapter-protocol/specification
+//
+// The CPU is the only thread. There is no source code, so clients work on
+// disassembly: stack frames carry instruction pointer references, breakpoints
+// are instruction breakpoints and the code is read with disassemble requests.
+
+const dapThreadID = 1
+
+// Variable references of the scopes
+const (
+	dapRegisters = iota + 1
+	dapFlags
+)
+
+type dapRequest struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+type dapResponse struct {
+	Seq        int    `json:"seq"`
+	Type       string `json:"type"`
+	RequestSeq int    `json:"request_seq"`
+	Success    bool   `json:"success"`
+	Command    string `json:"command"`
+	Message    string `json:"message,omitempty"`
+	Body       any    `json:"body,omitempty"`
+}
+
+type dapEvent struct {
+	Seq   int    `json:"seq"`
+	Type  string `json:"type"`
+	Event string `json:"event"`
+	Body  any    `json:"body,omitempty"`
+}
+
+type dapStackFrame struct {
+	ID                          int    `json:"id"`
+	Name                        string `json:"name"`
+	Line                        int    `json:"line"`
+	Column                      int    `json:"column"`
+	InstructionPointerReference string `json:"instructionPointerReference"`
+}
+
+type dapVariable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	VariablesReference int    `json:"variablesReference"`
+	MemoryReference    string `json:"memoryReference,omitempty"`
+}
+
+type dapInstruction struct {
+	Address          string `json:"address"`
+	InstructionBytes string `json:"instructionBytes,omitempty"`
+	Instruction      string `json:"instruction"`
+	Symbol           string `json:"symbol,omitempty"`
+	PresentationHint string `json:"presentationHint,omitempty"`
+}
+
+type dapBreakpoint struct {
+	Verified             bool   `json:"verified"`
+	Message              string `json:"message,omitempty"`
+	InstructionReference string `json:"instructionReference,omitempty"`
+}
+
+// StartDAPServer listens on addr, e.g. "localhost:4711", and serves DAP
+// clients until listening fails.
+func (d *Debugger) StartDAPServer(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("DAP server listening on %s\n", l.Addr())
+	return d.ServeDAP(l)
+}
+
+// ServeDAP serves DAP clients connecting to l, one at a time. Execution is
+// resumed when a client disconnects. It returns when l is closed.
+func (d *Debugger) ServeDAP(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		log.Printf("DAP client %s connected\n", conn.RemoteAddr())
+		s := &dapSession{d: d, w: conn}
+		s.serve(conn)
+		conn.Close()
+		log.Printf("DAP client %s disconnected\n", conn.RemoteAddr())
+	}
+}
+
+type dapSession struct {
+	d   *Debugger
+	w   io.Writer
+	seq int
+
+	// breakpoints set by the client, replaced on every request
+	breakpoints []uint16
+	done        bool
+}
+
+func (s *dapSession) serve(r io.Reader) {
+	stops, cancel := s.d.Subscribe()
+	defer cancel()
+
+	stopTracking := s.d.TrackCalls()
+	defer stopTracking()
+
+	defer func() {
+		for _, addr := range s.breakpoints {
+			s.d.RemoveBreakpoint(addr)
+		}
+		s.d.Resume()
+	}()
+
+	requests := make(chan dapRequest)
+	done := make(chan struct{})
+	defer close(done)
+	go readDAPRequests(bufio.NewReader(r), requests, done)
+
+	for !s.done {
+		select {
+		case req, ok := <-requests:
+			if !ok {
+				return
+			}
+			s.handle(req)
+		case stop := <-stops:
+			s.stopped(stop)
+		}
+	}
+}
+
+func readDAPRequests(r *bufio.Reader, requests chan<- dapRequest, done <-chan struct{}) {
+	defer close(requests)
+
+	headers := textproto.NewReader(r)
+	for {
+		header, err := headers.ReadMIMEHeader()
+		if err != nil {
+			return
+		}
+
+		length, err := strconv.Atoi(header.Get("Content-Length"))
+		if err != nil {
+			return
+		}
+
+		content := make([]byte, length)
+		if _, err := io.ReadFull(r, content); err != nil {
+			return
+		}
+
+		var req dapRequest
+		if err := json.Unmarshal(content, &req); err != nil {
+			log.Println("Invalid DAP message", err)
+			continue
+		}
+
+		select {
+		case requests <- req:
+		case <-done:
+			return
+		}
+	}
+}
+
+func (s *dapSession) write(message any) {
+	content, _ := json.Marshal(message)
+	fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n%s", len(content), content)
+}
+
+func (s *dapSession) respond(req dapRequest, body any) {
+	s.seq++
+	s.write(dapResponse{Seq: s.seq, Type: "response", RequestSeq: req.Seq, Success: true, Command: req.Command, Body: body})
+}
+
+func (s *dapSession) fail(req dapRequest, message string) {
+	s.seq++
+	s.write(dapResponse{Seq: s.seq, Type: "response", RequestSeq: req.Seq, Command: req.Command, Message: message})
+}
+
+func (s *dapSession) event(event string, body any) {
+	s.seq++
+	s.write(dapEvent{Seq: s.seq, Type: "event", Event: event, Body: body})
+}
+
+func (s *dapSession) stopped(stop Stop) {
+	reason := map[StopKind]string{
+		StopBreakpoint: "instruction breakpoint",
+		StopWatchpoint: "data breakpoint",
+		StopPort:       "breakpoint",
+		StopStep:       "step",
+		StopPause:      "pause",
+	}[stop.Kind]
+
+	s.event("stopped", map[string]any{
+		"reason":            reason,
+		"description":       stop.String(),
+		"threadId":          dapThreadID,
+		"allThreadsStopped": true,
+	})
+}
+
+func (s *dapSession) handle(req dapRequest) {
+	switch req.Command {
+	case "initialize":
+		s.respond(req, map[string]bool{
+			"supportsConfigurationDoneRequest": true,
+			"supportsInstructionBreakpoints":   true,
+			"supportsReadMemoryRequest":        true,
+			"supportsDisassembleRequest":       true,
+			"supportsSteppingGranularity":      true,
+		})
+		s.event("initialized", nil)
+	case "launch", "attach", "setExceptionBreakpoints":
+		s.respond(req, nil)
+	case "configurationDone":
+		s.respond(req, nil)
+		// Tell the client if it attached to a paused game
+		if s.d.Paused() {
+			s.stopped(s.d.LastStop())
+		}
+	case "setBreakpoints":
+		var args struct {
+			Breakpoints []struct{} `json:"breakpoints"`
+		}
+		json.Unmarshal(req.Arguments, &args)
+
+		breakpoints := make([]dapBreakpoint, len(args.Breakpoints))
+		for i := range breakpoints {
+			breakpoints[i].Message = "no source code, set breakpoints in the disassembly"
+		}
+		s.respond(req, map[string]any{"breakpoints": breakpoints})
+	case "setInstructionBreakpoints":
+		s.setInstructionBreakpoints(req)
+	case "threads":
+		s.respond(req, map[string]any{
+			"threads": []map[string]any{{"id": dapThreadID, "name": "Intel 8080"}},
+		})
+	case "stackTrace":
+		s.stackTrace(req)
+	case "scopes":
+		s.respond(req, map[string]any{
+			"scopes": []map[string]any{
+				{"name": "Registers", "variablesReference": dapRegisters, "expensive": false},
+				{"name": "Flags", "variablesReference": dapFlags, "expensive": false},
+			},
+		})
+	case "variables":
+		s.variables(req)
+	case "readMemory":
+		s.readMemory(req)
+	case "disassemble":
+		s.disassemble(req)
+	case "continue":
+		s.respond(req, map[string]bool{"allThreadsContinued": true})
+		s.d.Resume()
+	case "next":
+		s.respond(req, nil)
+		s.d.StepOver()
+	case "stepIn":
+		s.respond(req, nil)
+		s.d.Step()
+	case "stepOut":
+		s.respond(req, nil)
+		s.d.StepOut()
+	case "pause":
+		s.respond(req, nil)
+		s.d.Pause()
+	case "disconnect":
+		s.respond(req, nil)
+		s.done = true
+	default:
+		s.fail(req, fmt.Sprintf("unsupported request %q", req.Command))
+	}
+}
+
+func (s *dapSession) setInstructionBreakpoints(req dapRequest) {
+	var args struct {
+		Breakpoints []struct {
+			InstructionReference string `json:"instructionReference"`
+			Offset               int    `json:"offset"`
+		} `json:"breakpoints"`
+	}
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		s.fail(req, err.Error())
+		return
+	}
+
+	for _, addr := range s.breakpoints {
+		s.d.RemoveBreakpoint(addr)
+	}
+	s.breakpoints = nil
+
+	breakpoints := make([]dapBreakpoint, len(args.Breakpoints))
+	for i, b := range args.Breakpoints {
+		ref, err := s.d.ParseAddress(b.InstructionReference)
+		addr := int(ref) + b.Offset
+		if err != nil || addr < 0 || addr > 0xFFFF {
+			breakpoints[i].Message = fmt.Sprintf("invalid address %q", b.InstructionReference)
+			continue
+		}
+
+		s.d.AddBreakpoint(uint16(addr))
+		s.breakpoints = append(s.breakpoints, uint16(addr))
+		breakpoints[i] = dapBreakpoint{Verified: true, InstructionReference: fmt.Sprintf("0x%04X", addr)}
+	}
+
+	s.respond(req, map[string]any{"breakpoints": breakpoints})
+}
+
+// stackTrace reports the current instruction and then the CALL of every
+// subroutine being run. Frames are named after the symbol of the subroutine
+// they are in, or its address.
+func (s *dapSession) stackTrace(req dapRequest) {
+	var args struct {
+		StartFrame int `json:"startFrame"`
+		Levels     int `json:"levels"`
+	}
+	json.Unmarshal(req.Arguments, &args)
+
+	calls := s.d.CallStack()
+	symbols := s.d.Symbols()
+	var pc uint16
+	s.d.Exec(func() { pc = s.d.cpu.GetPC() })
+
+	frames := make([]dapStackFrame, len(calls)+1)
+	for i := range frames {
+		frames[i] = dapStackFrame{ID: i, Name: "main", InstructionPointerReference: fmt.Sprintf("0x%04X", pc)}
+		if i < len(calls) {
+			frames[i].Name = fmt.Sprintf("sub_%04X", calls[i].Addr)
+			if sym, ok := symbols.At(calls[i].Addr); ok {
+				frames[i].Name = sym.Name
+			}
+			pc = calls[i].Caller
+		}
+	}
+
+	total := len(frames)
+	frames = frames[min(args.StartFrame, total):]
+	if args.Levels > 0 && args.Levels < len(frames) {
+		frames = frames[:args.Levels]
+	}
+
+	s.respond(req, map[string]any{"stackFrames": frames, "totalFrames": total})
+}
+
+func (s *dapSession) variables(req dapRequest) {
+	var args struct {
+		VariablesReference int `json:"variablesReference"`
+	}
+	json.Unmarshal(req.Arguments, &args)
+
+	var r map[string]byte
+	var pc, sp uint16
+	var flags byte
+	s.d.Exec(func() {
+		r = s.d.cpu.GetRegisters()
+		pc, sp = s.d.cpu.GetPC(), s.d.cpu.GetSP()
+		flags = s.d.cpu.GetFlags()
+	})
+
+	variables := []dapVariable{}
+	switch args.VariablesReference {
+	case dapRegisters:
+		for _, name := range []string{"A", "B", "C", "D", "E", "H", "L"} {
+			variables = append(variables, dapVariable{Name: name, Value: fmt.Sprintf("0x%02X", r[name])})
+		}
+
+		pairs := []struct {
+			name  string
+			value uint16
+		}{
+			{"BC", uint16(r["B"])<<8 | uint16(r["C"])},
+			{"DE", uint16(r["D"])<<8 | uint16(r["E"])},
+			{"HL", uint16(r["H"])<<8 | uint16(r["L"])},
+			{"SP", sp},
+			{"PC", pc},
+		}
+		for _, p := range pairs {
+			value := fmt.Sprintf("0x%04X", p.value)
+			variables = append(variables, dapVariable{Name: p.name, Value: value, MemoryReference: value})
+		}
+	case dapFlags:
+		for _, f := range []struct {
+			name string
+			bit  uint
+		}{{"S", 7}, {"Z", 6}, {"AC", 4}, {"P", 2}, {"CY", 0}} {
+			variables = append(variables, dapVariable{Name: f.name, Value: strconv.Itoa(int(flags >> f.bit & 1))})
+		}
+	}
+
+	s.respond(req, map[string]any{"variables": variables})
+}
+
+func (s *dapSession) readMemory(req dapRequest) {
+	var args struct {
+		MemoryReference string `json:"memoryReference"`
+		Offset          int    `json:"offset"`
+		Count           int    `json:"count"`
+	}
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		s.fail(req, err.Error())
+		return
+	}
+
+	ref, err := strconv.ParseUint(args.MemoryReference, 0, 16)
+	if err != nil {
+		s.fail(req, fmt.Sprintf("invalid memory reference %q", args.MemoryReference))
+		return
+	}
+
+	addr := int(ref) + args.Offset
+	count := max(0, min(args.Count, 0x10000-addr))
+	if addr < 0 || addr > 0xFFFF {
+		s.respond(req, map[string]any{"address": args.MemoryReference, "unreadableBytes": args.Count})
+		return
+	}
+
+	data := make([]byte, count)
+	s.d.Exec(func() {
+		for i := range data {
+			data[i] = s.d.cpu.ReadFromMemory(uint16(addr + i))
+		}
+	})
+
+	body := map[string]any{"address": fmt.Sprintf("0x%04X", addr), "data": base64.StdEncoding.EncodeToString(data)}
+	if count < args.Count {
+		body["unreadableBytes"] = args.Count - count
+	}
+	s.respond(req, body)
+}
+
+func (s *dapSession) disassemble(req dapRequest) {
+	var args struct {
+		MemoryReference   string `json:"memoryReference"`
+		Offset            int    `json:"offset"`
+		InstructionOffset int    `json:"instructionOffset"`
+		InstructionCount  int    `json:"instructionCount"`
+	}
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		s.fail(req, err.Error())
+		return
+	}
+
+	ref, err := strconv.ParseUint(args.MemoryReference, 0, 16)
+	if err != nil {
+		s.fail(req, fmt.Sprintf("invalid memory reference %q", args.MemoryReference))
+		return
+	}
+
+	var instructions []dapInstruction
+	s.d.Exec(func() {
+		for _, addr := range s.instructionAddresses(int(ref)+args.Offset, args.InstructionOffset, args.InstructionCount) {
+			if addr < 0 || addr > 0xFFFF {
+				instructions = append(instructions, dapInstruction{Address: "0x0000", Instruction: "??", PresentationHint: "invalid"})
+				continue
+			}
+
+			i := s.d.instructionAt(uint16(addr))
+			instruction := dapInstruction{
+				Address:          fmt.Sprintf("0x%04X", addr),
+				InstructionBytes: i.HexBytes(),
+				Instruction:      i.StringWith(s.d.symbols.Label),
+			}
+			if sym, ok := s.d.symbols.At(uint16(addr)); ok {
+				instruction.Symbol = sym.Name
+			}
+			instructions = append(instructions, instruction)
+		}
+	})
+
+	s.respond(req, map[string]any{"instructions": instructions})
+}
+
+// instructionAddresses returns the addresses of count instructions, starting
+// offset instructions away from addr. Instructions before addr are found by
+// decoding forward from a few bytes earlier, which may not line up with the
+// way the code is actually run. Addresses out of memory are -1.
+func (s *dapSession) instructionAddresses(addr, offset, count int) []int {
+	size := func(a int) int {
+		return int(s.d.instructionAt(uint16(a)).Size)
+	}
+
+	count = max(0, count)
+
+	var addrs []int
+	if offset < 0 {
+		for a := max(0, addr+3*offset); a < addr; a += size(a) {
+			addrs = append(addrs, a)
+		}
+		if len(addrs) > -offset {
+			addrs = addrs[len(addrs)+offset:]
+		}
+		for len(addrs) < -offset {
+			addrs = append([]int{-1}, addrs...)
+		}
+	} else {
+		for i := 0; i < offset && addr <= 0xFFFF; i++ {
+			addr += size(addr)
+		}
+	}
+
+	for a := addr; len(addrs) < count; {
+		if a > 0xFFFF {
+			addrs = append(addrs, -1)
+			continue
+		}
+		addrs = append(addrs, a)
+		a += size(a)
+	}
+
+	return addrs[:count]
+}
@@ -0,0 +1,115 @@
+package debug
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A DAP session recorded from a client. The debugger starts paused with
+// program loaded at $0000, every message sent is followed by the messages
+// expected back, in order.
+type dapSessionRecording struct {
+	Program  string `json:"program"`
+	Messages []struct {
+		Send json.RawMessage `json:"send,omitempty"`
+		Recv json.RawMessage `json:"recv,omitempty"`
+	} `json:"messages"`
+}
+
+func readDAPMessage(t *testing.T, r *bufio.Reader) json.RawMessage {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		t.Fatalf("no message from the DAP server: %v", err)
+	}
+
+	length, _ := strconv.Atoi(header.Get("Content-Length"))
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		t.Fatalf("no message from the DAP server: %v", err)
+	}
+
+	return content
+}
+
+func replayDAPSession(t *testing.T, path string) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var recording dapSessionRecording
+	if err := json.Unmarshal(content, &recording); err != nil {
+		t.Fatal(err)
+	}
+
+	program, err := hex.DecodeString(recording.Program)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, c := createDebuggerWithProgramLoaded(program)
+	d.Pause()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go d.ServeDAP(l)
+
+	stopHost := startHost(d, c)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	defer func() {
+		conn.Close()
+		l.Close()
+		stopHost()
+	}()
+
+	r := bufio.NewReader(conn)
+	for i, m := range recording.Messages {
+		if m.Send != nil {
+			fmt.Fprintf(conn, "Content-Length: %d\r\n\r\n%s", len(m.Send), m.Send)
+			continue
+		}
+
+		got := readDAPMessage(t, r)
+
+		var expected, actual any
+		json.Unmarshal(m.Recv, &expected)
+		json.Unmarshal(got, &actual)
+
+		if !reflect.DeepEqual(expected, actual) {
+			t.Fatalf("message %d differs from the recording\nexpected: %s\ngot:      %s", i, m.Recv, got)
+		}
+	}
+}
+
+func TestDAPSessions(t *testing.T) {
+	paths, _ := filepath.Glob("testdata/dap/*.json")
+	if len(paths) == 0 {
+		t.Fatal("no recorded DAP sessions found")
+	}
+
+	for _, path := range paths {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".json"), func(t *testing.T) {
+			replayDAPSession(t, path)
+		})
+	}
+}
@@ -1,67 +1,144 @@
 package debug
 
 import (
-	"encoding/json"
-	"log"
-	"os"
+	"net/http"
+	"sync"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/cheat"
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/cpu"
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/disasm"
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/opcode"
 )
 
 type Cpu interface {
 	GetMemory() []byte
+	GetVRAM() []byte
 	GetRegisters() map[string]byte
 	GetPointers() map[string]uint16
+	GetPC() uint16
+	SetPC(value uint16)
+	GetSP() uint16
+	SetSP(value uint16)
+	SetRegisters(values map[string]byte)
+	GetFlags() byte
+	SetFlags(psw byte)
+	ReadFromMemory(addr uint16) byte
+	WriteIntoMemory(addr uint16, b byte)
+	InstructionAt(addr uint16) cpu.Intel8080Instruction
+	Opcodes() *[256]opcode.Opcode
+	GetCycles() uint
+	GetInterruptState() (enabled bool, enablePending bool)
+	IsHalted() bool
+	SetInterruptState(enabled bool, enablePending bool)
+	SetCycles(cycles uint)
+	SetHalted(halted bool)
+	LoadProgram(program []byte, offset int)
+	AddHooks(h *cpu.Hooks)
+	RemoveHooks(h *cpu.Hooks)
 }
 
 type CpuState struct {
 	Registers map[string]byte   `json:"registers"`
 	Pointers  map[string]uint16 `json:"pointers"`
+	// Symbol names of the pointers that have one
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 type Debugger struct {
+	// mu serializes access to the CPU and the debugger state between the
+	// host running instructions and clients such as the GDB server.
+	mu  sync.Mutex
 	cpu Cpu
+
+	breakpoints     map[uint16]bool
+	watchpoints     []Watchpoint
+	portBreakpoints []PortBreakpoint
+	hooks           *cpu.Hooks
+
+	paused         bool
+	stop           Stop
+	skipBreakpoint bool
+	step           stepMode
+	// instructions left to run when stepping instructions
+	steps      int
+	stepTarget uint16
+	stepSP     uint16
+
+	// the instruction being executed, as seen by the BeforeExecute hook
+	instructionPC uint16
+	instructionSP uint16
+	opcode        byte
+
+	trackingCalls int
+	callStack     []CallFrame
+
+	ports [256]PortActivity
+
+	subscribers []chan Stop
+	commands    chan Command
+
+	httpServer *http.Server
+	streams    []*streamClient
+	// frames published to stream clients
+	frames uint64
+
+	searches   map[int]*cheat.Search
+	nextSearch int
+	cheats     *cheat.List
+
+	io        IODevice
+	memoryMap []MemoryRegion
+
+	symbols *Symbols
 }
 
 func NewDebugger(c Cpu) *Debugger {
-	return &Debugger{
-		cpu: c,
+	d := &Debugger{
+		cpu:         c,
+		breakpoints: make(map[uint16]bool),
+		commands:    make(chan Command),
+		searches:    make(map[int]*cheat.Search),
+		memoryMap:   []MemoryRegion{{Name: "Memory", Start: 0x0000, End: 0xFFFF}},
+		symbols:     &Symbols{},
 	}
-}
+	d.httpServer = &http.Server{Handler: d.Handler()}
+	d.updateHooks()
 
-func (d *Debugger) Dump() {
-	d.dumpMemory()
-	d.dumpCpuState()
+	return d
 }
 
-func (d *Debugger) createDumpFolderIfNotExists() {
-	if _, err := os.Stat(".dump"); os.IsNotExist(err) {
-		os.Mkdir(".dump", os.ModePerm)
-	}
+// instructionAt decodes the instruction at addr as the CPU would.
+func (d *Debugger) instructionAt(addr uint16) disasm.Instruction {
+	return disasm.DisassembleWith(d.cpu.Opcodes(), d.cpu, addr)
 }
 
-func (d *Debugger) dumpMemory() {
-	log.Println("Dumping memory...")
-	mem := d.cpu.GetMemory()
+// Exec runs f while no instructions are executing and no client is using
+// the CPU. f must not call other Debugger methods, except CanExecute.
+func (d *Debugger) Exec(f func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
-	d.createDumpFolderIfNotExists()
-
-	err := os.WriteFile(".dump/memory", mem, 0644)
-	if err != nil {
-		log.Fatal(err)
-	}
+	f()
 }
 
-func (d *Debugger) dumpCpuState() {
-	log.Println("Dumping cpu state...")
-	d.createDumpFolderIfNotExists()
+// Subscribe returns a channel that receives every stop until cancel is
+// called. Stops are dropped while the channel is full.
+func (d *Debugger) Subscribe() (stops <-chan Stop, cancel func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
-	state := &CpuState{
-		Registers: d.cpu.GetRegisters(),
-		Pointers:  d.cpu.GetPointers(),
-	}
+	c := make(chan Stop, 16)
+	d.subscribers = append(d.subscribers, c)
+
+	return c, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
 
-	stateJson, _ := json.Marshal(state)
-	err := os.WriteFile(".dump/cpu_state.json", stateJson, 0644)
-	if err != nil {
-		log.Fatal(err)
+		for i, s := range d.subscribers {
+			if s == c {
+				d.subscribers = append(d.subscribers[:i:i], d.subscribers[i+1:]...)
+				break
+			}
+		}
 	}
 }
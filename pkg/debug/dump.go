@@ -0,0 +1,249 @@
+package debug
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/ihex"
+)
+
+// DumpVersion is bumped whenever the layout of state.json changes.
+const DumpVersion = 1
+
+// Files written by Dump and read by LoadDump
+const (
+	dumpStateFile     = "state.json"
+	dumpMemoryHexFile = "memory.hex"
+	dumpMemoryTxtFile = "memory.txt"
+)
+
+// IODevice is the IO bus of the host, whose latches are dumped with the CPU.
+type IODevice interface {
+	GetLatches() map[string]byte
+	SetLatches(values map[string]byte)
+}
+
+// MemoryRegion labels a part of the address space in memory dumps.
+type MemoryRegion struct {
+	Name  string `json:"name"`
+	Start uint16 `json:"start"`
+	End   uint16 `json:"end"`
+}
+
+// DumpState is everything needed to resume execution from a dump, except
+// memory, which is dumped as Intel HEX.
+type DumpState struct {
+	Version    int                `json:"version"`
+	Registers  map[string]byte    `json:"registers"`
+	Pointers   map[string]uint16  `json:"pointers"`
+	Flags      FlagsResponse      `json:"flags"`
+	Interrupts InterruptsResponse `json:"interrupts"`
+	Cycles     uint               `json:"cycles"`
+	// Latches of the IO bus, when the host has set one
+	IO        map[string]byte `json:"io,omitempty"`
+	Ports     []PortActivity  `json:"ports"`
+	MemoryMap []MemoryRegion  `json:"memoryMap"`
+}
+
+func flagsResponse(psw byte) FlagsResponse {
+	return FlagsResponse{
+		PSW:      psw,
+		Sign:     psw&(1<<7) != 0,
+		Zero:     psw&(1<<6) != 0,
+		AuxCarry: psw&(1<<4) != 0,
+		Parity:   psw&(1<<2) != 0,
+		Carry:    psw&(1<<0) != 0,
+	}
+}
+
+// SetIO has the latches of bus dumped and restored with the CPU.
+func (d *Debugger) SetIO(bus IODevice) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.io = bus
+}
+
+// SetMemoryMap sets the regions labelled in annotated memory dumps. By
+// default the whole address space is a single region.
+func (d *Debugger) SetMemoryMap(regions []MemoryRegion) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.memoryMap = regions
+}
+
+// DumpState waits for the current instruction to finish and returns the
+// state of the machine.
+func (d *Debugger) DumpState() DumpState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.dumpState(d.snapshot())
+}
+
+func (d *Debugger) dumpState(s Snapshot) DumpState {
+	state := DumpState{
+		Version:   DumpVersion,
+		Registers: s.Registers,
+		Pointers:  s.Pointers,
+		Flags:     flagsResponse(s.Flags),
+		Interrupts: InterruptsResponse{
+			Enabled:       s.InterruptsEnabled,
+			EnablePending: s.InterruptEnablePending,
+			Halted:        s.Halted,
+		},
+		Cycles:    s.Cycles,
+		Ports:     s.Ports,
+		MemoryMap: d.memoryMap,
+	}
+	if d.io != nil {
+		state.IO = d.io.GetLatches()
+	}
+
+	return state
+}
+
+// Dump writes the machine state to dir: state.json, memory.hex with the
+// whole address space in Intel HEX, and memory.txt with the same memory as
+// hex and ASCII, labelled with the memory map.
+func (d *Debugger) Dump(dir string) error {
+	log.Printf("Dumping machine state to %s...\n", dir)
+
+	d.mu.Lock()
+	s := d.snapshot()
+	state := d.dumpState(s)
+	d.mu.Unlock()
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	stateJson, err := json.MarshalIndent(&state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, dumpStateFile), stateJson, 0644); err != nil {
+		return err
+	}
+
+	var hex bytes.Buffer
+	if err := ihex.Write(&hex, 0x0000, s.Memory); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, dumpMemoryHexFile), hex.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	var txt bytes.Buffer
+	writeAnnotatedMemory(&txt, 0x0000, s.Memory, state.MemoryMap)
+	return os.WriteFile(filepath.Join(dir, dumpMemoryTxtFile), txt.Bytes(), 0644)
+}
+
+// LoadDump restores a dump written by Dump, so execution resumes where it
+// was dumped. The dump is rejected if it was written by another version.
+func (d *Debugger) LoadDump(dir string) error {
+	stateJson, err := os.ReadFile(filepath.Join(dir, dumpStateFile))
+	if err != nil {
+		return err
+	}
+
+	var state DumpState
+	if err := json.Unmarshal(stateJson, &state); err != nil {
+		return fmt.Errorf("invalid %s: %w", dumpStateFile, err)
+	}
+
+	if state.Version != DumpVersion {
+		return fmt.Errorf("unsupported dump version %d", state.Version)
+	}
+
+	f, err := os.Open(filepath.Join(dir, dumpMemoryHexFile))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	segments, err := ihex.Read(f)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", dumpMemoryHexFile, err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, s := range segments {
+		d.cpu.LoadProgram(s.Data, int(s.Addr))
+	}
+
+	d.cpu.SetRegisters(state.Registers)
+	d.cpu.SetFlags(state.Flags.PSW)
+	d.cpu.SetPC(state.Pointers["pc"])
+	d.cpu.SetSP(state.Pointers["sp"])
+	d.cpu.SetCycles(state.Cycles)
+	d.cpu.SetInterruptState(state.Interrupts.Enabled, state.Interrupts.EnablePending)
+	d.cpu.SetHalted(state.Interrupts.Halted)
+	if d.io != nil && state.IO != nil {
+		d.io.SetLatches(state.IO)
+	}
+
+	d.ports = [256]PortActivity{}
+	for _, p := range state.Ports {
+		d.ports[p.Port] = p
+	}
+
+	return nil
+}
+
+func formatHexLine(addr uint16, line []byte) string {
+	var text strings.Builder
+	for _, b := range line {
+		if b >= 0x20 && b < 0x7f {
+			text.WriteByte(b)
+		} else {
+			text.WriteByte('.')
+		}
+	}
+
+	return fmt.Sprintf("%04X: % -47X  %s", addr, line, text.String())
+}
+
+func regionAt(regions []MemoryRegion, addr uint16) (MemoryRegion, bool) {
+	for _, r := range regions {
+		if addr >= r.Start && addr <= r.End {
+			return r, true
+		}
+	}
+	return MemoryRegion{}, false
+}
+
+// writeAnnotatedMemory writes memory, starting at addr, 16 bytes a line with
+// a heading before each region of the memory map.
+func writeAnnotatedMemory(w io.Writer, addr uint16, memory []byte, regions []MemoryRegion) {
+	var current MemoryRegion
+	mapped := false
+
+	for i := 0; i < len(memory); i += 16 {
+		lineAddr := addr + uint16(i)
+
+		r, ok := regionAt(regions, lineAddr)
+		if i == 0 || ok != mapped || r != current {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			if ok {
+				fmt.Fprintf(w, "; %s $%04X-$%04X\n", r.Name, r.Start, r.End)
+			} else {
+				fmt.Fprintln(w, "; Unmapped")
+			}
+			current, mapped = r, ok
+		}
+
+		fmt.Fprintln(w, formatHexLine(lineAddr, memory[i:min(i+16, len(memory))]))
+	}
+}
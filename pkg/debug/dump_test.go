@@ -0,0 +1,147 @@
+package debug
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/cpu"
+)
+
+type testLatches map[string]byte
+
+func (l testLatches) GetLatches() map[string]byte {
+	return map[string]byte{"shiftHigh": l["shiftHigh"]}
+}
+
+func (l testLatches) SetLatches(values map[string]byte) {
+	for name, value := range values {
+		l[name] = value
+	}
+}
+
+func TestDumpAndLoadDump(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(portsProgram)
+	d.SetIO(testLatches{"shiftHigh": 0xAA})
+	run(d, c)
+	c.WriteIntoMemory(0x2000, 0xAB)
+
+	dir := t.TempDir()
+	if err := d.Dump(dir); err != nil {
+		t.Fatalf("Dump returned an error: %s", err)
+	}
+
+	for _, name := range []string{"state.json", "memory.hex", "memory.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Dump did not write %s", name)
+		}
+	}
+
+	restored := cpu.NewIntel8080(&TestIOBus{})
+	latches := testLatches{}
+	rd := NewDebugger(restored)
+	rd.SetIO(latches)
+
+	if err := rd.LoadDump(dir); err != nil {
+		t.Fatalf("LoadDump returned an error: %s", err)
+	}
+
+	if !reflect.DeepEqual(rd.DumpState(), d.DumpState()) {
+		t.Errorf("LoadDump did not restore the dumped state, got %+v", rd.DumpState())
+	}
+
+	if !bytes.Equal(restored.GetMemory(), c.GetMemory()) {
+		t.Errorf("LoadDump did not restore memory")
+	}
+
+	if latches["shiftHigh"] != 0xAA {
+		t.Errorf("LoadDump did not restore the IO latches")
+	}
+}
+
+func TestDumpState(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(portsProgram)
+	run(d, c)
+
+	s := d.DumpState()
+
+	if s.Version != DumpVersion || s.Pointers["pc"] != 0x000C || s.Registers["A"] != 0x02 {
+		t.Errorf("DumpState did not return the CPU state, got %+v", s)
+	}
+
+	if !s.Interrupts.Enabled || !s.Interrupts.Halted || s.Cycles != 10+4+10+5+10+10+7 {
+		t.Errorf("DumpState did not return the interrupt state and cycles, got %+v", s)
+	}
+
+	if len(s.Ports) != 2 || s.IO != nil {
+		t.Errorf("DumpState did not return the ports accessed, got %+v", s)
+	}
+}
+
+func TestLoadDumpRejectsOtherVersions(t *testing.T) {
+	d, _ := createDebuggerWithProgramLoaded(portsProgram)
+
+	dir := t.TempDir()
+	if err := d.Dump(dir); err != nil {
+		t.Fatalf("Dump returned an error: %s", err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "state.json"), []byte(`{"version": 99}`), 0644)
+
+	if err := d.LoadDump(dir); err == nil {
+		t.Errorf("LoadDump did not reject a dump of another version")
+	}
+}
+
+func TestAnnotatedMemory(t *testing.T) {
+	memory := make([]byte, 0x40)
+	copy(memory[0x20:], "HELLO")
+
+	regions := []MemoryRegion{
+		{Name: "ROM", Start: 0x1000, End: 0x101F},
+		{Name: "RAM", Start: 0x1020, End: 0x102F},
+	}
+
+	var out strings.Builder
+	writeAnnotatedMemory(&out, 0x1000, memory, regions)
+
+	expected := "; ROM $1000-$101F\n" +
+		"1000: 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00  ................\n" +
+		"1010: 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00  ................\n" +
+		"\n" +
+		"; RAM $1020-$102F\n" +
+		"1020: 48 45 4C 4C 4F 00 00 00 00 00 00 00 00 00 00 00  HELLO...........\n" +
+		"\n" +
+		"; Unmapped\n" +
+		"1030: 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00  ................\n"
+	if out.String() != expected {
+		t.Errorf("writeAnnotatedMemory did not label the regions, got\n%s", out.String())
+	}
+}
+
+func TestServerMemoryDumpFormats(t *testing.T) {
+	d, _ := createDebuggerWithProgramLoaded(portsProgram)
+	d.SetMemoryMap([]MemoryRegion{{Name: "ROM", Start: 0x0000, End: 0x1FFF}})
+
+	w := httptest.NewRecorder()
+	d.getMemoryDump(w, httptest.NewRequest(http.MethodGet, "/dump/memory?format=ihex", nil))
+
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Body.String(), ":10000000310024FBDB013CD303D30376") {
+		t.Errorf("getMemoryDump did not return Intel HEX, got %d %.50q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	d.getMemoryDump(w, httptest.NewRequest(http.MethodGet, "/dump/memory?format=text", nil))
+
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Body.String(), "; ROM $0000-$1FFF\n0000: 31 00 24 FB") {
+		t.Errorf("getMemoryDump did not return annotated memory, got %d %.50q", w.Code, w.Body.String())
+	}
+
+	var res ResponseError
+	serve(t, d.getMemoryDump, httptest.NewRequest(http.MethodGet, "/dump/memory?format=bin", nil), http.StatusBadRequest, &res)
+}
@@ -0,0 +1,463 @@
+package debug
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// The GDB Remote Serial Protocol, see
+// https://sourceware.org/gdb/current/onlinedocs/gdb.html/Remote-Protocol.html
+//
+// GDB has no 8080 target, so the registers are described to it with a target
+// description. They are sent in this order, 16-bit registers little-endian:
+//
+//	0 a   1 f   2 bc   3 de   4 hl   5 sp   6 pc
+//
+// f is the flags byte as PUSH PSW stores it.
+const gdbTargetXML = `<?xml version="1.0"?>
+<!DOCTYPE target SYSTEM "gdb-target.dtd">
+<target version="1.0">
+  <feature name="org.gnu.gdb.i8080.core">
+    <reg name="a" bitsize="8" type="uint8" regnum="0"/>
+    <reg name="f" bitsize="8" type="uint8"/>
+    <reg name="bc" bitsize="16" type="uint16"/>
+    <reg name="de" bitsize="16" type="uint16"/>
+    <reg name="hl" bitsize="16" type="data_ptr"/>
+    <reg name="sp" bitsize="16" type="data_ptr"/>
+    <reg name="pc" bitsize="16" type="code_ptr"/>
+  </feature>
+</target>
+`
+
+// Offset and size of each register in the g packet
+var gdbRegisters = []struct{ offset, size int }{
+	{0, 1}, {1, 1}, {2, 2}, {4, 2}, {6, 2}, {8, 2}, {10, 2},
+}
+
+const gdbRegistersSize = 12
+
+// StartGDBServer listens on addr, e.g. "localhost:1234", and serves GDB
+// clients until listening fails.
+func (d *Debugger) StartGDBServer(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("GDB server listening on %s\n", l.Addr())
+	return d.ServeGDB(l)
+}
+
+// ServeGDB serves GDB clients connecting to l, one at a time. Execution is
+// paused when a client attaches and resumed when it detaches or
+// disconnects. It returns when l is closed.
+func (d *Debugger) ServeGDB(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		log.Printf("GDB client %s attached\n", conn.RemoteAddr())
+		s := &gdbSession{d: d, conn: conn, ack: true}
+		s.serve()
+		conn.Close()
+		log.Printf("GDB client %s detached\n", conn.RemoteAddr())
+	}
+}
+
+type gdbPacket struct {
+	data  string
+	valid bool
+}
+
+// Ctrl-C is sent on its own, outside of a packet
+const gdbInterrupt = "\x03"
+
+type gdbSession struct {
+	d     *Debugger
+	conn  io.ReadWriter
+	ack   bool
+	stops <-chan Stop
+
+	// running is set while a continue or step has not been answered yet
+	running     bool
+	interrupted bool
+	detached    bool
+}
+
+func (s *gdbSession) serve() {
+	stops, cancel := s.d.Subscribe()
+	defer cancel()
+	s.stops = stops
+
+	s.d.Pause()
+
+	packets := make(chan gdbPacket)
+	done := make(chan struct{})
+	defer close(done)
+	go readGDBPackets(bufio.NewReader(s.conn), packets, done)
+
+	for !s.detached {
+		select {
+		case p, ok := <-packets:
+			if !ok {
+				// Don't leave the game frozen when the client goes away
+				s.d.Resume()
+				return
+			}
+			s.receive(p)
+		case stop := <-stops:
+			if s.running {
+				s.running = false
+				s.send(s.stopReply(stop))
+			}
+		}
+	}
+}
+
+func readGDBPackets(r *bufio.Reader, packets chan<- gdbPacket, done <-chan struct{}) {
+	defer close(packets)
+
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+
+		var p gdbPacket
+		switch b {
+		case gdbInterrupt[0]:
+			p = gdbPacket{data: gdbInterrupt, valid: true}
+		case '$':
+			data, err := r.ReadString('#')
+			if err != nil {
+				return
+			}
+			data = data[:len(data)-1]
+
+			var checksum [2]byte
+			if _, err := io.ReadFull(r, checksum[:]); err != nil {
+				return
+			}
+
+			expected, err := strconv.ParseUint(string(checksum[:]), 16, 8)
+			p = gdbPacket{data: data, valid: err == nil && byte(expected) == gdbChecksum(data)}
+		default:
+			// Acks from the client; lost replies are not resent
+			continue
+		}
+
+		select {
+		case packets <- p:
+		case <-done:
+			return
+		}
+	}
+}
+
+func gdbChecksum(data string) byte {
+	var sum byte
+	for i := 0; i < len(data); i++ {
+		sum += data[i]
+	}
+	return sum
+}
+
+func (s *gdbSession) send(data string) {
+	fmt.Fprintf(s.conn, "$%s#%02x", data, gdbChecksum(data))
+}
+
+func (s *gdbSession) receive(p gdbPacket) {
+	if p.data == gdbInterrupt {
+		if s.running {
+			s.interrupted = true
+			s.d.Pause()
+		}
+		return
+	}
+
+	if !p.valid {
+		s.conn.Write([]byte("-"))
+		return
+	}
+
+	if s.ack {
+		s.conn.Write([]byte("+"))
+	}
+
+	if reply, ok := s.handle(p.data); ok {
+		s.send(reply)
+	}
+
+	if p.data == "QStartNoAckMode" {
+		s.ack = false
+	}
+}
+
+// handle runs a command and returns its reply. There is no reply to continue
+// and step until execution stops again, nor to kill.
+func (s *gdbSession) handle(packet string) (string, bool) {
+	if packet == "" {
+		return "", true
+	}
+
+	args := packet[1:]
+
+	switch packet[0] {
+	case '?':
+		return s.stopReply(s.d.LastStop()), true
+	case 'g':
+		return hex.EncodeToString(s.readRegisters()), true
+	case 'G':
+		b, err := hex.DecodeString(args)
+		if err != nil || len(b) != gdbRegistersSize {
+			return "E01", true
+		}
+		s.writeRegisters(b)
+		return "OK", true
+	case 'p':
+		n, err := strconv.ParseUint(args, 16, 8)
+		if err != nil || n >= uint64(len(gdbRegisters)) {
+			return "E01", true
+		}
+		r := gdbRegisters[n]
+		return hex.EncodeToString(s.readRegisters()[r.offset : r.offset+r.size]), true
+	case 'P':
+		number, value, _ := strings.Cut(args, "=")
+		n, err := strconv.ParseUint(number, 16, 8)
+		if err != nil || n >= uint64(len(gdbRegisters)) {
+			return "E01", true
+		}
+		b, err := hex.DecodeString(value)
+		r := gdbRegisters[n]
+		if err != nil || len(b) != r.size {
+			return "E01", true
+		}
+		registers := s.readRegisters()
+		copy(registers[r.offset:], b)
+		s.writeRegisters(registers)
+		return "OK", true
+	case 'm':
+		addr, length, err := parseGDBRange(args)
+		if err != nil {
+			return "E01", true
+		}
+		return hex.EncodeToString(s.readMemory(addr, length)), true
+	case 'M':
+		r, data, _ := strings.Cut(args, ":")
+		addr, length, err := parseGDBRange(r)
+		if err != nil {
+			return "E01", true
+		}
+		b, err := hex.DecodeString(data)
+		if err != nil || len(b) != length {
+			return "E01", true
+		}
+		s.writeMemory(addr, b)
+		return "OK", true
+	case 'Z', 'z':
+		return s.breakpoint(packet[0] == 'Z', args), true
+	case 'c', 's':
+		if args != "" {
+			pc, err := strconv.ParseUint(args, 16, 16)
+			if err != nil {
+				return "E01", true
+			}
+			s.d.Exec(func() { s.d.cpu.SetPC(uint16(pc)) })
+		}
+
+		// Forget stops from before, e.g. the pause on attach
+		for len(s.stops) > 0 {
+			<-s.stops
+		}
+
+		s.running = true
+		s.interrupted = false
+		if packet[0] == 'c' {
+			s.d.Resume()
+		} else {
+			s.d.Step()
+		}
+		return "", false
+	case 'D':
+		s.d.Resume()
+		s.detached = true
+		return "OK", true
+	case 'k':
+		s.d.Resume()
+		s.detached = true
+		return "", false
+	case 'H':
+		return "OK", true
+	case 'q':
+		return s.query(args), true
+	case 'Q':
+		if args == "StartNoAckMode" {
+			return "OK", true
+		}
+	}
+
+	return "", true
+}
+
+func (s *gdbSession) query(q string) string {
+	switch {
+	case strings.HasPrefix(q, "Supported"):
+		return "PacketSize=4000;qXfer:features:read+;QStartNoAckMode+"
+	case q == "Attached":
+		return "1"
+	case q == "C":
+		return "QC1"
+	case q == "fThreadInfo":
+		return "m1"
+	case q == "sThreadInfo":
+		return "l"
+	case strings.HasPrefix(q, "Xfer:features:read:target.xml:"):
+		addr, length, err := parseGDBRange(strings.TrimPrefix(q, "Xfer:features:read:target.xml:"))
+		if err != nil {
+			return "E01"
+		}
+
+		offset := int(addr)
+		if offset >= len(gdbTargetXML) {
+			return "l"
+		}
+		if offset+length >= len(gdbTargetXML) {
+			return "l" + gdbTargetXML[offset:]
+		}
+		return "m" + gdbTargetXML[offset:offset+length]
+	}
+
+	return ""
+}
+
+// breakpoint inserts or removes a breakpoint given as type,addr,kind.
+// Types 0 and 1 are breakpoints, 2, 3 and 4 write, read and access
+// watchpoints.
+func (s *gdbSession) breakpoint(insert bool, args string) string {
+	fields := strings.Split(args, ",")
+	if len(fields) < 2 {
+		return "E01"
+	}
+
+	addr, err := strconv.ParseUint(fields[1], 16, 16)
+	if err != nil {
+		return "E01"
+	}
+
+	var access Access
+	switch fields[0] {
+	case "0", "1":
+		if insert {
+			s.d.AddBreakpoint(uint16(addr))
+		} else {
+			s.d.RemoveBreakpoint(uint16(addr))
+		}
+		return "OK"
+	case "2":
+		access = Write
+	case "3":
+		access = Read
+	case "4":
+		access = ReadWrite
+	default:
+		return ""
+	}
+
+	w := Watchpoint{Addr: uint16(addr), Access: access}
+	if insert {
+		s.d.AddWatchpoint(w)
+	} else {
+		s.d.RemoveWatchpoint(w)
+	}
+	return "OK"
+}
+
+func (s *gdbSession) stopReply(stop Stop) string {
+	switch {
+	case stop.Kind == StopPause && s.interrupted:
+		return "S02" // SIGINT
+	case stop.Kind == StopWatchpoint:
+		kind := "watch"
+		if stop.Access == Read {
+			kind = "rwatch"
+		}
+		return fmt.Sprintf("T05%s:%04x;", kind, stop.Addr)
+	}
+	return "S05" // SIGTRAP
+}
+
+func (s *gdbSession) readRegisters() []byte {
+	var b []byte
+	s.d.Exec(func() {
+		r, p := s.d.cpu.GetRegisters(), s.d.cpu.GetPointers()
+		b = []byte{
+			r["A"], s.d.cpu.GetFlags(),
+			r["C"], r["B"],
+			r["E"], r["D"],
+			r["L"], r["H"],
+			byte(p["sp"]), byte(p["sp"] >> 8),
+			byte(p["pc"]), byte(p["pc"] >> 8),
+		}
+	})
+	return b
+}
+
+func (s *gdbSession) writeRegisters(b []byte) {
+	s.d.Exec(func() {
+		s.d.cpu.SetRegisters(map[string]byte{
+			"A": b[0],
+			"C": b[2], "B": b[3],
+			"E": b[4], "D": b[5],
+			"L": b[6], "H": b[7],
+		})
+		s.d.cpu.SetFlags(b[1])
+		s.d.cpu.SetSP(uint16(b[9])<<8 | uint16(b[8]))
+		s.d.cpu.SetPC(uint16(b[11])<<8 | uint16(b[10]))
+	})
+}
+
+func (s *gdbSession) readMemory(addr uint16, length int) []byte {
+	b := make([]byte, length)
+	s.d.Exec(func() {
+		for i := range b {
+			b[i] = s.d.cpu.ReadFromMemory(addr + uint16(i))
+		}
+	})
+	return b
+}
+
+func (s *gdbSession) writeMemory(addr uint16, b []byte) {
+	s.d.Exec(func() {
+		for i, v := range b {
+			s.d.cpu.WriteIntoMemory(addr+uint16(i), v)
+		}
+	})
+}
+
+// parseGDBRange parses addr,length as sent in m, M and qXfer packets.
+func parseGDBRange(s string) (uint16, int, error) {
+	a, l, ok := strings.Cut(s, ",")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid range %q", s)
+	}
+
+	addr, err := strconv.ParseUint(a, 16, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	length, err := strconv.ParseUint(l, 16, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return uint16(addr), int(length), nil
+}
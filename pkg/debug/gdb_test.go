@@ -0,0 +1,249 @@
+package debug
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/cpu"
+)
+
+var counterProgram = []byte{
+	0x31, 0x00, 0x24, // LXI SP,$2400
+	0x3E, 0x01, // MVI A,$01
+	0x3C,             // $0005: INR A
+	0x32, 0x00, 0x20, // STA $2000
+	0xC3, 0x05, 0x00, // JMP $0005
+}
+
+// Runs instructions the way cmd/invaders does until stop is called
+func startHost(d *Debugger, c *cpu.Intel8080) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			d.Exec(func() {
+				for i := 0; i < 100 && d.CanExecute(); i++ {
+					c.Run()
+				}
+			})
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+type gdbClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func connectGDB(t *testing.T, p []byte) (*gdbClient, *Debugger, *cpu.Intel8080) {
+	d, c := createDebuggerWithProgramLoaded(p)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go d.ServeGDB(l)
+
+	stopHost := startHost(d, c)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	t.Cleanup(func() {
+		conn.Close()
+		l.Close()
+		stopHost()
+	})
+
+	return &gdbClient{t: t, conn: conn, r: bufio.NewReader(conn)}, d, c
+}
+
+func (g *gdbClient) write(packet string) {
+	fmt.Fprintf(g.conn, "$%s#%02x", packet, gdbChecksum(packet))
+}
+
+// Reads the next packet, skipping acks
+func (g *gdbClient) read() string {
+	if _, err := g.r.ReadString('$'); err != nil {
+		g.t.Fatalf("no reply from the GDB server: %v", err)
+	}
+
+	data, err := g.r.ReadString('#')
+	if err != nil {
+		g.t.Fatalf("no reply from the GDB server: %v", err)
+	}
+
+	g.r.Discard(2)
+	g.conn.Write([]byte("+"))
+
+	return strings.TrimSuffix(data, "#")
+}
+
+func (g *gdbClient) command(packet string) string {
+	g.write(packet)
+	return g.read()
+}
+
+func TestGDBAttachPausesExecution(t *testing.T) {
+	g, d, _ := connectGDB(t, counterProgram)
+
+	if reply := g.command("?"); reply != "S05" {
+		t.Errorf("GDB server did not report the stop on attach, got %q", reply)
+	}
+
+	if !d.Paused() {
+		t.Errorf("GDB server did not pause execution on attach")
+	}
+}
+
+func TestGDBQueries(t *testing.T) {
+	g, _, _ := connectGDB(t, counterProgram)
+
+	if reply := g.command("qSupported:multiprocess+"); !strings.Contains(reply, "qXfer:features:read+") {
+		t.Errorf("GDB server did not announce target descriptions, got %q", reply)
+	}
+
+	if reply := g.command("qXfer:features:read:target.xml:0,ffff"); reply != "l"+gdbTargetXML {
+		t.Errorf("GDB server did not send the target description, got %q", reply)
+	}
+
+	if reply := g.command("qXfer:features:read:target.xml:0,10"); reply != "m"+gdbTargetXML[:0x10] {
+		t.Errorf("GDB server did not send the first chunk of the target description, got %q", reply)
+	}
+
+	if reply := g.command("vMustReplyEmpty"); reply != "" {
+		t.Errorf("GDB server did not reply empty to an unknown packet, got %q", reply)
+	}
+}
+
+func TestGDBRegisters(t *testing.T) {
+	g, _, c := connectGDB(t, counterProgram)
+
+	// a f bc de hl sp pc
+	if reply := g.command("G" + "11" + "d7" + "3322" + "5544" + "7766" + "0024" + "0500"); reply != "OK" {
+		t.Fatalf("GDB server did not write the registers, got %q", reply)
+	}
+
+	r := c.GetRegisters()
+	if r["A"] != 0x11 || r["B"] != 0x22 || r["C"] != 0x33 || r["H"] != 0x66 || r["L"] != 0x77 || c.GetFlags() != 0xd7 {
+		t.Errorf("GDB server did not map the registers correctly, got %v", r)
+	}
+
+	if reply := g.command("g"); reply != "11d7332255447766002405"+"00" {
+		t.Errorf("GDB server did not read the registers correctly, got %q", reply)
+	}
+
+	if reply := g.command("P6=0900"); reply != "OK" || c.GetPC() != 0x0009 {
+		t.Errorf("GDB server did not write PC, got %q", reply)
+	}
+
+	if reply := g.command("p5"); reply != "0024" {
+		t.Errorf("GDB server did not read SP, got %q", reply)
+	}
+
+	if reply := g.command("p7"); reply != "E01" {
+		t.Errorf("GDB server did not reject an unknown register, got %q", reply)
+	}
+}
+
+func TestGDBMemory(t *testing.T) {
+	g, _, c := connectGDB(t, counterProgram)
+
+	if reply := g.command("m0,3"); reply != "310024" {
+		t.Errorf("GDB server did not read memory, got %q", reply)
+	}
+
+	if reply := g.command("M2100,2:abcd"); reply != "OK" || c.ReadFromMemory(0x2100) != 0xab || c.ReadFromMemory(0x2101) != 0xcd {
+		t.Errorf("GDB server did not write memory, got %q", reply)
+	}
+
+	if reply := g.command("M2100,2:ab"); reply != "E01" {
+		t.Errorf("GDB server did not reject a short write, got %q", reply)
+	}
+}
+
+func TestGDBBreakpointContinueAndStep(t *testing.T) {
+	g, _, c := connectGDB(t, counterProgram)
+
+	if reply := g.command("Z0,6,1"); reply != "OK" {
+		t.Fatalf("GDB server did not insert the breakpoint, got %q", reply)
+	}
+
+	// Attaching paused the loop anywhere, start over from $0000
+	if reply := g.command("c0"); reply != "S05" || c.GetPC() != 0x0006 || c.GetRegisters()["A"] != 0x02 {
+		t.Errorf("continue did not stop at the breakpoint, got %q at $%04X", reply, c.GetPC())
+	}
+
+	if reply := g.command("s"); reply != "S05" || c.GetPC() != 0x0009 {
+		t.Errorf("step did not run a single instruction, got %q at $%04X", reply, c.GetPC())
+	}
+
+	if reply := g.command("c"); reply != "S05" || c.GetPC() != 0x0006 || c.GetRegisters()["A"] != 0x03 {
+		t.Errorf("continue did not run the loop again, got %q at $%04X", reply, c.GetPC())
+	}
+
+	g.command("z0,6,1")
+	g.command("Z2,2000,1")
+
+	if reply := g.command("c"); reply != "T05watch:2000;" {
+		t.Errorf("continue did not stop at the watchpoint, got %q", reply)
+	}
+}
+
+func TestGDBInterrupt(t *testing.T) {
+	g, d, _ := connectGDB(t, counterProgram)
+
+	g.write("c")
+	time.Sleep(10 * time.Millisecond)
+	g.conn.Write([]byte(gdbInterrupt))
+
+	if reply := g.read(); reply != "S02" || !d.Paused() {
+		t.Errorf("Ctrl-C did not interrupt execution, got %q", reply)
+	}
+}
+
+func TestGDBDetachResumesExecution(t *testing.T) {
+	g, d, _ := connectGDB(t, counterProgram)
+
+	if reply := g.command("D"); reply != "OK" {
+		t.Errorf("GDB server did not acknowledge the detach, got %q", reply)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if d.Paused() {
+		t.Errorf("GDB server did not resume execution on detach")
+	}
+}
+
+func TestGDBRejectsBadChecksum(t *testing.T) {
+	g, _, _ := connectGDB(t, counterProgram)
+
+	g.conn.Write([]byte("$g#00"))
+
+	b, err := g.r.ReadByte()
+	if err != nil || b != '-' {
+		t.Errorf("GDB server did not reject a packet with a bad checksum")
+	}
+}
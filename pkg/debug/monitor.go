@@ -0,0 +1,521 @@
+package debug
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// MonitorAction tells the host what to do after a monitor command.
+type MonitorAction int
+
+const (
+	// MonitorPrompt asks for the next command
+	MonitorPrompt MonitorAction = iota
+	// MonitorResume means execution was resumed or is being stepped
+	MonitorResume
+	MonitorQuit
+)
+
+const monitorHelp = `x [addr [len]]       examine memory
+d addr byte...       deposit bytes at addr
+r                    show registers and flags
+r reg=value...       set A-L, SP, PC, F, or the S, Z, AC, P and CY flags
+l [addr [count]]     disassemble
+b [spec]             list breakpoints, or add one: addr, read=addr[:byte],
+                     write=, access=, in=port or out=port
+bc addr|in=n|out=n   clear the breakpoints and watchpoints on addr or port
+g [addr]             go, from addr if given
+s [n]                step n instructions
+n                    step over a CALL
+o                    step out to the caller
+save file addr len   save memory to a file
+load file addr       load a file into memory
+h                    help
+q                    quit
+Numbers are hexadecimal; $ and 0x prefixes are accepted. Addresses may also
+be symbol names, or name+offset.`
+
+// Default lengths of x and l
+const (
+	monitorExamineLength = 0x40
+	monitorListLength    = 16
+)
+
+// Monitor interprets the commands of a terminal machine-code monitor.
+// Execute may be called from any goroutine, but not from inside Exec.
+type Monitor struct {
+	d   *Debugger
+	out io.Writer
+
+	// where x and l carry on from when no address is given; l starts at PC
+	// again once execution has been resumed
+	examineAddr uint16
+	listAddr    *uint16
+}
+
+func NewMonitor(d *Debugger, out io.Writer) *Monitor {
+	return &Monitor{d: d, out: out}
+}
+
+// Execute runs a command line, printing its output or error.
+func (m *Monitor) Execute(line string) MonitorAction {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return MonitorPrompt
+	}
+
+	action, err := m.execute(args[0], args[1:])
+	if err != nil {
+		fmt.Fprintf(m.out, "? %v\n", err)
+		return MonitorPrompt
+	}
+
+	if action == MonitorResume {
+		m.listAddr = nil
+	}
+	return action
+}
+
+func (m *Monitor) execute(cmd string, args []string) (MonitorAction, error) {
+	switch cmd {
+	case "x":
+		return MonitorPrompt, m.examine(args)
+	case "d":
+		return MonitorPrompt, m.deposit(args)
+	case "r":
+		if len(args) > 0 {
+			return MonitorPrompt, m.setRegisters(args)
+		}
+		m.showRegisters()
+		return MonitorPrompt, nil
+	case "l":
+		return MonitorPrompt, m.list(args)
+	case "b":
+		if len(args) > 0 {
+			return MonitorPrompt, m.addBreakpoint(args[0])
+		}
+		m.showBreakpoints()
+		return MonitorPrompt, nil
+	case "bc":
+		return MonitorPrompt, m.clearBreakpoints(args)
+	case "g":
+		if len(args) > 0 {
+			addr, err := m.parseAddress(args[0])
+			if err != nil {
+				return MonitorPrompt, err
+			}
+			m.d.Exec(func() { m.d.cpu.SetPC(addr) })
+		}
+		m.d.Resume()
+		return MonitorResume, nil
+	case "s":
+		n := uint64(1)
+		if len(args) > 0 {
+			var err error
+			if n, err = strconv.ParseUint(args[0], 10, 31); err != nil || n == 0 {
+				return MonitorPrompt, fmt.Errorf("invalid step count %q", args[0])
+			}
+		}
+		m.d.StepN(int(n))
+		return MonitorResume, nil
+	case "n":
+		m.d.StepOver()
+		return MonitorResume, nil
+	case "o":
+		m.d.StepOut()
+		return MonitorResume, nil
+	case "save":
+		return MonitorPrompt, m.save(args)
+	case "load":
+		return MonitorPrompt, m.load(args)
+	case "h", "?":
+		fmt.Fprintln(m.out, monitorHelp)
+		return MonitorPrompt, nil
+	case "q":
+		return MonitorQuit, nil
+	}
+
+	return MonitorPrompt, fmt.Errorf("unknown command %q, h for help", cmd)
+}
+
+// parseMonitorNumber parses hexadecimal numbers, optionally written $1234 or
+// 0x1234.
+func parseMonitorNumber(s string, bitSize int) (uint64, error) {
+	digits := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(s), "$"), "0x")
+	n, err := strconv.ParseUint(digits, 16, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return n, nil
+}
+
+// parseAddress parses a symbol name, name+offset, or a hexadecimal number.
+// Symbols win over numbers, such as a symbol called ADD.
+func (m *Monitor) parseAddress(s string) (uint16, error) {
+	if addr, ok := m.d.Symbols().Resolve(s); ok {
+		return addr, nil
+	}
+
+	addr, err := parseMonitorNumber(s, 16)
+	return uint16(addr), err
+}
+
+// Parses an optional address and length, the length defaulting to def
+func (m *Monitor) parseRange(args []string, addr uint16, def int) (uint16, int, error) {
+	length := def
+
+	if len(args) > 0 {
+		a, err := m.parseAddress(args[0])
+		if err != nil {
+			return 0, 0, err
+		}
+		addr = a
+	}
+
+	if len(args) > 1 {
+		l, err := parseMonitorNumber(args[1], 17)
+		if err != nil || l == 0 || l > 0x10000 {
+			return 0, 0, fmt.Errorf("invalid length %q", args[1])
+		}
+		length = int(l)
+	}
+
+	return addr, length, nil
+}
+
+func (m *Monitor) readMemory(addr uint16, length int) []byte {
+	data := make([]byte, length)
+	m.d.Exec(func() {
+		for i := range data {
+			data[i] = m.d.cpu.ReadFromMemory(addr + uint16(i))
+		}
+	})
+	return data
+}
+
+func (m *Monitor) writeMemory(addr uint16, data []byte) {
+	m.d.Exec(func() {
+		for i, b := range data {
+			m.d.cpu.WriteIntoMemory(addr+uint16(i), b)
+		}
+	})
+}
+
+func (m *Monitor) examine(args []string) error {
+	addr, length, err := m.parseRange(args, m.examineAddr, monitorExamineLength)
+	if err != nil {
+		return err
+	}
+
+	data := m.readMemory(addr, length)
+	for i := 0; i < len(data); i += 16 {
+		fmt.Fprintln(m.out, formatHexLine(addr+uint16(i), data[i:min(i+16, len(data))]))
+	}
+
+	m.examineAddr = addr + uint16(length)
+	return nil
+}
+
+func (m *Monitor) deposit(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: d addr byte...")
+	}
+
+	addr, err := m.parseAddress(args[0])
+	if err != nil {
+		return err
+	}
+
+	data := make([]byte, len(args)-1)
+	for i, arg := range args[1:] {
+		b, err := parseMonitorNumber(arg, 8)
+		if err != nil {
+			return err
+		}
+		data[i] = byte(b)
+	}
+
+	m.writeMemory(addr, data)
+	return nil
+}
+
+func (m *Monitor) showRegisters() {
+	var r map[string]byte
+	var pc, sp uint16
+	var flags byte
+	var next DisassemblyLine
+
+	m.d.Exec(func() {
+		r = m.d.cpu.GetRegisters()
+		pc, sp = m.d.cpu.GetPC(), m.d.cpu.GetSP()
+		flags = m.d.cpu.GetFlags()
+		next = m.d.disassemble(pc, 1)[0]
+	})
+
+	var f []string
+	for _, flag := range monitorFlags {
+		if flags&flag.mask != 0 {
+			f = append(f, flag.name)
+		} else {
+			f = append(f, "-")
+		}
+	}
+
+	fmt.Fprintf(m.out, "A=%02X B=%02X C=%02X D=%02X E=%02X H=%02X L=%02X SP=%04X PC=%04X F=%02X %s\n",
+		r["A"], r["B"], r["C"], r["D"], r["E"], r["H"], r["L"], sp, pc, flags, strings.Join(f, " "))
+	m.printLine("", next)
+}
+
+var monitorFlags = []struct {
+	name string
+	mask byte
+}{
+	{"S", 1 << 7},
+	{"Z", 1 << 6},
+	{"AC", 1 << 4},
+	{"P", 1 << 2},
+	{"CY", 1 << 0},
+}
+
+func (m *Monitor) setRegisters(args []string) error {
+	registers := make(map[string]byte)
+	pointers := make(map[string]uint16)
+	var setFlags, clearFlags byte
+	var psw *byte
+
+	for _, arg := range args {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return fmt.Errorf("expected reg=value, got %q", arg)
+		}
+		name = strings.ToUpper(name)
+
+		switch name {
+		case "A", "B", "C", "D", "E", "H", "L", "F":
+			v, err := parseMonitorNumber(value, 8)
+			if err != nil {
+				return err
+			}
+			if name == "F" {
+				b := byte(v)
+				psw = &b
+			} else {
+				registers[name] = byte(v)
+			}
+		case "SP", "PC":
+			v, err := parseMonitorNumber(value, 16)
+			if err != nil {
+				return err
+			}
+			pointers[name] = uint16(v)
+		default:
+			i := -1
+			for j, flag := range monitorFlags {
+				if flag.name == name {
+					i = j
+				}
+			}
+			if i < 0 {
+				return fmt.Errorf("unknown register %q", name)
+			}
+
+			switch value {
+			case "1":
+				setFlags |= monitorFlags[i].mask
+			case "0":
+				clearFlags |= monitorFlags[i].mask
+			default:
+				return fmt.Errorf("flag %s can only be set to 0 or 1", name)
+			}
+		}
+	}
+
+	m.d.Exec(func() {
+		m.d.cpu.SetRegisters(registers)
+		if sp, ok := pointers["SP"]; ok {
+			m.d.cpu.SetSP(sp)
+		}
+		if pc, ok := pointers["PC"]; ok {
+			m.d.cpu.SetPC(pc)
+		}
+
+		flags := m.d.cpu.GetFlags()
+		if psw != nil {
+			flags = *psw
+		}
+		m.d.cpu.SetFlags(flags&^clearFlags | setFlags)
+	})
+
+	return nil
+}
+
+func (m *Monitor) list(args []string) error {
+	var addr uint16
+	if m.listAddr != nil {
+		addr = *m.listAddr
+	} else {
+		m.d.Exec(func() { addr = m.d.cpu.GetPC() })
+	}
+
+	addr, count, err := m.parseRange(args, addr, monitorListLength)
+	if err != nil {
+		return err
+	}
+
+	var lines []DisassemblyLine
+	m.d.Exec(func() { lines = m.d.disassemble(addr, count) })
+
+	breakpoints := m.d.Breakpoints()
+	for _, l := range lines {
+		marker := " "
+		for _, b := range breakpoints {
+			if b == l.Addr {
+				marker = "*"
+			}
+		}
+		m.printLine(marker, l)
+		addr = l.Addr + uint16(len(strings.Fields(l.Bytes)))
+	}
+
+	m.listAddr = &addr
+	return nil
+}
+
+// printLine prints a line of disassembly, after its label
+func (m *Monitor) printLine(marker string, l DisassemblyLine) {
+	if l.Label != "" {
+		fmt.Fprintf(m.out, "%s:\n", l.Label)
+	}
+
+	line := fmt.Sprintf("%s%04X: %-8s  %s", marker, l.Addr, l.Bytes, l.Instruction)
+	if l.Comment != "" {
+		line = fmt.Sprintf("%-40s ; %s", line, l.Comment)
+	}
+	fmt.Fprintln(m.out, line)
+}
+
+func (m *Monitor) showBreakpoints() {
+	for _, addr := range m.d.Breakpoints() {
+		fmt.Fprintf(m.out, "pc=$%04X\n", addr)
+	}
+
+	for _, w := range m.d.Watchpoints() {
+		if w.MatchValue {
+			fmt.Fprintf(m.out, "%s=$%04X:$%02X\n", w.Access, w.Addr, w.Value)
+		} else {
+			fmt.Fprintf(m.out, "%s=$%04X\n", w.Access, w.Addr)
+		}
+	}
+
+	for _, p := range m.d.PortBreakpoints() {
+		kind := map[Access]string{Read: "in", Write: "out", ReadWrite: "in/out"}[p.Access]
+		fmt.Fprintf(m.out, "%s=$%02X\n", kind, p.Port)
+	}
+}
+
+// addBreakpoint adds a breakpoint written like --break, but with hexadecimal
+// numbers or symbol names.
+func (m *Monitor) addBreakpoint(spec string) error {
+	kind, value, ok := strings.Cut(spec, "=")
+	if !ok {
+		kind, value = "pc", spec
+	}
+
+	n, match, hasMatch := strings.Cut(value, ":")
+	if kind == "in" || kind == "out" {
+		port, err := parseMonitorNumber(n, 8)
+		if err != nil {
+			return err
+		}
+		spec = fmt.Sprintf("%s=0x%X", kind, port)
+	} else {
+		addr, err := m.parseAddress(n)
+		if err != nil {
+			return err
+		}
+		spec = fmt.Sprintf("%s=0x%X", kind, addr)
+	}
+
+	if hasMatch {
+		v, err := parseMonitorNumber(match, 8)
+		if err != nil {
+			return err
+		}
+		spec += fmt.Sprintf(":0x%X", v)
+	}
+
+	return m.d.AddBreakpointSpec(spec)
+}
+
+func (m *Monitor) clearBreakpoints(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: bc addr|in=port|out=port")
+	}
+
+	if kind, value, ok := strings.Cut(args[0], "="); ok {
+		if kind != "in" && kind != "out" {
+			return fmt.Errorf("expected in= or out=, got %q", args[0])
+		}
+
+		port, err := parseMonitorNumber(value, 8)
+		if err != nil {
+			return err
+		}
+		m.d.RemovePortBreakpoints(byte(port))
+		return nil
+	}
+
+	addr, err := m.parseAddress(args[0])
+	if err != nil {
+		return err
+	}
+
+	m.d.RemoveBreakpoint(addr)
+	m.d.RemoveWatchpoints(addr)
+	return nil
+}
+
+func (m *Monitor) save(args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("usage: save file addr len")
+	}
+
+	addr, length, err := m.parseRange(args[1:], 0, 0)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(args[0], m.readMemory(addr, length), 0644); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(m.out, "%d bytes saved\n", length)
+	return nil
+}
+
+func (m *Monitor) load(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: load file addr")
+	}
+
+	addr, err := m.parseAddress(args[1])
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	if int(addr)+len(data) > 0x10000 {
+		return fmt.Errorf("%d bytes do not fit at $%04X", len(data), addr)
+	}
+
+	m.writeMemory(addr, data)
+	fmt.Fprintf(m.out, "%d bytes loaded\n", len(data))
+	return nil
+}
@@ -0,0 +1,180 @@
+package debug
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/cpu"
+)
+
+func createMonitor(p []byte) (*Monitor, *bytes.Buffer, *Debugger, *cpu.Intel8080) {
+	d, c := createDebuggerWithProgramLoaded(p)
+	out := &bytes.Buffer{}
+
+	return NewMonitor(d, out), out, d, c
+}
+
+// Runs a command and returns its output
+func execute(t *testing.T, m *Monitor, out *bytes.Buffer, line string, expected MonitorAction) string {
+	out.Reset()
+	if action := m.Execute(line); action != expected {
+		t.Errorf("%q returned action %d instead of %d, printed %q", line, action, expected, out.String())
+	}
+	return out.String()
+}
+
+func TestMonitorExamineAndDeposit(t *testing.T) {
+	m, out, _, c := createMonitor(subroutineProgram)
+
+	execute(t, m, out, "d 2000 48 49 $0a", MonitorPrompt)
+	if c.ReadFromMemory(0x2000) != 0x48 || c.ReadFromMemory(0x2002) != 0x0a {
+		t.Errorf("d did not deposit the bytes")
+	}
+
+	expected := "2000: 48 49 0A" + strings.Repeat(" ", 47-8) + "  HI.\n"
+	if s := execute(t, m, out, "x 2000 3", MonitorPrompt); s != expected {
+		t.Errorf("x did not examine memory, got %q", s)
+	}
+
+	// Carries on where the last one stopped
+	if s := execute(t, m, out, "x", MonitorPrompt); !strings.HasPrefix(s, "2003: ") || strings.Count(s, "\n") != 4 {
+		t.Errorf("x did not carry on from the last address, got %q", s)
+	}
+}
+
+func TestMonitorRegisters(t *testing.T) {
+	m, out, _, c := createMonitor(subroutineProgram)
+
+	execute(t, m, out, "r a=12 H=34 sp=2400 pc=0003 cy=1 z=1", MonitorPrompt)
+
+	r := c.GetRegisters()
+	if r["A"] != 0x12 || r["H"] != 0x34 || c.GetSP() != 0x2400 || c.GetPC() != 0x0003 || c.GetFlags() != 0x43 {
+		t.Errorf("r did not set the registers, got %v SP=%04X PC=%04X F=%02X", r, c.GetSP(), c.GetPC(), c.GetFlags())
+	}
+
+	expected := "A=12 B=00 C=00 D=00 E=00 H=34 L=00 SP=2400 PC=0003 F=43 - Z - - CY\n" +
+		"0003: CD 10 00  CALL $0010\n"
+	if s := execute(t, m, out, "r", MonitorPrompt); s != expected {
+		t.Errorf("r did not show the registers, got %q", s)
+	}
+
+	execute(t, m, out, "r f=ff z=0", MonitorPrompt)
+	if c.GetFlags() != 0x97 {
+		t.Errorf("r did not set F and clear Z, got %02X", c.GetFlags())
+	}
+
+	for _, line := range []string{"r x=1", "r a=100", "r cy=2", "r a"} {
+		if s := execute(t, m, out, line, MonitorPrompt); !strings.HasPrefix(s, "? ") {
+			t.Errorf("%q was not rejected, got %q", line, s)
+		}
+	}
+}
+
+func TestMonitorList(t *testing.T) {
+	m, out, d, _ := createMonitor(subroutineProgram)
+	d.AddBreakpoint(0x0003)
+
+	expected := " 0000: 31 00 24  LXI SP,$2400\n" +
+		"*0003: CD 10 00  CALL $0010\n"
+	if s := execute(t, m, out, "l 0 2", MonitorPrompt); s != expected {
+		t.Errorf("l did not disassemble, got %q", s)
+	}
+
+	if s := execute(t, m, out, "l 0 1", MonitorPrompt); !strings.HasPrefix(s, " 0000:") {
+		t.Errorf("l did not start from the address given, got %q", s)
+	}
+
+	if s := execute(t, m, out, "l", MonitorPrompt); !strings.HasPrefix(s, "*0003:") {
+		t.Errorf("l did not carry on from the last instruction, got %q", s)
+	}
+}
+
+func TestMonitorBreakpoints(t *testing.T) {
+	m, out, d, _ := createMonitor(subroutineProgram)
+
+	execute(t, m, out, "b 10", MonitorPrompt)
+	execute(t, m, out, "b write=$2000:5", MonitorPrompt)
+	execute(t, m, out, "b out=3", MonitorPrompt)
+
+	if s := execute(t, m, out, "b in=100", MonitorPrompt); !strings.HasPrefix(s, "? ") {
+		t.Errorf("b did not reject a port out of range, got %q", s)
+	}
+
+	expected := "pc=$0010\nwrite=$2000:$05\nout=$03\n"
+	if s := execute(t, m, out, "b", MonitorPrompt); s != expected {
+		t.Errorf("b did not list the breakpoints, got %q", s)
+	}
+
+	execute(t, m, out, "bc 10", MonitorPrompt)
+	execute(t, m, out, "bc 2000", MonitorPrompt)
+	execute(t, m, out, "bc out=3", MonitorPrompt)
+
+	if len(d.Breakpoints()) != 0 || len(d.Watchpoints()) != 0 || len(d.PortBreakpoints()) != 0 {
+		t.Errorf("bc did not clear the breakpoints")
+	}
+}
+
+func TestMonitorStepAndGo(t *testing.T) {
+	m, out, d, c := createMonitor(subroutineProgram)
+	d.Pause()
+
+	execute(t, m, out, "s 3", MonitorResume)
+	run(d, c)
+
+	if !d.Paused() || c.GetPC() != 0x0012 {
+		t.Errorf("s did not step 3 instructions, PC=%04X", c.GetPC())
+	}
+
+	execute(t, m, out, "b 20", MonitorPrompt)
+	execute(t, m, out, "g", MonitorResume)
+	run(d, c)
+
+	if c.GetPC() != 0x0020 {
+		t.Errorf("g did not run to the breakpoint, PC=%04X", c.GetPC())
+	}
+
+	execute(t, m, out, "g 6", MonitorResume)
+	run(d, c)
+
+	if !c.IsHalted() || c.GetRegisters()["A"] != 0x01 {
+		t.Errorf("g did not run from the address given")
+	}
+
+	execute(t, m, out, "q", MonitorQuit)
+}
+
+func TestMonitorSaveAndLoad(t *testing.T) {
+	m, out, _, c := createMonitor(subroutineProgram)
+	path := filepath.Join(t.TempDir(), "code.bin")
+
+	execute(t, m, out, "save "+path+" 0 6", MonitorPrompt)
+
+	data, err := os.ReadFile(path)
+	if err != nil || !bytes.Equal(data, subroutineProgram[:6]) {
+		t.Errorf("save did not write the memory range, got %X", data)
+	}
+
+	execute(t, m, out, "load "+path+" 3000", MonitorPrompt)
+	if c.ReadFromMemory(0x3000) != 0x31 || c.ReadFromMemory(0x3005) != 0x00 || c.ReadFromMemory(0x3004) != 0x10 {
+		t.Errorf("load did not read the file into memory")
+	}
+
+	if s := execute(t, m, out, "load "+path+" fffe", MonitorPrompt); !strings.HasPrefix(s, "? ") {
+		t.Errorf("load did not reject a file past the end of memory, got %q", s)
+	}
+}
+
+func TestMonitorUnknownCommand(t *testing.T) {
+	m, out, _, _ := createMonitor(subroutineProgram)
+
+	if s := execute(t, m, out, "zz", MonitorPrompt); s != "? unknown command \"zz\", h for help\n" {
+		t.Errorf("unknown command was not reported, got %q", s)
+	}
+
+	if s := execute(t, m, out, "  ", MonitorPrompt); s != "" {
+		t.Errorf("empty line printed %q", s)
+	}
+}
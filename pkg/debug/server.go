@@ -1,13 +1,32 @@
 package debug
 
 import (
+	"context"
+	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
-	"os"
+	"strconv"
+	"time"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/ihex"
 )
 
+// The browser debugger, served at /
+//
+//go:embed web/index.html
+var web embed.FS
+
+// How long control endpoints wait for the host to carry out a command
+const controlTimeout = 5 * time.Second
+
+// maxStepCount bounds /control/step, as the host runs the steps while every
+// other client waits
+const maxStepCount = 100000
+
 type ResponseError struct {
 	StatusCode int
 	Message    string
@@ -17,55 +36,269 @@ type MemoryDumpResponse struct {
 	Data []string
 }
 
-func getMemoryDump(w http.ResponseWriter, _ *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+type FlagsResponse struct {
+	PSW      byte `json:"psw"`
+	Sign     bool `json:"sign"`
+	Zero     bool `json:"zero"`
+	AuxCarry bool `json:"auxCarry"`
+	Parity   bool `json:"parity"`
+	Carry    bool `json:"carry"`
+}
 
-	memory, err := os.ReadFile(".dump/memory")
-	if err != nil {
-		res := &ResponseError{
-			StatusCode: http.StatusInternalServerError,
-			Message:    "Could not read memory dump",
+type CyclesResponse struct {
+	Cycles uint `json:"cycles"`
+}
+
+type InterruptsResponse struct {
+	Enabled       bool `json:"enabled"`
+	EnablePending bool `json:"enablePending"`
+	Halted        bool `json:"halted"`
+}
+
+type PortsResponse struct {
+	Ports []PortActivity `json:"ports"`
+}
+
+type ControlResponse struct {
+	Paused bool   `json:"paused"`
+	PC     uint16 `json:"pc"`
+	Cycles uint   `json:"cycles"`
+}
+
+// PortActivity counts the IN and OUT instructions that accessed a port and
+// keeps the last values transferred.
+type PortActivity struct {
+	Port        byte   `json:"port"`
+	Reads       uint64 `json:"reads"`
+	LastRead    byte   `json:"lastRead"`
+	Writes      uint64 `json:"writes"`
+	LastWritten byte   `json:"lastWritten"`
+}
+
+// Snapshot is the state of the machine between two instructions.
+type Snapshot struct {
+	Memory                 []byte
+	Registers              map[string]byte
+	Pointers               map[string]uint16
+	Flags                  byte
+	Cycles                 uint
+	InterruptsEnabled      bool
+	InterruptEnablePending bool
+	Halted                 bool
+	// Ports that have been accessed since the debugger was created
+	Ports []PortActivity
+}
+
+// Snapshot waits for the current instruction, or the frame when the host
+// runs frames inside Exec, to finish and copies the machine state.
+func (d *Debugger) Snapshot() Snapshot {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.snapshot()
+}
+
+func (d *Debugger) snapshot() Snapshot {
+	s := Snapshot{
+		Memory:    d.cpu.GetMemory(),
+		Registers: d.cpu.GetRegisters(),
+		Pointers:  d.cpu.GetPointers(),
+		Flags:     d.cpu.GetFlags(),
+		Cycles:    d.cpu.GetCycles(),
+		Halted:    d.cpu.IsHalted(),
+		Ports:     []PortActivity{},
+	}
+	s.InterruptsEnabled, s.InterruptEnablePending = d.cpu.GetInterruptState()
+
+	for port, activity := range d.ports {
+		if activity.Reads > 0 || activity.Writes > 0 {
+			activity.Port = byte(port)
+			s.Ports = append(s.Ports, activity)
 		}
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(res)
-		return
 	}
 
-	hexMemoryDump := []string{}
-	for _, v := range memory {
-		hexMemoryDump = append(hexMemoryDump, fmt.Sprintf("%.2X", v))
+	return s
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, res any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(res)
+}
+
+// getMemoryDump returns memory as JSON, or with ?format=ihex or ?format=text
+// as Intel HEX or as hex and ASCII labelled with the memory map.
+func (d *Debugger) getMemoryDump(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	memory := d.cpu.GetMemory()
+	regions := d.memoryMap
+	d.mu.Unlock()
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		hexMemoryDump := make([]string, len(memory))
+		for i, v := range memory {
+			hexMemoryDump[i] = fmt.Sprintf("%.2X", v)
+		}
+
+		writeJSON(w, http.StatusOK, &MemoryDumpResponse{
+			Data: hexMemoryDump,
+		})
+	case "ihex":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		ihex.Write(w, 0x0000, memory)
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		writeAnnotatedMemory(w, 0x0000, memory, regions)
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown format %q, expected json, ihex or text", format))
 	}
+}
 
-	res := &MemoryDumpResponse{
-		Data: hexMemoryDump,
+func (d *Debugger) getDumpState(w http.ResponseWriter, _ *http.Request) {
+	state := d.DumpState()
+	writeJSON(w, http.StatusOK, &state)
+}
+
+func (d *Debugger) getCpuState(w http.ResponseWriter, _ *http.Request) {
+	s := d.Snapshot()
+	symbols := d.Symbols()
+
+	state := &CpuState{
+		Registers: s.Registers,
+		Pointers:  s.Pointers,
+		Labels:    make(map[string]string),
 	}
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(res)
+	for name, addr := range s.Pointers {
+		if label, ok := symbols.Label(addr); ok {
+			state.Labels[name] = label
+		}
+	}
+
+	writeJSON(w, http.StatusOK, state)
 }
 
-func getCpuState(w http.ResponseWriter, _ *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+func (d *Debugger) getFlags(w http.ResponseWriter, _ *http.Request) {
+	flags := flagsResponse(d.Snapshot().Flags)
+	writeJSON(w, http.StatusOK, &flags)
+}
 
-	cpuState, err := os.ReadFile(".dump/cpu_state.json")
-	if err != nil {
-		res := &ResponseError{
-			StatusCode: http.StatusInternalServerError,
-			Message:    "Could not read cpu dump",
+func (d *Debugger) getCycles(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, &CyclesResponse{Cycles: d.Snapshot().Cycles})
+}
+
+func (d *Debugger) getInterrupts(w http.ResponseWriter, _ *http.Request) {
+	s := d.Snapshot()
+
+	writeJSON(w, http.StatusOK, &InterruptsResponse{
+		Enabled:       s.InterruptsEnabled,
+		EnablePending: s.InterruptEnablePending,
+		Halted:        s.Halted,
+	})
+}
+
+func (d *Debugger) getPorts(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, &PortsResponse{Ports: d.Snapshot().Ports})
+}
+
+func getDebuggerPage(w http.ResponseWriter, r *http.Request) {
+	http.ServeFileFS(w, r, web, "web/index.html")
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, &ResponseError{
+		StatusCode: statusCode,
+		Message:    message,
+	})
+}
+
+// control returns a handler that has the host carry out a command and replies
+// with the state it left the machine in.
+func (d *Debugger) control(kind CommandKind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		count := 1
+		if kind == CommandStep && r.URL.Query().Has("count") {
+			n, err := strconv.Atoi(r.URL.Query().Get("count"))
+			if err != nil || n < 1 || n > maxStepCount {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("count must be a number from 1 to %d", maxStepCount))
+				return
+			}
+			count = n
 		}
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(res)
-		return
+
+		ctx, cancel := context.WithTimeout(r.Context(), controlTimeout)
+		defer cancel()
+
+		if err := d.send(ctx, kind, count); err != nil {
+			writeError(w, http.StatusServiceUnavailable, fmt.Sprintf("%s was not carried out: %v", kind, err))
+			return
+		}
+
+		s := d.Snapshot()
+		writeJSON(w, http.StatusOK, &ControlResponse{
+			Paused: d.Paused(),
+			PC:     s.Pointers["pc"],
+			Cycles: s.Cycles,
+		})
 	}
+}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write(cpuState)
+// Handler returns the debug HTTP API of d. Every Debugger has its own, so
+// several can be served in one process.
+func (d *Debugger) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /{$}", getDebuggerPage)
+	mux.HandleFunc("GET /stream", d.stream)
+	mux.HandleFunc("GET /dump/memory", d.getMemoryDump)
+	mux.HandleFunc("GET /dump/cpu", d.getCpuState)
+	mux.HandleFunc("GET /dump/state", d.getDumpState)
+	mux.HandleFunc("GET /dump/flags", d.getFlags)
+	mux.HandleFunc("GET /dump/cycles", d.getCycles)
+	mux.HandleFunc("GET /dump/interrupts", d.getInterrupts)
+	mux.HandleFunc("GET /dump/ports", d.getPorts)
+	mux.HandleFunc("POST /control/pause", d.control(CommandPause))
+	mux.HandleFunc("POST /control/resume", d.control(CommandResume))
+	mux.HandleFunc("POST /control/step", d.control(CommandStep))
+	mux.HandleFunc("POST /control/step-frame", d.control(CommandStepFrame))
+	mux.HandleFunc("POST /control/reset", d.control(CommandReset))
+	mux.HandleFunc("POST /cheats/searches", d.startSearch)
+	mux.HandleFunc("GET /cheats/searches/{id}", d.getSearch)
+	mux.HandleFunc("POST /cheats/searches/{id}/filter", d.filterSearch)
+	mux.HandleFunc("DELETE /cheats/searches/{id}", d.deleteSearch)
+	mux.HandleFunc("GET /cheats", d.getCheats)
+	mux.HandleFunc("POST /cheats", d.addCheat)
+	mux.HandleFunc("DELETE /cheats/{addr}", d.removeCheat)
+	mux.HandleFunc("GET /symbols", d.getSymbols)
+	mux.HandleFunc("GET /symbols/{name}", d.getSymbol)
+
+	return mux
 }
 
-func (d *Debugger) StartHttpServer() {
-	log.Println("Running debug server...")
+// StartHttpServer serves the debug HTTP API on addr until Shutdown is called.
+func (d *Debugger) StartHttpServer(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Debug server listening on %s\n", l.Addr())
+	return d.ServeHttp(l)
+}
 
-	http.HandleFunc("GET /dump/memory", getMemoryDump)
-	http.HandleFunc("GET /dump/cpu", getCpuState)
+// ServeHttp serves the debug HTTP API on l. It returns nil once Shutdown has
+// been called.
+func (d *Debugger) ServeHttp(l net.Listener) error {
+	if err := d.httpServer.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
 
-	http.ListenAndServe(":8080", nil)
+// Shutdown stops serving the debug HTTP API, waiting for requests in progress
+// until ctx is done.
+func (d *Debugger) Shutdown(ctx context.Context) error {
+	err := d.httpServer.Shutdown(ctx)
+	d.closeStreams()
+	return err
 }
@@ -0,0 +1,297 @@
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/cpu"
+)
+
+var portsProgram = []byte{
+	0x31, 0x00, 0x24, // LXI SP,$2400
+	0xFB,       // EI
+	0xDB, 0x01, // IN $01
+	0x3C,       // INR A
+	0xD3, 0x03, // OUT $03
+	0xD3, 0x03, // OUT $03
+	0x76, // HLT
+}
+
+func serve(t *testing.T, h http.HandlerFunc, r *http.Request, statusCode int, res any) {
+	w := httptest.NewRecorder()
+	h(w, r)
+
+	if w.Code != statusCode || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Handler did not reply with JSON and status %d, got %d %q", statusCode, w.Code, w.Header().Get("Content-Type"))
+	}
+
+	if err := json.Unmarshal(w.Body.Bytes(), res); err != nil {
+		t.Fatalf("Handler did not reply with valid JSON: %v", err)
+	}
+}
+
+func get(t *testing.T, h http.HandlerFunc, res any) {
+	serve(t, h, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusOK, res)
+}
+
+// Carries out control commands the way cmd/invaders does, without a machine
+func startCommandHost(d *Debugger, c *cpu.Intel8080) <-chan Command {
+	received := make(chan Command, 1)
+
+	go func() {
+		for cmd := range d.Commands() {
+			switch cmd.Kind {
+			case CommandPause:
+				d.Pause()
+			case CommandResume:
+				d.Resume()
+			case CommandStep:
+				d.ExecSteps(cmd.Count, func() { c.Run() })
+			}
+			cmd.Done()
+			received <- cmd
+		}
+	}()
+
+	return received
+}
+
+func TestServerCpuState(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(portsProgram)
+	run(d, c)
+
+	var res CpuState
+	get(t, d.getCpuState, &res)
+
+	if res.Registers["A"] != 0x02 || res.Pointers["sp"] != 0x2400 || res.Pointers["pc"] != 0x000C {
+		t.Errorf("getCpuState did not return the live CPU state, got %v %v", res.Registers, res.Pointers)
+	}
+}
+
+func TestServerMemoryDump(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(portsProgram)
+	c.WriteIntoMemory(0x2000, 0xAB)
+
+	var res MemoryDumpResponse
+	get(t, d.getMemoryDump, &res)
+
+	if len(res.Data) != 0x10000 || res.Data[0] != "31" || res.Data[0x2000] != "AB" {
+		t.Errorf("getMemoryDump did not return the live memory")
+	}
+}
+
+func TestServerFlags(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(portsProgram)
+	c.SetFlags(0x41)
+
+	var res FlagsResponse
+	get(t, d.getFlags, &res)
+
+	expected := FlagsResponse{PSW: 0x43, Zero: true, Carry: true}
+	if res != expected {
+		t.Errorf("getFlags did not decode the flags, got %+v", res)
+	}
+}
+
+func TestServerCyclesAndInterrupts(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(portsProgram)
+	run(d, c)
+
+	var cycles CyclesResponse
+	get(t, d.getCycles, &cycles)
+
+	if cycles.Cycles != 10+4+10+5+10+10+7 {
+		t.Errorf("getCycles did not return the cycle count, got %d", cycles.Cycles)
+	}
+
+	var interrupts InterruptsResponse
+	get(t, d.getInterrupts, &interrupts)
+
+	expected := InterruptsResponse{Enabled: true, Halted: true}
+	if interrupts != expected {
+		t.Errorf("getInterrupts did not return the interrupt state, got %+v", interrupts)
+	}
+}
+
+func TestServerPorts(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(portsProgram)
+	run(d, c)
+
+	var res PortsResponse
+	get(t, d.getPorts, &res)
+
+	expected := []PortActivity{
+		{Port: 0x01, Reads: 1, LastRead: 0x01},
+		{Port: 0x03, Writes: 2, LastWritten: 0x02},
+	}
+	if !reflect.DeepEqual(res.Ports, expected) {
+		t.Errorf("getPorts did not return the port activity, got %+v", res.Ports)
+	}
+}
+
+func TestServerControlStep(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(portsProgram)
+	received := startCommandHost(d, c)
+
+	var res ControlResponse
+	serve(t, d.control(CommandStep), httptest.NewRequest(http.MethodPost, "/control/step?count=3", nil), http.StatusOK, &res)
+
+	if cmd := <-received; cmd.Kind != CommandStep || cmd.Count != 3 {
+		t.Errorf("control did not send the step command, got %s %d", cmd.Kind, cmd.Count)
+	}
+
+	expected := ControlResponse{Paused: true, PC: 0x0006, Cycles: 10 + 4 + 10}
+	if res != expected {
+		t.Errorf("control did not reply with the state after stepping, got %+v", res)
+	}
+
+	serve(t, d.control(CommandResume), httptest.NewRequest(http.MethodPost, "/control/resume", nil), http.StatusOK, &res)
+
+	if cmd := <-received; cmd.Kind != CommandResume || res.Paused {
+		t.Errorf("control did not resume execution, got %s", cmd.Kind)
+	}
+}
+
+func TestServerControlStepStopsAtBreakpoint(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(subroutineProgram)
+	d.AddBreakpoint(0x0020)
+	received := startCommandHost(d, c)
+
+	var res ControlResponse
+	serve(t, d.control(CommandStep), httptest.NewRequest(http.MethodPost, "/control/step?count=100", nil), http.StatusOK, &res)
+	<-received
+
+	if !res.Paused || res.PC != 0x0020 || d.LastStop().Kind != StopBreakpoint {
+		t.Errorf("control did not stop stepping at the breakpoint, got %+v %s", res, d.LastStop())
+	}
+
+	if c.GetRegisters()["C"] != 0 {
+		t.Errorf("instruction at the breakpoint was executed")
+	}
+}
+
+func TestServerControlRejectsBadCount(t *testing.T) {
+	d, _ := createDebuggerWithProgramLoaded(portsProgram)
+
+	for _, count := range []string{"0", "-1", "abc", "100001"} {
+		var res ResponseError
+		serve(t, d.control(CommandStep), httptest.NewRequest(http.MethodPost, "/control/step?count="+count, nil), http.StatusBadRequest, &res)
+
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("control did not reject count=%s", count)
+		}
+	}
+}
+
+func TestServerControlWithoutHost(t *testing.T) {
+	d, _ := createDebuggerWithProgramLoaded(portsProgram)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var res ResponseError
+	r := httptest.NewRequest(http.MethodPost, "/control/pause", nil).WithContext(ctx)
+	serve(t, d.control(CommandPause), r, http.StatusServiceUnavailable, &res)
+
+	if d.Paused() {
+		t.Errorf("control carried out a command nobody received")
+	}
+}
+
+// Serves the debug HTTP API of d on a free port and returns its URL
+func startHttpServer(t *testing.T, d *Debugger) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan error, 1)
+	go func() { served <- d.ServeHttp(l) }()
+
+	t.Cleanup(func() {
+		d.Shutdown(context.Background())
+		if err := <-served; err != nil {
+			t.Errorf("ServeHttp did not return nil after Shutdown, got %v", err)
+		}
+	})
+
+	return "http://" + l.Addr().String()
+}
+
+func TestServerRoutes(t *testing.T) {
+	d, _ := createDebuggerWithProgramLoaded(portsProgram)
+	h := d.Handler()
+
+	for _, r := range []struct{ method, path string }{
+		{http.MethodPost, "/dump/cpu"},
+		{http.MethodGet, "/control/pause"},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(r.method, r.path, nil))
+
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Handler did not reject %s %s, got %d", r.method, r.path, w.Code)
+		}
+	}
+}
+
+func TestServerDebuggersCoexist(t *testing.T) {
+	for _, a := range []byte{0x11, 0x22} {
+		t.Run(fmt.Sprintf("A=%02X", a), func(t *testing.T) {
+			t.Parallel()
+
+			d, c := createDebuggerWithProgramLoaded([]byte{0x3e, a}) // MVI A
+			run(d, c)
+			url := startHttpServer(t, d)
+
+			res, err := http.Get(url + "/dump/cpu")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			var state CpuState
+			if err := json.NewDecoder(res.Body).Decode(&state); err != nil || state.Registers["A"] != a {
+				t.Errorf("Debug server did not serve its own debugger, got %v", state.Registers)
+			}
+		})
+	}
+}
+
+func TestServerShutdown(t *testing.T) {
+	d, _ := createDebuggerWithProgramLoaded(portsProgram)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan error, 1)
+	go func() { served <- d.ServeHttp(l) }()
+
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	if err := <-served; err != nil {
+		t.Errorf("ServeHttp did not return nil after Shutdown, got %v", err)
+	}
+
+	if _, err := http.Get("http://" + l.Addr().String() + "/dump/cpu"); err == nil {
+		t.Errorf("Debug server still served requests after Shutdown")
+	}
+}
+
+func TestStartHttpServerReportsListenErrors(t *testing.T) {
+	d, _ := createDebuggerWithProgramLoaded(portsProgram)
+
+	if err := d.StartHttpServer("invalid address"); err == nil {
+		t.Errorf("StartHttpServer did not report that it cannot listen")
+	}
+}
@@ -0,0 +1,245 @@
+package debug
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Instructions disassembled from PC and bytes of memory sent with each frame
+const (
+	streamDisassemblyLength = 16
+	streamMemoryLength      = 256
+)
+
+// StreamState is sent to stream clients as a text message before each frame.
+// The frame follows as a binary message: the VRAM returned by GetVRAM,
+// compressed with raw DEFLATE.
+type StreamState struct {
+	Type        string            `json:"type"`
+	Frame       uint64            `json:"frame"`
+	Paused      bool              `json:"paused"`
+	Registers   map[string]byte   `json:"registers"`
+	Pointers    map[string]uint16 `json:"pointers"`
+	Flags       byte              `json:"flags"`
+	Cycles      uint              `json:"cycles"`
+	Disassembly []DisassemblyLine `json:"disassembly"`
+	Memory      MemoryWindow      `json:"memory"`
+}
+
+type DisassemblyLine struct {
+	Addr        uint16 `json:"addr"`
+	Bytes       string `json:"bytes"`
+	Instruction string `json:"instruction"`
+	// Symbol at the address and its comment
+	Label   string `json:"label,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+type MemoryWindow struct {
+	Addr uint16 `json:"addr"`
+	Data string `json:"data"`
+}
+
+// StreamMessage is sent by stream clients. Input presses or releases a
+// cabinet control, memory moves the memory window sent with each frame.
+type StreamMessage struct {
+	Type    string `json:"type"`
+	Key     string `json:"key"`
+	Pressed bool   `json:"pressed"`
+	Addr    uint16 `json:"addr"`
+}
+
+type streamFrame struct {
+	state []byte
+	vram  []byte
+}
+
+type streamClient struct {
+	ws     *wsConn
+	frames chan streamFrame
+	// start of the memory window, guarded by Debugger.mu
+	memoryAddr uint16
+}
+
+// PublishFrame sends the screen and CPU state to stream clients. Hosts call it
+// once per rendered frame, outside Exec. Clients too slow to keep up miss
+// frames.
+func (d *Debugger) PublishFrame() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.frames++
+	if len(d.streams) == 0 {
+		return
+	}
+
+	var vram bytes.Buffer
+	w, _ := flate.NewWriter(&vram, flate.BestSpeed)
+	w.Write(d.cpu.GetVRAM())
+	w.Close()
+
+	state := StreamState{
+		Type:        "state",
+		Frame:       d.frames,
+		Paused:      d.paused,
+		Registers:   d.cpu.GetRegisters(),
+		Pointers:    d.cpu.GetPointers(),
+		Flags:       d.cpu.GetFlags(),
+		Cycles:      d.cpu.GetCycles(),
+		Disassembly: d.disassemble(d.cpu.GetPC(), streamDisassemblyLength),
+	}
+
+	for _, c := range d.streams {
+		state.Memory = d.memoryWindow(c.memoryAddr, streamMemoryLength)
+
+		data, err := json.Marshal(&state)
+		if err != nil {
+			log.Println("Cannot encode stream state", err)
+			return
+		}
+
+		select {
+		case c.frames <- streamFrame{state: data, vram: vram.Bytes()}:
+		default:
+		}
+	}
+}
+
+func (d *Debugger) disassemble(addr uint16, count int) []DisassemblyLine {
+	lines := make([]DisassemblyLine, 0, count)
+	for n := 0; n < count; n++ {
+		i := d.instructionAt(addr)
+		line := DisassemblyLine{Addr: addr, Bytes: i.HexBytes(), Instruction: i.StringWith(d.symbols.Label)}
+		if sym, ok := d.symbols.At(addr); ok {
+			line.Label = sym.Name
+		}
+		line.Comment, _ = d.symbols.Comment(addr)
+		lines = append(lines, line)
+		addr += i.Size
+	}
+	return lines
+}
+
+func (d *Debugger) memoryWindow(addr uint16, length int) MemoryWindow {
+	data := make([]byte, length)
+	for i := range data {
+		data[i] = d.cpu.ReadFromMemory(addr + uint16(i))
+	}
+	return MemoryWindow{Addr: addr, Data: hex.EncodeToString(data)}
+}
+
+// stream serves a WebSocket client until it disconnects or the server is shut
+// down.
+func (d *Debugger) stream(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgradeWebSocket(w, r)
+	if err != nil {
+		log.Println("Cannot open stream", err)
+		return
+	}
+
+	c := &streamClient{ws: ws, frames: make(chan streamFrame, 2)}
+
+	d.mu.Lock()
+	d.streams = append(d.streams, c)
+	d.mu.Unlock()
+
+	log.Printf("Stream client %s connected\n", r.RemoteAddr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		d.writeFrames(ctx, c)
+	}()
+
+	d.readStream(c)
+
+	cancel()
+	<-written
+	d.removeStream(c)
+	ws.Close()
+
+	log.Printf("Stream client %s disconnected\n", r.RemoteAddr)
+}
+
+func (d *Debugger) writeFrames(ctx context.Context, c *streamClient) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case f := <-c.frames:
+			if c.ws.WriteMessage(wsText, f.state) != nil || c.ws.WriteMessage(wsBinary, f.vram) != nil {
+				// The reader sees the connection fail and ends the stream
+				c.ws.Close()
+				return
+			}
+		}
+	}
+}
+
+func (d *Debugger) readStream(c *streamClient) {
+	for {
+		opcode, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var msg StreamMessage
+		if opcode != wsText || json.Unmarshal(data, &msg) != nil {
+			log.Println("Ignoring invalid stream message")
+			continue
+		}
+
+		switch msg.Type {
+		case "input":
+			d.sendInput(msg.Key, msg.Pressed)
+		case "memory":
+			d.mu.Lock()
+			c.memoryAddr = msg.Addr
+			d.mu.Unlock()
+		default:
+			log.Printf("Ignoring stream message %q\n", msg.Type)
+		}
+	}
+}
+
+// sendInput hands an input event to the host without waiting for it to be
+// carried out, so inputs keep their order and the client is not held up.
+func (d *Debugger) sendInput(key string, pressed bool) {
+	cmd := Command{Kind: CommandInput, Key: key, Pressed: pressed, done: make(chan struct{})}
+
+	select {
+	case d.commands <- cmd:
+	case <-time.After(controlTimeout):
+		log.Printf("Input %s was dropped, the host did not receive it\n", key)
+	}
+}
+
+func (d *Debugger) removeStream(c *streamClient) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i, s := range d.streams {
+		if s == c {
+			d.streams = append(d.streams[:i:i], d.streams[i+1:]...)
+			return
+		}
+	}
+}
+
+// closeStreams disconnects stream clients, whose connections are no longer
+// tracked by the HTTP server.
+func (d *Debugger) closeStreams() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, c := range d.streams {
+		c.ws.Close()
+	}
+}
@@ -0,0 +1,152 @@
+package debug
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/cpu"
+)
+
+// Publishes frames until one reaches the client, which registers with the
+// debugger after the handshake
+func readFrame(t *testing.T, d *Debugger, c *wsClient) (StreamState, []byte) {
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+				d.PublishFrame()
+			}
+		}
+	}()
+
+	opcode, data := c.read()
+	if opcode != wsText {
+		t.Fatalf("Stream did not send the state first, got opcode %d", opcode)
+	}
+
+	var state StreamState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+
+	opcode, data = c.read()
+	if opcode != wsBinary {
+		t.Fatalf("Stream did not send the frame after the state, got opcode %d", opcode)
+	}
+
+	vram, err := io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatalf("Stream did not compress the frame with DEFLATE: %v", err)
+	}
+
+	return state, vram
+}
+
+func startStreamServer(t *testing.T, p []byte) (*Debugger, *cpu.Intel8080, string) {
+	d, c := createDebuggerWithProgramLoaded(p)
+	s := httptest.NewServer(d.Handler())
+	t.Cleanup(s.Close)
+
+	return d, c, s.URL
+}
+
+func TestStreamSendsFrames(t *testing.T) {
+	d, c, url := startStreamServer(t, portsProgram)
+	run(d, c)
+	c.WriteIntoMemory(0x2400, 0xff)
+
+	state, vram := readFrame(t, d, dialWebSocket(t, url))
+
+	if state.Type != "state" || state.Registers["A"] != 0x02 || state.Pointers["pc"] != 0x000C || state.Frame == 0 {
+		t.Errorf("Stream did not send the CPU state, got %+v", state)
+	}
+
+	if len(state.Disassembly) != streamDisassemblyLength || state.Disassembly[0].Addr != 0x000C {
+		t.Errorf("Stream did not disassemble from PC, got %+v", state.Disassembly)
+	}
+
+	if !bytes.Equal(vram, c.GetVRAM()) {
+		t.Errorf("Stream did not send the VRAM")
+	}
+}
+
+func TestStreamMemoryWindow(t *testing.T) {
+	d, c, url := startStreamServer(t, portsProgram)
+	c.WriteIntoMemory(0x2000, 0xab)
+	ws := dialWebSocket(t, url)
+
+	ws.write(wsText, true, []byte(`{"type":"memory","addr":8192}`))
+
+	// The message may arrive after a frame has been sent
+	for i := 0; i < 10; i++ {
+		state, _ := readFrame(t, d, ws)
+		if state.Memory.Addr == 0x2000 {
+			if !strings.HasPrefix(state.Memory.Data, "ab") || len(state.Memory.Data) != streamMemoryLength*2 {
+				t.Errorf("Stream did not send the memory window, got %q", state.Memory.Data)
+			}
+			return
+		}
+	}
+
+	t.Errorf("Stream did not move the memory window")
+}
+
+func TestStreamInput(t *testing.T) {
+	d, _, url := startStreamServer(t, portsProgram)
+	ws := dialWebSocket(t, url)
+
+	ws.write(wsText, true, []byte(`{"type":"input","key":"coin","pressed":true}`))
+
+	select {
+	case cmd := <-d.Commands():
+		cmd.Done()
+		if cmd.Kind != CommandInput || cmd.Key != "coin" || !cmd.Pressed {
+			t.Errorf("Stream did not send the input to the host, got %s %q %v", cmd.Kind, cmd.Key, cmd.Pressed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Stream did not send the input to the host")
+	}
+}
+
+func TestStreamClosedOnShutdown(t *testing.T) {
+	d, _ := createDebuggerWithProgramLoaded(portsProgram)
+	ws := dialWebSocket(t, startHttpServer(t, d))
+
+	// Wait for the client to be registered
+	readFrame(t, d, ws)
+	d.Shutdown(context.Background())
+
+	// Frames sent before the shutdown may still be buffered; a stream left
+	// open hits the deadline instead of EOF
+	if _, err := io.ReadAll(ws.r); err != nil {
+		t.Errorf("Shutdown did not close the stream: %v", err)
+	}
+}
+
+func TestDebuggerPage(t *testing.T) {
+	_, _, url := startStreamServer(t, portsProgram)
+
+	res, err := http.Get(url + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK || !strings.Contains(string(body), "/stream") {
+		t.Errorf("Debug server did not serve the debugger page, got %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,373 @@
+package debug
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SymbolType is what a symbol labels.
+type SymbolType int
+
+const (
+	SymbolCode SymbolType = iota
+	SymbolByte
+	SymbolWord
+	SymbolText
+)
+
+var symbolTypeNames = []string{"code", "byte", "word", "text"}
+
+func (t SymbolType) String() string {
+	return symbolTypeNames[t]
+}
+
+func parseSymbolType(s string) (SymbolType, bool) {
+	for t, name := range symbolTypeNames {
+		if name == s {
+			return SymbolType(t), true
+		}
+	}
+	return 0, false
+}
+
+func (t SymbolType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *SymbolType) UnmarshalText(text []byte) error {
+	st, ok := parseSymbolType(string(text))
+	if !ok {
+		return fmt.Errorf("invalid symbol type %q", text)
+	}
+	*t = st
+	return nil
+}
+
+// Symbol names an address, or Count bytes, words or characters of data
+// starting at it. Symbols without a name only carry a comment.
+type Symbol struct {
+	Addr    uint16     `json:"addr"`
+	Name    string     `json:"name,omitempty"`
+	Type    SymbolType `json:"type"`
+	Count   int        `json:"count"`
+	Comment string     `json:"comment,omitempty"`
+}
+
+// Size returns the number of bytes the symbol covers. Code symbols only
+// cover their address.
+func (s Symbol) Size() int {
+	switch s.Type {
+	case SymbolByte, SymbolText:
+		return s.Count
+	case SymbolWord:
+		return 2 * s.Count
+	}
+	return 1
+}
+
+// Symbols is a symbol file: names, comments and data types of addresses.
+// Symbols must not be changed once read, the zero value has no symbols.
+type Symbols struct {
+	// in address order
+	symbols []Symbol
+	byName  map[string]int
+}
+
+func validSymbolName(name string) bool {
+	for i, c := range name {
+		if !(c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// NewSymbols checks the symbols and sorts them by address. Names start with
+// a letter or _, are followed by letters, digits and _, and are unique.
+func NewSymbols(symbols []Symbol) (*Symbols, error) {
+	s := &Symbols{symbols: append([]Symbol{}, symbols...), byName: make(map[string]int)}
+	sort.SliceStable(s.symbols, func(a, b int) bool {
+		return s.symbols[a].Addr < s.symbols[b].Addr
+	})
+
+	for i, sym := range s.symbols {
+		if sym.Name == "" && sym.Comment == "" {
+			return nil, fmt.Errorf("symbol at $%04X has neither a name nor a comment", sym.Addr)
+		}
+		if sym.Count < 1 || sym.Type == SymbolCode && sym.Count != 1 {
+			return nil, fmt.Errorf("invalid count %d for %s symbol at $%04X", sym.Count, sym.Type, sym.Addr)
+		}
+		if int(sym.Addr)+sym.Size() > 0x10000 {
+			return nil, fmt.Errorf("symbol at $%04X goes past $FFFF", sym.Addr)
+		}
+
+		if sym.Name == "" {
+			continue
+		}
+		if !validSymbolName(sym.Name) {
+			return nil, fmt.Errorf("invalid symbol name %q", sym.Name)
+		}
+		if _, ok := s.byName[sym.Name]; ok {
+			return nil, fmt.Errorf("duplicate symbol %q", sym.Name)
+		}
+		s.byName[sym.Name] = i
+	}
+
+	return s, nil
+}
+
+// parseSymbolAddress parses hexadecimal addresses, optionally written $1234
+// or 0x1234.
+func parseSymbolAddress(s string) (uint16, error) {
+	digits := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(s), "$"), "0x")
+	addr, err := strconv.ParseUint(digits, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", s)
+	}
+	return uint16(addr), nil
+}
+
+func parseSymbol(line string) (Symbol, error) {
+	text, comment, _ := strings.Cut(line, ";")
+	sym := Symbol{Count: 1, Comment: strings.TrimSpace(comment)}
+
+	fields := strings.Fields(text)
+	if len(fields) > 4 {
+		return sym, fmt.Errorf("expected addr [name [type [count]]] [; comment]")
+	}
+
+	addr, err := parseSymbolAddress(fields[0])
+	if err != nil {
+		return sym, err
+	}
+	sym.Addr = addr
+
+	if len(fields) > 1 {
+		sym.Name = fields[1]
+	}
+
+	if len(fields) > 2 {
+		t, ok := parseSymbolType(fields[2])
+		if !ok {
+			return sym, fmt.Errorf("invalid type %q, expected code, byte, word or text", fields[2])
+		}
+		sym.Type = t
+	}
+
+	if len(fields) > 3 {
+		count, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return sym, fmt.Errorf("invalid count %q", fields[3])
+		}
+		sym.Count = count
+	}
+
+	return sym, nil
+}
+
+// ParseSymbols reads a symbol file. Each line is a symbol:
+//
+//	addr [name [type [count]]] [; comment]
+//
+// e.g.
+//
+//	$1439 DrawSimpSprite            ; Display character to screen
+//	$2000 waitOnDraw byte
+//	$2100 alienGrid byte 55         ; 1 for live aliens
+//
+// Addresses are hexadecimal, optionally written $1234 or 0x1234. Types are
+// code, the default, byte, word and text, count is the number of them.
+// Lines that start with ; are comments about the file and are skipped.
+func ParseSymbols(r io.Reader) (*Symbols, error) {
+	var symbols []Symbol
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, ";") {
+			continue
+		}
+
+		sym, err := parseSymbol(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		symbols = append(symbols, sym)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewSymbols(symbols)
+}
+
+// LoadSymbols reads the symbol file at path.
+func LoadSymbols(path string) (*Symbols, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseSymbols(f)
+}
+
+// Write writes the symbols in the format ParseSymbols reads.
+func (s *Symbols) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	for _, sym := range s.symbols {
+		line := fmt.Sprintf("$%04X", sym.Addr)
+		if sym.Name != "" {
+			line += " " + sym.Name
+			if sym.Type != SymbolCode {
+				line += " " + sym.Type.String()
+			}
+			if sym.Count != 1 {
+				line += " " + strconv.Itoa(sym.Count)
+			}
+		}
+
+		if sym.Comment != "" {
+			line = fmt.Sprintf("%-31s ; %s", line, sym.Comment)
+		}
+		fmt.Fprintln(bw, line)
+	}
+
+	return bw.Flush()
+}
+
+// All returns the symbols in address order.
+func (s *Symbols) All() []Symbol {
+	return append([]Symbol{}, s.symbols...)
+}
+
+// Lookup returns the symbol called name.
+func (s *Symbols) Lookup(name string) (Symbol, bool) {
+	i, ok := s.byName[name]
+	if !ok {
+		return Symbol{}, false
+	}
+	return s.symbols[i], true
+}
+
+// At returns the symbol named at addr.
+func (s *Symbols) At(addr uint16) (Symbol, bool) {
+	i := sort.Search(len(s.symbols), func(i int) bool {
+		return s.symbols[i].Addr >= addr
+	})
+	for ; i < len(s.symbols) && s.symbols[i].Addr == addr; i++ {
+		if s.symbols[i].Name != "" {
+			return s.symbols[i], true
+		}
+	}
+	return Symbol{}, false
+}
+
+// Label returns the name of addr: the name of the symbol at addr, or name+n
+// for the nth byte of data.
+func (s *Symbols) Label(addr uint16) (string, bool) {
+	if sym, ok := s.At(addr); ok {
+		return sym.Name, true
+	}
+
+	for _, sym := range s.symbols {
+		if sym.Name != "" && sym.Type != SymbolCode && addr > sym.Addr && int(addr) < int(sym.Addr)+sym.Size() {
+			return fmt.Sprintf("%s+%d", sym.Name, addr-sym.Addr), true
+		}
+	}
+	return "", false
+}
+
+// Comment returns the comment of the symbols at addr.
+func (s *Symbols) Comment(addr uint16) (string, bool) {
+	var comments []string
+	for _, sym := range s.symbols {
+		if sym.Addr == addr && sym.Comment != "" {
+			comments = append(comments, sym.Comment)
+		}
+	}
+	return strings.Join(comments, "; "), len(comments) > 0
+}
+
+// Resolve returns the address of name or name+offset, where the offset may
+// be written in any base strconv accepts.
+func (s *Symbols) Resolve(text string) (uint16, bool) {
+	name, offset, hasOffset := strings.Cut(text, "+")
+
+	sym, ok := s.Lookup(name)
+	if !ok {
+		return 0, false
+	}
+
+	var n uint64
+	if hasOffset {
+		var err error
+		if n, err = strconv.ParseUint(offset, 0, 16); err != nil || int(sym.Addr)+int(n) > 0xFFFF {
+			return 0, false
+		}
+	}
+	return sym.Addr + uint16(n), true
+}
+
+// SetSymbols names addresses in breakpoints, disassembly, the HTTP API and
+// DAP clients.
+func (d *Debugger) SetSymbols(s *Symbols) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.symbols = s
+}
+
+// Symbols returns the symbols set with SetSymbols.
+func (d *Debugger) Symbols() *Symbols {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.symbols
+}
+
+// ParseAddress parses a symbol name, name+offset, or a number in any base
+// strconv accepts.
+func (d *Debugger) ParseAddress(text string) (uint16, error) {
+	if addr, ok := d.Symbols().Resolve(text); ok {
+		return addr, nil
+	}
+
+	addr, err := strconv.ParseUint(text, 0, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", text)
+	}
+	return uint16(addr), nil
+}
+
+func (d *Debugger) getSymbols(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, d.Symbols().All())
+}
+
+// getSymbol returns the symbol called name, or the first symbol at name when
+// it is an address.
+func (d *Debugger) getSymbol(w http.ResponseWriter, r *http.Request) {
+	symbols := d.Symbols()
+	name := r.PathValue("name")
+
+	sym, ok := symbols.Lookup(name)
+	if !ok {
+		if addr, err := strconv.ParseUint(name, 0, 16); err == nil {
+			sym, ok = symbols.At(uint16(addr))
+		}
+	}
+
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no symbol %s", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, &sym)
+}
@@ -0,0 +1,191 @@
+package debug
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Symbols of subroutineProgram
+const subroutineSymbols = `; subroutineProgram
+$0000 Start                     ; Entry point
+$0010 Outer
+$0020 Inner                     ; Loads C
+$0020                           ; Called from Outer
+$2000 counter byte
+$2002 table word 4
+$2100 message text 12
+`
+
+func parseTestSymbols(t *testing.T) *Symbols {
+	s, err := ParseSymbols(strings.NewReader(subroutineSymbols))
+	if err != nil {
+		t.Fatalf("ParseSymbols returned an error: %s", err)
+	}
+	return s
+}
+
+func TestParseSymbols(t *testing.T) {
+	s := parseTestSymbols(t)
+
+	expected := []Symbol{
+		{Addr: 0x0000, Name: "Start", Type: SymbolCode, Count: 1, Comment: "Entry point"},
+		{Addr: 0x0010, Name: "Outer", Type: SymbolCode, Count: 1},
+		{Addr: 0x0020, Name: "Inner", Type: SymbolCode, Count: 1, Comment: "Loads C"},
+		{Addr: 0x0020, Type: SymbolCode, Count: 1, Comment: "Called from Outer"},
+		{Addr: 0x2000, Name: "counter", Type: SymbolByte, Count: 1},
+		{Addr: 0x2002, Name: "table", Type: SymbolWord, Count: 4},
+		{Addr: 0x2100, Name: "message", Type: SymbolText, Count: 12},
+	}
+	if !reflect.DeepEqual(s.All(), expected) {
+		t.Errorf("ParseSymbols did not read the symbols, got %+v", s.All())
+	}
+}
+
+func TestSymbolsRoundTrip(t *testing.T) {
+	s := parseTestSymbols(t)
+
+	var out strings.Builder
+	if err := s.Write(&out); err != nil {
+		t.Fatalf("Write returned an error: %s", err)
+	}
+
+	// Everything but the comment about the file is written back
+	if expected := subroutineSymbols[strings.Index(subroutineSymbols, "\n")+1:]; out.String() != expected {
+		t.Errorf("Write did not write the symbols back, got\n%s", out.String())
+	}
+
+	read, err := ParseSymbols(strings.NewReader(out.String()))
+	if err != nil || !reflect.DeepEqual(read.All(), s.All()) {
+		t.Errorf("ParseSymbols did not read the written symbols back, got %+v %v", read, err)
+	}
+}
+
+func TestParseSymbolsErrors(t *testing.T) {
+	for _, text := range []string{
+		"DrawSprite $1439",
+		"$1439 DrawSprite sprite",
+		"$1439 DrawSprite code 2",
+		"$2000 counter byte many",
+		"$2000 counter byte 0",
+		"$FFFF table word 1",
+		"$2000 1counter byte",
+		"$2000 counter\n$2001 counter",
+		"$2000 counter byte 1 extra",
+	} {
+		if _, err := ParseSymbols(strings.NewReader(text)); err == nil {
+			t.Errorf("ParseSymbols did not reject %q", text)
+		}
+	}
+}
+
+func TestSymbolsLabelAndResolve(t *testing.T) {
+	s := parseTestSymbols(t)
+
+	labels := map[uint16]string{0x0010: "Outer", 0x0011: "", 0x2000: "counter", 0x2001: "", 0x2007: "table+5", 0x210B: "message+11", 0x210C: ""}
+	for addr, expected := range labels {
+		if label, ok := s.Label(addr); label != expected || ok != (expected != "") {
+			t.Errorf("Label did not name $%04X %q, got %q", addr, expected, label)
+		}
+	}
+
+	if comment, _ := s.Comment(0x0020); comment != "Loads C; Called from Outer" {
+		t.Errorf("Comment did not return the comments at $0020, got %q", comment)
+	}
+
+	if addr, ok := s.Resolve("table+0x02"); !ok || addr != 0x2004 {
+		t.Errorf("Resolve did not resolve table+0x02, got $%04X", addr)
+	}
+
+	for _, text := range []string{"Missing", "table+x", "0x2000"} {
+		if _, ok := s.Resolve(text); ok {
+			t.Errorf("Resolve resolved %q", text)
+		}
+	}
+}
+
+func TestSymbolicBreakpoints(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(subroutineProgram)
+	d.SetSymbols(parseTestSymbols(t))
+
+	for _, spec := range []string{"Inner", "write=counter+1:0x05"} {
+		if err := d.AddBreakpointSpec(spec); err != nil {
+			t.Errorf("AddBreakpointSpec(%q) returned %v", spec, err)
+		}
+	}
+
+	if !d.breakpoints[0x0020] || len(d.watchpoints) != 1 || d.watchpoints[0].Addr != 0x2001 {
+		t.Errorf("AddBreakpointSpec did not resolve the symbols, got %v %v", d.breakpoints, d.watchpoints)
+	}
+
+	if err := d.AddBreakpointSpec("Missing"); err == nil {
+		t.Errorf("AddBreakpointSpec did not reject an unknown symbol")
+	}
+
+	run(d, c)
+	if c.GetPC() != 0x0020 {
+		t.Errorf("Debugger did not stop at Inner, PC is $%04X", c.GetPC())
+	}
+
+	if lines := d.disassemble(0x0003, 1); lines[0].Instruction != "CALL Outer" {
+		t.Errorf("disassemble did not name the CALL target, got %+v", lines[0])
+	}
+
+	if lines := d.disassemble(0x0020, 1); lines[0].Label != "Inner" || lines[0].Comment != "Loads C; Called from Outer" {
+		t.Errorf("disassemble did not label the instruction, got %+v", lines[0])
+	}
+}
+
+func TestMonitorSymbols(t *testing.T) {
+	m, out, d, _ := createMonitor(subroutineProgram)
+	d.SetSymbols(parseTestSymbols(t))
+
+	execute(t, m, out, "b Outer", MonitorPrompt)
+	if bs := d.Breakpoints(); len(bs) != 1 || bs[0] != 0x0010 {
+		t.Errorf("b did not add a breakpoint at Outer, got %v", bs)
+	}
+
+	expected := "Outer:\n" +
+		"*0010: 06 02     MVI B,$02\n" +
+		" 0012: CD 20 00  CALL Inner\n"
+	if s := execute(t, m, out, "l Outer 2", MonitorPrompt); s != expected {
+		t.Errorf("l did not list with symbols, got %q", s)
+	}
+}
+
+func TestServerSymbols(t *testing.T) {
+	d, c := createDebuggerWithProgramLoaded(subroutineProgram)
+	d.SetSymbols(parseTestSymbols(t))
+	d.AddBreakpoint(0x0020)
+	run(d, c)
+
+	var state CpuState
+	get(t, d.getCpuState, &state)
+	if state.Labels["pc"] != "Inner" {
+		t.Errorf("getCpuState did not name PC, got %v", state.Labels)
+	}
+
+	var symbols []Symbol
+	get(t, d.getSymbols, &symbols)
+	if len(symbols) != 7 || symbols[5].Name != "table" || symbols[5].Type != SymbolWord {
+		t.Errorf("getSymbols did not return the symbols, got %+v", symbols)
+	}
+
+	for _, name := range []string{"table", "0x2002"} {
+		r := httptest.NewRequest(http.MethodGet, "/symbols/"+name, nil)
+		r.SetPathValue("name", name)
+
+		var sym Symbol
+		serve(t, d.getSymbol, r, http.StatusOK, &sym)
+		if sym.Addr != 0x2002 || sym.Count != 4 {
+			t.Errorf("getSymbol did not return %s, got %+v", name, sym)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/symbols/Missing", nil)
+	r.SetPathValue("name", "Missing")
+	var res ResponseError
+	serve(t, d.getSymbol, r, http.StatusNotFound, &res)
+}
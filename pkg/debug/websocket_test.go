@@ -0,0 +1,182 @@
+package debug
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A browser-like client: frames it sends are masked
+type wsClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialWebSocket(t *testing.T, url string) *wsClient {
+	addr := strings.TrimPrefix(url, "http://")
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	t.Cleanup(func() { conn.Close() })
+
+	fmt.Fprintf(conn, "GET /stream HTTP/1.1\r\n"+
+		"Host: %s\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+
+		"Sec-WebSocket-Version: 13\r\n\r\n", addr)
+
+	r := bufio.NewReader(conn)
+	res, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.StatusCode != http.StatusSwitchingProtocols || res.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("WebSocket handshake was not completed, got %d %v", res.StatusCode, res.Header)
+	}
+
+	return &wsClient{t: t, conn: conn, r: r}
+}
+
+func (c *wsClient) write(opcode byte, fin bool, data []byte) {
+	header := []byte{opcode, 0x80 | byte(len(data))}
+	if fin {
+		header[0] |= 0x80
+	}
+
+	mask := []byte{0x12, 0x34, 0x56, 0x78}
+	masked := make([]byte, len(data))
+	for i := range data {
+		masked[i] = data[i] ^ mask[i%4]
+	}
+
+	c.conn.Write(append(append(header, mask...), masked...))
+}
+
+func (c *wsClient) read() (byte, []byte) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(c.r, header); err != nil {
+		c.t.Fatalf("no frame from the WebSocket server: %v", err)
+	}
+
+	length := int(header[1] & 0x7f)
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		io.ReadFull(c.r, ext)
+		length = int(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		io.ReadFull(c.r, ext)
+		length = int(binary.BigEndian.Uint64(ext))
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		c.t.Fatalf("no frame from the WebSocket server: %v", err)
+	}
+
+	return header[0] & 0x0f, data
+}
+
+func echoServer(t *testing.T) string {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgradeWebSocket(w, r)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		for {
+			opcode, data, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			ws.WriteMessage(opcode, data)
+		}
+	}))
+	t.Cleanup(s.Close)
+
+	return s.URL
+}
+
+func TestWebSocketAccept(t *testing.T) {
+	// The example from RFC 6455
+	if accept := websocketAccept("dGhlIHNhbXBsZSBub25jZQ=="); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("websocketAccept did not hash the key correctly, got %q", accept)
+	}
+}
+
+func TestWebSocketRejectsPlainRequests(t *testing.T) {
+	url := echoServer(t)
+
+	res, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("upgradeWebSocket did not reject a plain request, got %d", res.StatusCode)
+	}
+}
+
+func TestWebSocketMessages(t *testing.T) {
+	c := dialWebSocket(t, echoServer(t))
+
+	c.write(wsText, true, []byte("hello"))
+	if opcode, data := c.read(); opcode != wsText || string(data) != "hello" {
+		t.Errorf("WebSocket did not echo a text message, got %d %q", opcode, data)
+	}
+
+	// Fragmented, with a ping in between
+	c.write(wsBinary, false, []byte{1, 2})
+	c.write(wsPing, true, []byte("ping"))
+	c.write(wsContinuation, true, []byte{3})
+
+	if opcode, data := c.read(); opcode != wsPong || string(data) != "ping" {
+		t.Errorf("WebSocket did not answer the ping, got %d %q", opcode, data)
+	}
+
+	if opcode, data := c.read(); opcode != wsBinary || string(data) != "\x01\x02\x03" {
+		t.Errorf("WebSocket did not join the fragments, got %d %v", opcode, data)
+	}
+
+	c.write(wsClose, true, nil)
+	if opcode, _ := c.read(); opcode != wsClose {
+		t.Errorf("WebSocket did not answer the close frame, got %d", opcode)
+	}
+}
+
+func TestWebSocketLongMessages(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgradeWebSocket(w, r)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		ws.WriteMessage(wsBinary, make([]byte, 300))
+		ws.WriteMessage(wsBinary, make([]byte, 70000))
+	}))
+	t.Cleanup(s.Close)
+
+	c := dialWebSocket(t, s.URL)
+
+	for _, length := range []int{300, 70000} {
+		if _, data := c.read(); len(data) != length {
+			t.Errorf("WebSocket did not encode the length of a %d bytes message, got %d", length, len(data))
+		}
+	}
+}
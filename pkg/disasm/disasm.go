@@ -0,0 +1,195 @@
+package disasm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/opcode"
+)
+
+// Memory is read by the disassembler. *cpu.Intel8080 implements it.
+type Memory interface {
+	ReadFromMemory(addr uint16) byte
+}
+
+// Bytes is memory backed by a slice, such as a ROM image loaded at $0000.
+// Addresses past its end read as 0.
+type Bytes []byte
+
+func (b Bytes) ReadFromMemory(addr uint16) byte {
+	if int(addr) >= len(b) {
+		return 0
+	}
+	return b[addr]
+}
+
+type OperandKind = opcode.OperandKind
+
+const (
+	Register = opcode.Register
+	Data8    = opcode.Data8
+	Data16   = opcode.Data16
+	Address  = opcode.Address
+	Port     = opcode.Port
+	Restart  = opcode.Restart
+)
+
+type Operand struct {
+	Kind OperandKind
+	// Name of a register, or the number of an RST
+	Name  string
+	Value uint16
+}
+
+func (o Operand) String() string {
+	switch o.Kind {
+	case Register:
+		return o.Name
+	case Data8, Port:
+		return fmt.Sprintf("$%02X", o.Value)
+	case Data16, Address:
+		return fmt.Sprintf("$%04X", o.Value)
+	}
+	return fmt.Sprint(o.Value)
+}
+
+type Flow = opcode.Flow
+
+const (
+	Next   = opcode.Next
+	Jump   = opcode.Jump
+	Call   = opcode.Call
+	Return = opcode.Return
+	Halt   = opcode.Halt
+)
+
+type Access = opcode.Access
+
+// Write is taken by the function writing instructions
+const (
+	NoAccess    = opcode.NoAccess
+	ReadAccess  = opcode.Read
+	WriteAccess = opcode.Write
+)
+
+// Instruction is a decoded instruction.
+type Instruction struct {
+	Addr     uint16
+	Bytes    []byte
+	Mnemonic string
+	Operands []Operand
+	Size     uint16
+	// Cycles spent, or spent when the condition of a conditional instruction
+	// does not hold. CyclesTaken is spent when it holds.
+	Cycles      uint
+	CyclesTaken uint
+
+	Flow        Flow
+	Conditional bool
+	// Destination of jumps, calls and RSTs. PCHL jumps and returns have none.
+	Target    uint16
+	HasTarget bool
+	// Access to the memory at the address operand, of AccessSize bytes
+	Access     Access
+	AccessSize uint16
+
+	Undocumented bool
+}
+
+// Disassemble decodes the instruction at addr as an 8080 would.
+func Disassemble(mem Memory, addr uint16) Instruction {
+	return DisassembleWith(&opcode.Intel8080, mem, addr)
+}
+
+// DisassembleWith decodes the instruction at addr with another instruction
+// set, such as opcode.Intel8085.
+func DisassembleWith(set *[256]opcode.Opcode, mem Memory, addr uint16) Instruction {
+	o := set[mem.ReadFromMemory(addr)]
+
+	i := Instruction{
+		Addr:         addr,
+		Mnemonic:     o.Mnemonic,
+		Size:         o.Size,
+		Cycles:       o.Cycles,
+		CyclesTaken:  o.CyclesTaken,
+		Flow:         o.Flow,
+		Conditional:  o.Conditional,
+		Target:       o.Vector,
+		HasTarget:    o.HasVector,
+		Access:       o.Access,
+		AccessSize:   o.AccessSize,
+		Undocumented: o.Undocumented,
+	}
+
+	i.Bytes = make([]byte, i.Size)
+	for n := range i.Bytes {
+		i.Bytes[n] = mem.ReadFromMemory(addr + uint16(n))
+	}
+
+	for n, operand := range o.Operands {
+		i.Operands = append(i.Operands, Operand{Kind: operand.Kind, Name: operand.Name})
+		switch operand.Kind {
+		case Data8, Port:
+			i.Operands[n].Value = uint16(i.Bytes[1])
+		case Data16, Address:
+			i.Operands[n].Value = uint16(i.Bytes[2])<<8 | uint16(i.Bytes[1])
+		case Restart:
+			i.Operands[n].Value = uint16(operand.Name[0] - '0')
+		}
+
+		if operand.Kind == Address && (i.Flow == Jump || i.Flow == Call) {
+			i.Target, i.HasTarget = i.Operands[n].Value, true
+		}
+	}
+
+	return i
+}
+
+// Sweep decodes instructions one after the other from start, until one
+// reaches past end.
+func Sweep(mem Memory, start uint16, end uint16) []Instruction {
+	var instructions []Instruction
+	for addr := int(start); addr <= int(end); {
+		i := Disassemble(mem, uint16(addr))
+		instructions = append(instructions, i)
+		addr += int(i.Size)
+	}
+	return instructions
+}
+
+// operands returns the operands as written in assembly. Addresses and 16-bit
+// data label names are written as names.
+func (i Instruction) operands(label func(addr uint16) (string, bool)) string {
+	operands := make([]string, len(i.Operands))
+	for n, o := range i.Operands {
+		operands[n] = o.String()
+		if label != nil && (o.Kind == Address || o.Kind == Data16) {
+			if name, ok := label(o.Value); ok {
+				operands[n] = name
+			}
+		}
+	}
+	return strings.Join(operands, ",")
+}
+
+func (i Instruction) mnemonic() string {
+	if i.Undocumented {
+		return "*" + i.Mnemonic
+	}
+	return i.Mnemonic
+}
+
+// String returns the instruction as written in assembly, e.g. MVI A,$42.
+// Undocumented opcodes are marked with *.
+func (i Instruction) String() string {
+	return i.StringWith(nil)
+}
+
+// StringWith returns the instruction as String does, with the addresses label
+// names, such as CALL DrawSprite.
+func (i Instruction) StringWith(label func(addr uint16) (string, bool)) string {
+	if len(i.Operands) == 0 {
+		return i.mnemonic()
+	}
+	return i.mnemonic() + " " + i.operands(label)
+}
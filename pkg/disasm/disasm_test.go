@@ -0,0 +1,123 @@
+package disasm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/opcode"
+)
+
+func TestDisassemble(t *testing.T) {
+	tests := []struct {
+		program  Bytes
+		text     string
+		size     uint16
+		cycles   uint
+		operands []Operand
+	}{
+		{Bytes{0x00}, "NOP", 1, 4, nil},
+		{Bytes{0x31, 0x00, 0x24}, "LXI SP,$2400", 3, 10, []Operand{{Kind: Register, Name: "SP"}, {Kind: Data16, Value: 0x2400}}},
+		{Bytes{0x3E, 0x42}, "MVI A,$42", 2, 7, []Operand{{Kind: Register, Name: "A"}, {Kind: Data8, Value: 0x42}}},
+		{Bytes{0x7E}, "MOV A,M", 1, 7, []Operand{{Kind: Register, Name: "A"}, {Kind: Register, Name: "M"}}},
+		{Bytes{0x32, 0x01, 0x20}, "STA $2001", 3, 13, []Operand{{Kind: Address, Value: 0x2001}}},
+		{Bytes{0xDB, 0x01}, "IN $01", 2, 10, []Operand{{Kind: Port, Value: 0x01}}},
+		{Bytes{0xF5}, "PUSH PSW", 1, 11, []Operand{{Kind: Register, Name: "PSW"}}},
+		{Bytes{0xCF}, "RST 1", 1, 11, []Operand{{Kind: Restart, Name: "1", Value: 1}}},
+	}
+
+	for _, test := range tests {
+		i := Disassemble(test.program, 0)
+
+		if i.String() != test.text || i.Size != test.size || i.Cycles != test.cycles {
+			t.Errorf("Disassemble did not decode %s, got %s/%d/%d", test.text, i, i.Size, i.Cycles)
+		}
+
+		if !reflect.DeepEqual(i.Operands, test.operands) || !reflect.DeepEqual(i.Bytes, []byte(test.program)) {
+			t.Errorf("Disassemble did not decode the operands of %s, got %+v % X", test.text, i.Operands, i.Bytes)
+		}
+	}
+}
+
+func TestDisassembleFlow(t *testing.T) {
+	tests := []struct {
+		program     Bytes
+		flow        Flow
+		conditional bool
+		target      uint16
+		hasTarget   bool
+		cyclesTaken uint
+	}{
+		{Bytes{0x3C}, Next, false, 0, false, 5},
+		{Bytes{0xC3, 0x34, 0x12}, Jump, false, 0x1234, true, 10},
+		{Bytes{0xCA, 0x34, 0x12}, Jump, true, 0x1234, true, 10},
+		{Bytes{0xE9}, Jump, false, 0, false, 5},
+		{Bytes{0xCD, 0x34, 0x12}, Call, false, 0x1234, true, 17},
+		{Bytes{0xC4, 0x34, 0x12}, Call, true, 0x1234, true, 17},
+		{Bytes{0xD7}, Call, false, 0x0010, true, 11},
+		{Bytes{0xC9}, Return, false, 0, false, 10},
+		{Bytes{0xF8}, Return, true, 0, false, 11},
+		{Bytes{0x76}, Halt, false, 0, false, 7},
+		// CMA and CPI are not conditional calls
+		{Bytes{0x2F}, Next, false, 0, false, 4},
+		{Bytes{0xFE, 0x01}, Next, false, 0, false, 7},
+	}
+
+	for _, test := range tests {
+		i := Disassemble(test.program, 0)
+
+		if i.Flow != test.flow || i.Conditional != test.conditional || i.Target != test.target || i.HasTarget != test.hasTarget || i.CyclesTaken != test.cyclesTaken {
+			t.Errorf("Disassemble did not decode the flow of %s, got %+v", i, i)
+		}
+	}
+}
+
+func TestDisassembleUndocumented(t *testing.T) {
+	for _, op := range []byte{0x08, 0x10, 0x18, 0x20, 0x28, 0x30, 0x38, 0xCB, 0xD9, 0xDD, 0xED, 0xFD} {
+		if i := Disassemble(Bytes{op}, 0); !i.Undocumented || i.String()[0] != '*' {
+			t.Errorf("Disassemble did not mark the undocumented opcode %02X, got %s", op, i)
+		}
+	}
+
+	if i := Disassemble(Bytes{0xDD, 0x34, 0x12}, 0); i.String() != "*CALL $1234" || i.Flow != Call || i.Target != 0x1234 {
+		t.Errorf("Disassemble did not decode *CALL as CALL, got %+v", i)
+	}
+}
+
+func TestDisassembleWith8085(t *testing.T) {
+	tests := map[string]Bytes{
+		"RIM":        {0x20},
+		"*LDHI $10":  {0x28, 0x10},
+		"*JNK $1234": {0xDD, 0x34, 0x12},
+		"*RSTV":      {0xCB},
+	}
+
+	for text, program := range tests {
+		if i := DisassembleWith(&opcode.Intel8085, program, 0); i.String() != text {
+			t.Errorf("DisassembleWith did not decode %s, got %s", text, i)
+		}
+	}
+
+	if i := DisassembleWith(&opcode.Intel8085, Bytes{0xCB}, 0); i.Flow != Call || !i.Conditional || i.Target != 0x0040 {
+		t.Errorf("DisassembleWith did not decode the RSTV call, got %+v", i)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	program := Bytes{0x31, 0x00, 0x24, 0x3E, 0x42, 0x00, 0xC3, 0x00, 0x00}
+
+	instructions := Sweep(program, 0, 5)
+
+	if len(instructions) != 3 || instructions[1].Addr != 0x0003 || instructions[2].Addr != 0x0005 {
+		t.Errorf("Sweep did not decode the instructions one after the other, got %+v", instructions)
+	}
+
+	if instructions := Sweep(program, 0, 6); len(instructions) != 4 || instructions[3].String() != "JMP $0000" {
+		t.Errorf("Sweep did not decode the instruction starting at end")
+	}
+}
+
+func TestBytesPastEnd(t *testing.T) {
+	if i := Disassemble(Bytes{0xC3, 0x34}, 0); i.String() != "JMP $0034" {
+		t.Errorf("Disassemble did not read bytes past the end of the slice as 0, got %s", i)
+	}
+}
@@ -0,0 +1,103 @@
+package disasm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Format is how Write lays out instructions.
+type Format int
+
+const (
+	// Plain writes a line per instruction: address, bytes and instruction
+	Plain Format = iota
+	// Color is Plain with ANSI colours, for terminals
+	Color
+	// JSON writes an array of objects
+	JSON
+)
+
+var formatNames = map[string]Format{
+	"plain": Plain,
+	"color": Color,
+	"json":  JSON,
+}
+
+func ParseFormat(s string) (Format, error) {
+	f, ok := formatNames[s]
+	if !ok {
+		return 0, fmt.Errorf("invalid format %q, expected plain, color or json", s)
+	}
+	return f, nil
+}
+
+const (
+	colorReset    = "\033[0m"
+	colorMnemonic = "\033[32m"
+	colorOperands = "\033[36m"
+)
+
+type jsonInstruction struct {
+	Addr        uint16   `json:"addr"`
+	Bytes       string   `json:"bytes"`
+	Mnemonic    string   `json:"mnemonic"`
+	Operands    []string `json:"operands"`
+	Size        uint16   `json:"size"`
+	Cycles      uint     `json:"cycles"`
+	CyclesTaken uint     `json:"cyclesTaken"`
+	Target      *uint16  `json:"target,omitempty"`
+}
+
+// HexBytes returns the bytes of the instruction as hex, e.g. 3E 42.
+func (i Instruction) HexBytes() string {
+	return fmt.Sprintf("% X", i.Bytes)
+}
+
+func formatLine(i Instruction, color bool) string {
+	text := i.String()
+	if color {
+		text = colorMnemonic + i.mnemonic() + colorReset
+		if len(i.Operands) > 0 {
+			text += " " + colorOperands + i.operands(nil) + colorReset
+		}
+	}
+
+	return fmt.Sprintf("%04X  %-8s  %s", i.Addr, i.HexBytes(), text)
+}
+
+// Write writes instructions to w in the given format.
+func Write(w io.Writer, instructions []Instruction, format Format) error {
+	if format == JSON {
+		out := make([]jsonInstruction, len(instructions))
+		for n, i := range instructions {
+			out[n] = jsonInstruction{
+				Addr:        i.Addr,
+				Bytes:       i.HexBytes(),
+				Mnemonic:    i.Mnemonic,
+				Operands:    make([]string, len(i.Operands)),
+				Size:        i.Size,
+				Cycles:      i.Cycles,
+				CyclesTaken: i.CyclesTaken,
+			}
+			for k, o := range i.Operands {
+				out[n].Operands[k] = o.String()
+			}
+			if i.HasTarget {
+				target := i.Target
+				out[n].Target = &target
+			}
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(out)
+	}
+
+	for _, i := range instructions {
+		if _, err := fmt.Fprintln(w, formatLine(i, format == Color)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package disasm
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var formatProgram = Bytes{0x3E, 0x42, 0x00, 0xC3, 0x00, 0x00}
+
+func TestWritePlain(t *testing.T) {
+	var out strings.Builder
+	if err := Write(&out, Sweep(formatProgram, 0, 5), Plain); err != nil {
+		t.Fatalf("Write returned an error: %s", err)
+	}
+
+	expected := "0000  3E 42     MVI A,$42\n" +
+		"0002  00        NOP\n" +
+		"0003  C3 00 00  JMP $0000\n"
+	if out.String() != expected {
+		t.Errorf("Write did not write a plain listing, got\n%s", out.String())
+	}
+}
+
+func TestWriteColor(t *testing.T) {
+	var out strings.Builder
+	Write(&out, Sweep(formatProgram, 0, 2), Color)
+
+	expected := "0000  3E 42     \033[32mMVI\033[0m \033[36mA,$42\033[0m\n" +
+		"0002  00        \033[32mNOP\033[0m\n"
+	if out.String() != expected {
+		t.Errorf("Write did not colour the listing, got %q", out.String())
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var out strings.Builder
+	Write(&out, Sweep(formatProgram, 0, 5), JSON)
+
+	var instructions []jsonInstruction
+	if err := json.Unmarshal([]byte(out.String()), &instructions); err != nil {
+		t.Fatalf("Write did not write valid JSON: %s", err)
+	}
+
+	if len(instructions) != 3 || instructions[0].Bytes != "3E 42" || instructions[0].Operands[1] != "$42" || instructions[0].Target != nil {
+		t.Errorf("Write did not write the instructions as JSON, got %+v", instructions)
+	}
+
+	if target := instructions[2].Target; target == nil || *target != 0x0000 || instructions[2].Cycles != 10 {
+		t.Errorf("Write did not write the branch target, got %+v", instructions[2])
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("json"); err != nil || f != JSON {
+		t.Errorf("ParseFormat did not parse json")
+	}
+
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Errorf("ParseFormat did not reject an unknown format")
+	}
+}
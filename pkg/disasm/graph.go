@@ -0,0 +1,197 @@
+package disasm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// EdgeKind is how control gets from a block to another.
+type EdgeKind int
+
+const (
+	// EdgeNext falls through to the following block
+	EdgeNext EdgeKind = iota
+	// EdgeJump is an unconditional jump, EdgeBranch a conditional one
+	EdgeJump
+	EdgeBranch
+	// EdgeCall is a CALL or RST, which the block goes on from once it returns
+	EdgeCall
+)
+
+var edgeKindNames = []string{"next", "jump", "branch", "call"}
+
+func (k EdgeKind) String() string {
+	return edgeKindNames[k]
+}
+
+// Edge goes from the block at From to the block at To.
+type Edge struct {
+	From uint16
+	To   uint16
+	Kind EdgeKind
+}
+
+// Block is a basic block: instructions run one after the other, entered at
+// the first one and left after the last one. CALLs do not end blocks.
+type Block struct {
+	Start        uint16
+	Instructions []Instruction
+}
+
+// Graph is the control-flow graph of a listing.
+type Graph struct {
+	// Blocks in address order
+	Blocks []Block
+	Edges  []Edge
+}
+
+// ends reports whether i is the last instruction of its block.
+func ends(i Instruction) bool {
+	return i.Flow == Jump || i.Flow == Return || i.Flow == Halt
+}
+
+// Graph splits the instructions of the listing into basic blocks, linked by
+// jumps, branches, calls and fall-throughs. Computed jumps (PCHL) and
+// returns have no edge.
+func (l *Listing) Graph() *Graph {
+	leaders := make(map[uint16]bool)
+	for _, addr := range l.entries {
+		leaders[addr] = l.IsCode(addr)
+	}
+	for _, i := range l.Instructions {
+		if i.HasTarget && l.IsCode(i.Target) {
+			leaders[i.Target] = true
+		}
+		if next := i.Addr + i.Size; ends(i) && l.IsCode(next) {
+			leaders[next] = true
+		}
+	}
+
+	g := &Graph{}
+	for n, i := range l.Instructions {
+		// Instructions the previous one does not run into start a block too
+		if n == 0 || leaders[i.Addr] || l.Instructions[n-1].Addr+l.Instructions[n-1].Size != i.Addr {
+			g.Blocks = append(g.Blocks, Block{Start: i.Addr})
+		}
+		b := &g.Blocks[len(g.Blocks)-1]
+		b.Instructions = append(b.Instructions, i)
+	}
+
+	for n, b := range g.Blocks {
+		for _, i := range b.Instructions {
+			if i.Flow == Call && i.HasTarget && l.IsCode(i.Target) {
+				g.Edges = append(g.Edges, Edge{From: b.Start, To: i.Target, Kind: EdgeCall})
+			}
+		}
+
+		last := b.Instructions[len(b.Instructions)-1]
+		if last.Flow == Jump && last.HasTarget && l.IsCode(last.Target) {
+			kind := EdgeJump
+			if last.Conditional {
+				kind = EdgeBranch
+			}
+			g.Edges = append(g.Edges, Edge{From: b.Start, To: last.Target, Kind: kind})
+		}
+
+		if n+1 < len(g.Blocks) && g.Blocks[n+1].Start == last.Addr+last.Size && continues(last) {
+			g.Edges = append(g.Edges, Edge{From: b.Start, To: g.Blocks[n+1].Start, Kind: EdgeNext})
+		}
+	}
+
+	return g
+}
+
+// dotEdgeStyles are the Graphviz attributes of each kind of edge
+var dotEdgeStyles = []string{
+	EdgeNext:   "",
+	EdgeJump:   "",
+	EdgeBranch: ` [color="darkgreen"]`,
+	EdgeCall:   ` [style="dashed", color="blue"]`,
+}
+
+// WriteDOT writes the graph of the listing in the Graphviz DOT language, a
+// node per block showing its instructions. Blocks are named after their
+// label.
+func (l *Listing) WriteDOT(w io.Writer) error {
+	g := l.Graph()
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "digraph code {")
+	fmt.Fprintln(bw, `	node [shape="box", fontname="monospace"];`)
+
+	for _, b := range g.Blocks {
+		var text strings.Builder
+		if label, ok := l.label(b.Start); ok {
+			text.WriteString(label + ":\\l")
+		}
+		for _, i := range b.Instructions {
+			fmt.Fprintf(&text, "%04X  %s\\l", i.Addr, i.StringWith(l.label))
+		}
+
+		// Instructions and labels need no escaping, \l ends left-aligned lines
+		fmt.Fprintf(bw, "\tb%04X [label=\"%s\"];\n", b.Start, text.String())
+	}
+
+	for _, e := range g.Edges {
+		fmt.Fprintf(bw, "\tb%04X -> b%04X%s;\n", e.From, e.To, dotEdgeStyles[e.Kind])
+	}
+
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// MemoryReference lists the instructions that read and write an address
+// given as an operand, e.g. by LDA and SHLD.
+type MemoryReference struct {
+	Addr   uint16   `json:"addr"`
+	Label  string   `json:"label,omitempty"`
+	Reads  []uint16 `json:"reads"`
+	Writes []uint16 `json:"writes"`
+}
+
+// MemoryReferences returns the addresses out of the listing, such as RAM,
+// read or written by its instructions, in address order. Accesses through
+// register pairs are not known.
+func (l *Listing) MemoryReferences() []MemoryReference {
+	refs := make(map[uint16]*MemoryReference)
+
+	for _, i := range l.Instructions {
+		if i.Access == NoAccess {
+			continue
+		}
+
+		operand := i.Operands[len(i.Operands)-1].Value
+		for n := uint16(0); n < i.AccessSize; n++ {
+			addr := operand + n
+			if addr >= l.Start && addr <= l.End {
+				continue
+			}
+
+			ref, ok := refs[addr]
+			if !ok {
+				ref = &MemoryReference{Addr: addr, Reads: []uint16{}, Writes: []uint16{}}
+				ref.Label, _ = l.label(addr)
+				refs[addr] = ref
+			}
+
+			if i.Access == ReadAccess {
+				ref.Reads = append(ref.Reads, i.Addr)
+			} else {
+				ref.Writes = append(ref.Writes, i.Addr)
+			}
+		}
+	}
+
+	references := make([]MemoryReference, 0, len(refs))
+	for _, ref := range refs {
+		references = append(references, *ref)
+	}
+	sort.Slice(references, func(a, b int) bool {
+		return references[a].Addr < references[b].Addr
+	})
+
+	return references
+}
@@ -0,0 +1,82 @@
+package disasm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGraph(t *testing.T) {
+	g := Trace(traceProgram, 0x0000, 0x001F, []uint16{0x0000, 0x0008}).Graph()
+
+	var blocks [][]uint16
+	for _, b := range g.Blocks {
+		var addrs []uint16
+		for _, i := range b.Instructions {
+			addrs = append(addrs, i.Addr)
+		}
+		blocks = append(blocks, addrs)
+	}
+
+	expectedBlocks := [][]uint16{{0x0000}, {0x0008, 0x0009}, {0x0010}, {0x0013}, {0x0016, 0x0017}, {0x001B, 0x001D}}
+	if !reflect.DeepEqual(blocks, expectedBlocks) {
+		t.Errorf("Graph did not split the code into basic blocks, got % X", blocks)
+	}
+
+	expectedEdges := []Edge{
+		{From: 0x0000, To: 0x0010, Kind: EdgeJump},
+		{From: 0x0010, To: 0x001B, Kind: EdgeCall},
+		{From: 0x0010, To: 0x0013, Kind: EdgeNext},
+		{From: 0x0013, To: 0x0010, Kind: EdgeBranch},
+		{From: 0x0013, To: 0x0016, Kind: EdgeNext},
+		{From: 0x0016, To: 0x0013, Kind: EdgeJump},
+	}
+	if !reflect.DeepEqual(g.Edges, expectedEdges) {
+		t.Errorf("Graph did not link the blocks, got %v", g.Edges)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	l := Trace(traceProgram, 0x0000, 0x001F, []uint16{0x0000, 0x0008})
+	l.Labels[0x001B] = "Load"
+
+	var out strings.Builder
+	if err := l.WriteDOT(&out); err != nil {
+		t.Fatalf("WriteDOT returned an error: %s", err)
+	}
+
+	for _, line := range []string{
+		"digraph code {\n",
+		"\tb0010 [label=\"L0010:\\l0010  CALL Load\\l\"];\n",
+		"\tb001B [label=\"Load:\\l001B  MVI A,$01\\l001D  RET\\l\"];\n",
+		"\tb0010 -> b001B [style=\"dashed\", color=\"blue\"];\n",
+		"\tb0013 -> b0010 [color=\"darkgreen\"];\n",
+		"\tb0013 -> b0016;\n",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("WriteDOT did not write %q, got\n%s", line, out.String())
+		}
+	}
+}
+
+func TestMemoryReferences(t *testing.T) {
+	program := Bytes{
+		0x3A, 0x00, 0x20, // 0000 LDA $2000
+		0x22, 0x01, 0x20, // 0003 SHLD $2001
+		0x32, 0x00, 0x20, // 0006 STA $2000
+		0x3A, 0x0C, 0x00, // 0009 LDA $000C
+		0x76, // 000C HLT
+	}
+
+	l := Trace(program, 0x0000, 0x000C, []uint16{0x0000})
+	l.Labels[0x2000] = "counter"
+
+	expected := []MemoryReference{
+		{Addr: 0x2000, Label: "counter", Reads: []uint16{0x0000}, Writes: []uint16{0x0006}},
+		{Addr: 0x2001, Reads: []uint16{}, Writes: []uint16{0x0003}},
+		{Addr: 0x2002, Reads: []uint16{}, Writes: []uint16{0x0003}},
+	}
+	if refs := l.MemoryReferences(); !reflect.DeepEqual(refs, expected) {
+		t.Errorf("MemoryReferences did not return the reads and writes, got %+v", refs)
+	}
+}
@@ -0,0 +1,248 @@
+package disasm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EntryPoints are where an 8080 starts executing: reset, and the RST 1 and
+// RST 2 interrupts raised by the Space Invaders hardware.
+var EntryPoints = []uint16{0x0000, 0x0008, 0x0010}
+
+// Bytes written on each DB line of a listing
+const dataLineLength = 8
+
+// Listing is memory from Start to End disassembled by following control
+// flow. Bytes no instruction reached are data.
+type Listing struct {
+	Start uint16
+	End   uint16
+	// Instructions reached, in address order
+	Instructions []Instruction
+	// Labels of the entry points and of jump and call targets. Labels may be
+	// added for any address, those without a line of their own are written
+	// as EQU.
+	Labels map[uint16]string
+	// Comments written before the line at their address
+	Comments map[uint16]string
+	// Names names operand addresses without a label, e.g. name+1 for the
+	// second byte of a table. The names must be made of labels.
+	Names func(addr uint16) (string, bool)
+
+	mem     Memory
+	code    map[uint16]Instruction
+	entries []uint16
+}
+
+// continues reports whether execution can go on to the instruction after i.
+// CALLs and RSTs are expected to return.
+func continues(i Instruction) bool {
+	switch i.Flow {
+	case Next, Call:
+		return true
+	case Jump, Return:
+		return i.Conditional
+	}
+	return false
+}
+
+// Trace disassembles memory from start to end, following jumps and calls
+// from each entry point. Computed jumps (PCHL) cannot be followed, their
+// destinations have to be given as entry points. The listing is empty when
+// end is before start.
+func Trace(mem Memory, start uint16, end uint16, entries []uint16) *Listing {
+	l := &Listing{
+		Start:    start,
+		End:      end,
+		Labels:   make(map[uint16]string),
+		Comments: make(map[uint16]string),
+		mem:      mem,
+		code:     make(map[uint16]Instruction),
+		entries:  entries,
+	}
+
+	covered := make([]bool, max(int(end)-int(start)+1, 0))
+	inRange := func(addr int) bool {
+		return addr >= int(start) && addr <= int(end)
+	}
+
+	var targets []uint16
+	pending := append([]uint16{}, entries...)
+	for len(pending) > 0 {
+		addr := int(pending[len(pending)-1])
+		pending = pending[:len(pending)-1]
+
+		for inRange(addr) && !covered[addr-int(start)] {
+			i := Disassemble(mem, uint16(addr))
+
+			// Stop at instructions running past the end or into code already
+			// decoded, they are not the ones the CPU would run
+			last := addr + int(i.Size) - 1
+			if !inRange(last) || covered[last-int(start)] {
+				break
+			}
+
+			for a := addr; a <= last; a++ {
+				covered[a-int(start)] = true
+			}
+			l.code[i.Addr] = i
+
+			if i.HasTarget {
+				targets = append(targets, i.Target)
+				pending = append(pending, i.Target)
+			}
+
+			if !continues(i) {
+				break
+			}
+			addr += int(i.Size)
+		}
+	}
+
+	for _, i := range l.code {
+		l.Instructions = append(l.Instructions, i)
+	}
+	sort.Slice(l.Instructions, func(a, b int) bool {
+		return l.Instructions[a].Addr < l.Instructions[b].Addr
+	})
+
+	// Targets in the middle of an instruction keep their address, as the
+	// listing has no line to put the label on
+	for _, addr := range append(targets, entries...) {
+		if _, ok := l.code[addr]; ok {
+			l.Labels[addr] = fmt.Sprintf("L%04X", addr)
+		}
+	}
+
+	return l
+}
+
+// IsCode reports whether an instruction reached starts at addr.
+func (l *Listing) IsCode(addr uint16) bool {
+	_, ok := l.code[addr]
+	return ok
+}
+
+func (l *Listing) label(addr uint16) (string, bool) {
+	if label, ok := l.Labels[addr]; ok {
+		return label, true
+	}
+	if l.Names != nil {
+		return l.Names(addr)
+	}
+	return "", false
+}
+
+func dataLine(data []byte) string {
+	values := make([]string, len(data))
+	for n, b := range data {
+		values[n] = fmt.Sprintf("$%02X", b)
+	}
+	return "DB      " + strings.Join(values, ",")
+}
+
+// instructionLine returns i as assembly. Undocumented opcodes are written as
+// DB, as assemblers do not know them.
+func (l *Listing) instructionLine(i Instruction) string {
+	text := strings.TrimSpace(fmt.Sprintf("%-8s%s", i.Mnemonic, i.operands(l.label)))
+	if i.Undocumented {
+		return fmt.Sprintf("%-32s; *%s", dataLine(i.Bytes), text)
+	}
+	return fmt.Sprintf("%-32s; %04X  %s", text, i.Addr, i.HexBytes())
+}
+
+// annotated reports whether addr has a label or comment, which start a new
+// line.
+func (l *Listing) annotated(addr uint16) bool {
+	_, labelled := l.Labels[addr]
+	_, commented := l.Comments[addr]
+	return labelled || commented
+}
+
+// Write writes the listing as assembly, which assembles back to the same
+// bytes.
+func (l *Listing) Write(w io.Writer) error {
+	var lines []string
+	placed := make(map[uint16]bool)
+
+	for addr := int(l.Start); addr <= int(l.End); {
+		if label, ok := l.Labels[uint16(addr)]; ok {
+			lines = append(lines, "", label+":")
+			placed[uint16(addr)] = true
+		}
+		if comment, ok := l.Comments[uint16(addr)]; ok {
+			lines = append(lines, "        ; "+comment)
+		}
+
+		if i, ok := l.code[uint16(addr)]; ok {
+			lines = append(lines, "        "+l.instructionLine(i))
+			addr += int(i.Size)
+			continue
+		}
+
+		data := []byte{l.mem.ReadFromMemory(uint16(addr))}
+		for addr++; addr <= int(l.End) && len(data) < dataLineLength && !l.IsCode(uint16(addr)) && !l.annotated(uint16(addr)); addr++ {
+			data = append(data, l.mem.ReadFromMemory(uint16(addr)))
+		}
+		lines = append(lines, fmt.Sprintf("        %-32s; %04X", dataLine(data), addr-len(data)))
+	}
+
+	bw := bufio.NewWriter(w)
+
+	// Labels out of the listing, or in the middle of an instruction
+	var equates []uint16
+	for addr := range l.Labels {
+		if !placed[addr] {
+			equates = append(equates, addr)
+		}
+	}
+	sort.Slice(equates, func(a, b int) bool { return equates[a] < equates[b] })
+	for _, addr := range equates {
+		fmt.Fprintf(bw, "%-15s EQU     $%04X\n", l.Labels[addr], addr)
+	}
+	if len(equates) > 0 {
+		fmt.Fprintln(bw)
+	}
+
+	fmt.Fprintf(bw, "        ORG     $%04X\n", l.Start)
+	for _, line := range lines {
+		fmt.Fprintln(bw, line)
+	}
+	fmt.Fprintln(bw, "        END")
+
+	return bw.Flush()
+}
+
+// ReadEntryPoints reads addresses, one per line, to trace from as well as
+// EntryPoints. Addresses are hex with a $ or 0x prefix, or decimal. Text
+// after ; or # is a comment.
+func ReadEntryPoints(r io.Reader) ([]uint16, error) {
+	var entries []uint16
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if n := strings.IndexAny(text, ";#"); n >= 0 {
+			text = text[:n]
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+
+		if strings.HasPrefix(text, "$") {
+			text = "0x" + text[1:]
+		}
+		addr, err := strconv.ParseUint(text, 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid address %q", line, scanner.Text())
+		}
+		entries = append(entries, uint16(addr))
+	}
+
+	return entries, scanner.Err()
+}
@@ -0,0 +1,261 @@
+package disasm
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/opcode"
+)
+
+// Reset jumps over a table to a loop calling a subroutine, the RST 1 handler
+// returns at once
+var traceProgram = Bytes{
+	0xC3, 0x10, 0x00, // 0000 JMP $0010
+	0x41, 0x42, 0x43, 0x44, 0x45, // 0003 "ABCDE"
+	0xFB, 0xC9, // 0008 EI, RET
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // 000A
+	0xCD, 0x1B, 0x00, // 0010 CALL $001B
+	0xCA, 0x10, 0x00, // 0013 JZ $0010
+	0x08,             // 0016 *NOP
+	0xC3, 0x13, 0x00, // 0017 JMP $0013
+	0xFF,       // 001A
+	0x3E, 0x01, // 001B MVI A,$01
+	0xC9,       // 001D RET
+	0xE9, 0xE9, // 001E
+}
+
+func TestTrace(t *testing.T) {
+	l := Trace(traceProgram, 0x0000, 0x001F, []uint16{0x0000, 0x0008})
+
+	var code []uint16
+	for _, i := range l.Instructions {
+		code = append(code, i.Addr)
+	}
+
+	expected := []uint16{0x0000, 0x0008, 0x0009, 0x0010, 0x0013, 0x0016, 0x0017, 0x001B, 0x001D}
+	if !reflect.DeepEqual(code, expected) {
+		t.Errorf("Trace did not follow control flow, got % X", code)
+	}
+
+	if l.IsCode(0x0003) || l.IsCode(0x001A) || l.IsCode(0x001E) {
+		t.Errorf("Trace decoded bytes no instruction reaches")
+	}
+
+	labels := map[uint16]string{0x0000: "L0000", 0x0008: "L0008", 0x0010: "L0010", 0x0013: "L0013", 0x001B: "L001B"}
+	if !reflect.DeepEqual(l.Labels, labels) {
+		t.Errorf("Trace did not label the jump and call targets, got %v", l.Labels)
+	}
+}
+
+func TestTraceStopsAtOverlappingCode(t *testing.T) {
+	// JMP $0001 lands on the operand of the JMP itself
+	l := Trace(Bytes{0xC3, 0x01, 0x00}, 0x0000, 0x0002, []uint16{0x0000})
+
+	if len(l.Instructions) != 1 || len(l.Labels) != 1 {
+		t.Errorf("Trace decoded an instruction overlapping another, got %v %v", l.Instructions, l.Labels)
+	}
+}
+
+func TestTraceInvertedRange(t *testing.T) {
+	l := Trace(traceProgram, 0x0010, 0x0000, EntryPoints)
+
+	if len(l.Instructions) != 0 || len(l.Labels) != 0 {
+		t.Errorf("Trace decoded instructions out of an empty range, got %v %v", l.Instructions, l.Labels)
+	}
+
+	var out strings.Builder
+	if err := l.Write(&out); err != nil || out.String() != "        ORG     $0010\n        END\n" {
+		t.Errorf("Write did not write an empty listing, got %q %v", out.String(), err)
+	}
+}
+
+func TestListingWrite(t *testing.T) {
+	var out strings.Builder
+	if err := Trace(traceProgram, 0x0000, 0x001F, []uint16{0x0000, 0x0008}).Write(&out); err != nil {
+		t.Fatalf("Write returned an error: %s", err)
+	}
+
+	expected := "        ORG     $0000\n" +
+		"\n" +
+		"L0000:\n" +
+		"        JMP     L0010                   ; 0000  C3 10 00\n" +
+		"        DB      $41,$42,$43,$44,$45     ; 0003\n" +
+		"\n" +
+		"L0008:\n" +
+		"        EI                              ; 0008  FB\n" +
+		"        RET                             ; 0009  C9\n" +
+		"        DB      $00,$00,$00,$00,$00,$00 ; 000A\n" +
+		"\n" +
+		"L0010:\n" +
+		"        CALL    L001B                   ; 0010  CD 1B 00\n" +
+		"\n" +
+		"L0013:\n" +
+		"        JZ      L0010                   ; 0013  CA 10 00\n" +
+		"        DB      $08                     ; *NOP\n" +
+		"        JMP     L0013                   ; 0017  C3 13 00\n" +
+		"        DB      $FF                     ; 001A\n" +
+		"\n" +
+		"L001B:\n" +
+		"        MVI     A,$01                   ; 001B  3E 01\n" +
+		"        RET                             ; 001D  C9\n" +
+		"        DB      $E9,$E9                 ; 001E\n" +
+		"        END\n"
+	if out.String() != expected {
+		t.Errorf("Write did not write the listing, got\n%s", out.String())
+	}
+
+	if program := assemble(t, out.String()); !bytes.Equal(program, traceProgram) {
+		t.Errorf("Write did not write a listing that reassembles, got % X", program)
+	}
+}
+
+func TestListingWriteLabelsAndComments(t *testing.T) {
+	program := Bytes{
+		0x32, 0x01, 0x20, // 0000 STA $2001
+		0x21, 0x06, 0x00, // 0003 LXI H,$0006
+		0x76,       // 0006 HLT
+		0x01, 0x02, // 0007
+	}
+
+	l := Trace(program, 0x0000, 0x0008, []uint16{0x0000})
+	l.Labels[0x0000] = "Start"
+	l.Labels[0x2000] = "counters"
+	l.Labels[0x0008] = "table"
+	l.Comments[0x0006] = "Wait for an interrupt"
+	l.Names = func(addr uint16) (string, bool) {
+		if addr == 0x2001 {
+			return "counters+1", true
+		}
+		return "", false
+	}
+
+	var out strings.Builder
+	l.Write(&out)
+
+	expected := "counters        EQU     $2000\n" +
+		"\n" +
+		"        ORG     $0000\n" +
+		"\n" +
+		"Start:\n" +
+		"        STA     counters+1              ; 0000  32 01 20\n" +
+		"        LXI     H,$0006                 ; 0003  21 06 00\n" +
+		"        ; Wait for an interrupt\n" +
+		"        HLT                             ; 0006  76\n" +
+		"        DB      $01                     ; 0007\n" +
+		"\n" +
+		"table:\n" +
+		"        DB      $02                     ; 0008\n" +
+		"        END\n"
+	if out.String() != expected {
+		t.Errorf("Write did not write the labels and comments, got\n%s", out.String())
+	}
+
+	if reassembled := assemble(t, out.String()); !bytes.Equal(reassembled, program) {
+		t.Errorf("Write did not write a listing that reassembles, got % X", reassembled)
+	}
+}
+
+func TestReadEntryPoints(t *testing.T) {
+	entries, err := ReadEntryPoints(strings.NewReader("; jump table\n$0100\n0x0200 # routine\n\n512\n"))
+	if err != nil || !reflect.DeepEqual(entries, []uint16{0x0100, 0x0200, 512}) {
+		t.Errorf("ReadEntryPoints did not read the addresses, got %v %v", entries, err)
+	}
+
+	if _, err := ReadEntryPoints(strings.NewReader("$0100\nstart\n")); err == nil {
+		t.Errorf("ReadEntryPoints did not reject an invalid address")
+	}
+}
+
+// assemble assembles the listings written by Listing.Write, from ORG $0000,
+// using the documented opcodes of opcode.Intel8080.
+func assemble(t *testing.T, source string) []byte {
+	opcodes := make(map[string]byte)
+	for op, o := range opcode.Intel8080 {
+		if !o.Undocumented {
+			opcodes[o.Name()] = byte(op)
+		}
+	}
+
+	labels := make(map[string]uint16)
+	value := func(s string) uint16 {
+		s, offset, _ := strings.Cut(s, "+")
+		n, _ := strconv.Atoi(offset)
+		if addr, ok := labels[s]; ok {
+			return addr + uint16(n)
+		}
+		v, err := strconv.ParseUint(strings.TrimPrefix(s, "$"), 16, 16)
+		if err != nil && strings.HasPrefix(s, "$") {
+			t.Fatalf("assemble: invalid value %q", s)
+		}
+		return uint16(v)
+	}
+
+	var program []byte
+	// Labels are defined on the first pass and used on the second
+	for pass := 0; pass < 2; pass++ {
+		program = nil
+		for _, line := range strings.Split(source, "\n") {
+			line, _, _ = strings.Cut(line, ";")
+			fields := strings.Fields(line)
+			if len(fields) == 0 || fields[0] == "ORG" || fields[0] == "END" {
+				continue
+			}
+
+			if len(fields) == 3 && fields[1] == "EQU" {
+				labels[fields[0]] = value(fields[2])
+				continue
+			}
+
+			if label, ok := strings.CutSuffix(fields[0], ":"); ok {
+				labels[label] = uint16(len(program))
+				continue
+			}
+
+			var operands []string
+			if len(fields) > 1 {
+				operands = strings.Split(fields[1], ",")
+			}
+
+			if fields[0] == "DB" {
+				for _, o := range operands {
+					program = append(program, byte(value(o)))
+				}
+				continue
+			}
+
+			// Registers and RST numbers are part of the opcode name
+			name := fields[0]
+			var values []string
+			for n, o := range operands {
+				if !strings.Contains(" A B C D E H L M SP PSW 0 1 2 3 4 5 6 7 ", " "+o+" ") {
+					values = operands[n:]
+					break
+				}
+				if n == 0 {
+					name += " " + o
+				} else {
+					name += "," + o
+				}
+			}
+
+			op, ok := opcodes[name]
+			if !ok {
+				t.Fatalf("assemble: unknown instruction %q", line)
+			}
+			program = append(program, op)
+
+			for _, v := range values {
+				n := value(v)
+				program = append(program, byte(n))
+				if opcode.Intel8080[op].Size == 3 {
+					program = append(program, byte(n>>8))
+				}
+			}
+		}
+	}
+
+	return program
+}
@@ -0,0 +1,126 @@
+package ihex
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Data bytes in each record written
+const recordLength = 16
+
+const (
+	recordData                   = 0x00
+	recordEndOfFile              = 0x01
+	recordExtendedSegmentAddress = 0x02
+	recordStartSegmentAddress    = 0x03
+	recordExtendedLinearAddress  = 0x04
+	recordStartLinearAddress     = 0x05
+)
+
+// Segment is a run of contiguous bytes read from an Intel HEX file.
+type Segment struct {
+	Addr uint16
+	Data []byte
+}
+
+func writeRecord(w *bufio.Writer, kind byte, addr uint16, data []byte) {
+	record := append([]byte{byte(len(data)), byte(addr >> 8), byte(addr), kind}, data...)
+
+	var sum byte
+	for _, b := range record {
+		sum += b
+	}
+	record = append(record, -sum)
+
+	fmt.Fprintf(w, ":%s\n", strings.ToUpper(hex.EncodeToString(record)))
+}
+
+// Write writes data, starting at addr, as Intel HEX data records followed by
+// an end of file record. Data must not go past $FFFF.
+func Write(w io.Writer, addr uint16, data []byte) error {
+	if int(addr)+len(data) > 0x10000 {
+		return errors.New("data does not fit in 64KB")
+	}
+
+	bw := bufio.NewWriter(w)
+	for i := 0; i < len(data); i += recordLength {
+		writeRecord(bw, recordData, addr+uint16(i), data[i:min(i+recordLength, len(data))])
+	}
+	writeRecord(bw, recordEndOfFile, 0, nil)
+
+	return bw.Flush()
+}
+
+// Read reads Intel HEX records up to the end of file record. Records
+// following each other are merged into a single segment. Only 16-bit
+// addresses are supported, start address records are ignored.
+func Read(r io.Reader) ([]Segment, error) {
+	var segments []Segment
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(text, ":") {
+			return nil, fmt.Errorf("line %d: record does not start with ':'", line)
+		}
+
+		record, err := hex.DecodeString(text[1:])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if len(record) < 5 || len(record) != int(record[0])+5 {
+			return nil, fmt.Errorf("line %d: invalid record length", line)
+		}
+
+		var sum byte
+		for _, b := range record {
+			sum += b
+		}
+		if sum != 0 {
+			return nil, fmt.Errorf("line %d: invalid checksum", line)
+		}
+
+		addr := uint16(record[1])<<8 | uint16(record[2])
+		data := record[4 : len(record)-1]
+
+		switch record[3] {
+		case recordData:
+			if int(addr)+len(data) > 0x10000 {
+				return nil, fmt.Errorf("line %d: record goes past $FFFF", line)
+			}
+
+			if n := len(segments); n > 0 && int(segments[n-1].Addr)+len(segments[n-1].Data) == int(addr) {
+				segments[n-1].Data = append(segments[n-1].Data, data...)
+			} else {
+				segments = append(segments, Segment{Addr: addr, Data: data})
+			}
+		case recordEndOfFile:
+			return segments, nil
+		case recordExtendedSegmentAddress, recordExtendedLinearAddress:
+			if len(data) != 2 {
+				return nil, fmt.Errorf("line %d: invalid extended address record", line)
+			}
+			if data[0] != 0 || data[1] != 0 {
+				return nil, fmt.Errorf("line %d: addresses above $FFFF are not supported", line)
+			}
+		case recordStartSegmentAddress, recordStartLinearAddress:
+		default:
+			return nil, fmt.Errorf("line %d: unknown record type %02X", line, record[3])
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, errors.New("missing end of file record")
+}
@@ -0,0 +1,83 @@
+package ihex
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	data := []byte{0x21, 0x46, 0x01, 0x36, 0x01, 0x21, 0x47, 0x01, 0x36, 0x00, 0x7E, 0xFE, 0x09, 0xD2, 0x19, 0x01, 0x21}
+
+	if err := Write(&buf, 0x0100, data); err != nil {
+		t.Fatalf("Write returned an error: %s", err)
+	}
+
+	expected := ":10010000214601360121470136007EFE09D2190140\n" +
+		":0101100021CD\n" +
+		":00000001FF\n"
+	if buf.String() != expected {
+		t.Errorf("Write did not write the expected records, got\n%s", buf.String())
+	}
+}
+
+func TestWriteTooLarge(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, 0xFFFF, []byte{0x00, 0x00}); err == nil {
+		t.Errorf("Write did not reject data going past $FFFF")
+	}
+}
+
+func TestReadMergesRecords(t *testing.T) {
+	data := make([]byte, 40)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	var buf bytes.Buffer
+	Write(&buf, 0x2000, data)
+
+	segments, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read returned an error: %s", err)
+	}
+
+	if len(segments) != 1 || segments[0].Addr != 0x2000 || !bytes.Equal(segments[0].Data, data) {
+		t.Errorf("Read did not return the data written as a single segment: %+v", segments)
+	}
+}
+
+func TestReadSegments(t *testing.T) {
+	hex := ":020000040000FA\n" +
+		":0200000001FEFF\n" +
+		":01100000AA45\n" +
+		":0400000300000000F9\n" +
+		":00000001FF\n"
+
+	segments, err := Read(strings.NewReader(hex))
+	if err != nil {
+		t.Fatalf("Read returned an error: %s", err)
+	}
+
+	if len(segments) != 2 || segments[0].Addr != 0x0000 || segments[1].Addr != 0x1000 || segments[1].Data[0] != 0xAA {
+		t.Errorf("Read did not return the expected segments: %+v", segments)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := map[string]string{
+		"missing colon":      "00000001FF\n",
+		"invalid checksum":   ":0101100021CC\n:00000001FF\n",
+		"invalid length":     ":0201100021CD\n:00000001FF\n",
+		"missing end":        ":0101100021CD\n",
+		"extended address":   ":020000040001F9\n:00000001FF\n",
+		"unknown record":     ":00000009F7\n:00000001FF\n",
+		"past end of memory": ":02FFFF000102FD\n:00000001FF\n",
+	}
+
+	for name, hex := range tests {
+		if _, err := Read(strings.NewReader(hex)); err == nil {
+			t.Errorf("Read did not reject a file with %s", name)
+		}
+	}
+}
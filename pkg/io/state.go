@@ -0,0 +1,84 @@
+package io
+
+import (
+	"encoding/binary"
+	goio "io"
+)
+
+type ioBusState struct {
+	Input1 byte
+	Input2 byte
+	ShiftH byte
+	ShiftL byte
+	Offset byte
+}
+
+// SaveState writes the input latches and the shift register to w.
+func (io *IOBus) SaveState(w goio.Writer) error {
+	state := &ioBusState{
+		Input1: io.input1,
+		Input2: io.input2,
+		ShiftH: io.shiftH,
+		ShiftL: io.shiftL,
+		Offset: io.offset,
+	}
+
+	return binary.Write(w, binary.LittleEndian, state)
+}
+
+// ReadState decodes a state written by SaveState and returns a function that
+// restores it.
+func (io *IOBus) ReadState(r goio.Reader) (apply func(), err error) {
+	state := &ioBusState{}
+	if err := binary.Read(r, binary.LittleEndian, state); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		io.input1 = state.Input1
+		io.input2 = state.Input2
+		io.shiftH = state.ShiftH
+		io.shiftL = state.ShiftL
+		io.offset = state.Offset
+	}, nil
+}
+
+// LoadState restores a state written by SaveState.
+func (io *IOBus) LoadState(r goio.Reader) error {
+	apply, err := io.ReadState(r)
+	if err != nil {
+		return err
+	}
+
+	apply()
+	return nil
+}
+
+// GetLatches returns the input latches and the shift register by name.
+func (io *IOBus) GetLatches() map[string]byte {
+	return map[string]byte{
+		"input1":      io.input1,
+		"input2":      io.input2,
+		"shiftHigh":   io.shiftH,
+		"shiftLow":    io.shiftL,
+		"shiftOffset": io.offset,
+	}
+}
+
+// SetLatches sets the latches present in values, using the names returned by
+// GetLatches.
+func (io *IOBus) SetLatches(values map[string]byte) {
+	latches := map[string]*byte{
+		"input1":      &io.input1,
+		"input2":      &io.input2,
+		"shiftHigh":   &io.shiftH,
+		"shiftLow":    &io.shiftL,
+		"shiftOffset": &io.offset,
+	}
+
+	for name, value := range values {
+		if l, ok := latches[name]; ok {
+			*l = value
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package io
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSaveAndLoadState(t *testing.T) {
+	bus := NewIOBus(nil)
+	bus.input1 = 0x01
+	bus.input2 = 0x02
+	bus.shiftH = 0xff
+	bus.shiftL = 0xaa
+	bus.offset = 3
+
+	var buf bytes.Buffer
+	if err := bus.SaveState(&buf); err != nil {
+		t.Fatalf("SaveState returned an error: %s", err)
+	}
+
+	restored := NewIOBus(nil)
+	if err := restored.LoadState(&buf); err != nil {
+		t.Fatalf("LoadState returned an error: %s", err)
+	}
+
+	if restored.input1 != 0x01 || restored.input2 != 0x02 {
+		t.Errorf("LoadState did not restore input latches correctly")
+	}
+
+	if restored.shiftH != 0xff || restored.shiftL != 0xaa || restored.offset != 3 {
+		t.Errorf("LoadState did not restore shift register correctly")
+	}
+}
+
+func TestGetAndSetLatches(t *testing.T) {
+	bus := NewIOBus(nil)
+	bus.input1 = 0x01
+	bus.shiftH = 0xff
+	bus.offset = 3
+
+	restored := NewIOBus(nil)
+	restored.SetLatches(bus.GetLatches())
+
+	if restored.input1 != 0x01 || restored.shiftH != 0xff || restored.offset != 3 {
+		t.Errorf("SetLatches did not restore the latches returned by GetLatches")
+	}
+
+	restored.SetLatches(map[string]byte{"input2": 0x80, "unknown": 0x01})
+
+	if restored.input2 != 0x80 || restored.input1 != 0x01 {
+		t.Errorf("SetLatches did not set only the latches given")
+	}
+}
@@ -0,0 +1,174 @@
+package machine
+
+import (
+	"crypto/sha256"
+	"io"
+	"time"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/cpu"
+)
+
+const (
+	ClockSpeed     = 2_000_000 // Intel 8080 @ 2MHz
+	FrameRate      = 60
+	CyclesPerFrame = ClockSpeed / FrameRate
+
+	// The video hardware raises RST 1 when the beam reaches the middle of the
+	// screen and RST 2 when it enters vblank.
+	MidScreenCycles = CyclesPerFrame / 2
+	MidScreenRST    = 1
+	VBlankRST       = 2
+)
+
+// Bus is the IO bus of the machine. Its state is part of save states;
+// ReadState decodes it and returns a function that restores it.
+type Bus interface {
+	cpu.IOBus
+	SaveState(w io.Writer) error
+	ReadState(r io.Reader) (apply func(), err error)
+}
+
+// Breaker lets a debugger stop the machine between instructions. CanExecute
+// is called before every instruction and stops RunFrame when it returns false.
+type Breaker interface {
+	CanExecute() bool
+}
+
+type Machine struct {
+	cpu     *cpu.Intel8080
+	bus     Bus
+	romHash [sha256.Size]byte
+	breaker Breaker
+
+	// cycles executed since the start of the current frame
+	frameCycles uint
+	frames      uint64
+	midScreen   bool
+
+	// The video hardware holds its interrupt request until the CPU accepts
+	// it, e.g. once the instruction after EI has run
+	interruptPending bool
+	pendingRST       int
+}
+
+func NewMachine(c *cpu.Intel8080, bus Bus) *Machine {
+	return &Machine{
+		cpu: c,
+		bus: bus,
+	}
+}
+
+// LoadROM loads the program at $0000 and remembers its hash, so save states
+// can only be restored on the ROM they were taken with.
+func (m *Machine) LoadROM(rom []byte) {
+	m.cpu.LoadProgram(rom, 0)
+	m.romHash = sha256.Sum256(rom)
+}
+
+func (m *Machine) SetBreaker(b Breaker) {
+	m.breaker = b
+}
+
+func (m *Machine) Frames() uint64 {
+	return m.frames
+}
+
+// Step executes a single instruction and fires the video interrupts once their
+// cycle position has been reached. Interrupts the CPU refuses are requested
+// again after every instruction until it accepts them. It returns the number
+// of cycles executed.
+func (m *Machine) Step() uint {
+	cycles := m.cpu.Run()
+	m.frameCycles += cycles
+
+	if m.interruptPending {
+		m.interrupt(m.pendingRST)
+	}
+
+	if !m.midScreen && m.frameCycles >= MidScreenCycles {
+		m.midScreen = true
+		m.interrupt(MidScreenRST)
+	}
+
+	if m.frameCycles >= CyclesPerFrame {
+		m.frameCycles -= CyclesPerFrame
+		m.midScreen = false
+		m.frames++
+		m.interrupt(VBlankRST)
+	}
+
+	return cycles
+}
+
+// RunFrame executes instructions until the vblank interrupt of the current
+// frame has been fired. It returns false if the breaker stopped it first; the
+// next call carries on with the same frame.
+func (m *Machine) RunFrame() bool {
+	frame := m.frames
+	for m.frames == frame {
+		if m.breaker != nil && !m.breaker.CanExecute() {
+			return false
+		}
+		m.Step()
+	}
+	return true
+}
+
+// StepFrame executes the rest of the current frame without asking the
+// breaker, so a paused debugger can advance the machine a frame at a time.
+func (m *Machine) StepFrame() {
+	frame := m.frames
+	for m.frames == frame {
+		m.Step()
+	}
+}
+
+// Reset restarts the CPU at $0000 at the beginning of a new frame. Memory and
+// the IO bus are left alone, like pressing reset on the cabinet.
+func (m *Machine) Reset() {
+	m.cpu.Reset()
+	m.frameCycles = 0
+	m.midScreen = false
+	m.interruptPending = false
+}
+
+// interrupt requests an RST, which replaces any request still pending as the
+// hardware only puts one on the data bus.
+func (m *Machine) interrupt(interruptType int) {
+	cycles := m.cpu.Interrupt(interruptType)
+	m.frameCycles += cycles
+	m.interruptPending = cycles == 0
+	m.pendingRST = interruptType
+}
+
+// Pacer keeps emulated frames in step with the wall clock. Emulation itself is
+// driven by cycles; the pacer only sleeps away the time left in each frame.
+type Pacer struct {
+	frameDuration time.Duration
+	next          time.Time
+}
+
+func NewPacer(frameRate int) *Pacer {
+	return &Pacer{
+		frameDuration: time.Second / time.Duration(frameRate),
+	}
+}
+
+func (p *Pacer) Wait() {
+	now := time.Now()
+	if p.next.IsZero() {
+		p.next = now
+	}
+
+	p.next = p.next.Add(p.frameDuration)
+
+	if wait := p.next.Sub(now); wait > 0 {
+		time.Sleep(wait)
+		return
+	}
+
+	// Running behind (e.g. the window was dragged); don't try to catch up.
+	if now.Sub(p.next) > p.frameDuration {
+		p.next = now
+	}
+}
@@ -0,0 +1,204 @@
+package machine
+
+import (
+	"io"
+	"testing"
+
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/cpu"
+)
+
+type TestIOBus struct {
+	latch byte
+}
+
+func (tb *TestIOBus) Read(b byte) byte {
+	return 0
+}
+
+func (tb *TestIOBus) Write(b1 byte, b2 byte) {}
+
+func (tb *TestIOBus) SaveState(w io.Writer) error {
+	_, err := w.Write([]byte{tb.latch})
+	return err
+}
+
+func (tb *TestIOBus) ReadState(r io.Reader) (func(), error) {
+	b := make([]byte, 1)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return func() { tb.latch = b[0] }, nil
+}
+
+// Counts RST 1 at $2000 and RST 2 at $2001
+var interruptCounterProgram = []byte{
+	0x31, 0x00, 0x24, // LXI SP,$2400
+	0xFB,             // EI
+	0xC3, 0x04, 0x00, // JMP $0004
+	0x00,
+	0x21, 0x00, 0x20, // $0008: LXI H,$2000
+	0x34, // INR M
+	0xFB, // EI
+	0xC9, // RET
+	0x00, 0x00,
+	0x21, 0x01, 0x20, // $0010: LXI H,$2001
+	0x34, // INR M
+	0xFB, // EI
+	0xC9, // RET
+}
+
+func createMachineWithProgramLoaded(p []byte) (*Machine, *cpu.Intel8080) {
+	bus := &TestIOBus{}
+	c := cpu.NewIntel8080(bus)
+	m := NewMachine(c, bus)
+	m.LoadROM(p)
+
+	return m, c
+}
+
+// Executes LXI H and INR M of the interrupt routine that has just been entered
+func runISR(m *Machine) {
+	m.Step()
+	m.Step()
+}
+
+func TestRunFrameFiresBothInterrupts(t *testing.T) {
+	m, c := createMachineWithProgramLoaded(interruptCounterProgram)
+
+	for i := 0; i < 3; i++ {
+		m.RunFrame()
+	}
+	runISR(m)
+
+	if m.Frames() != 3 {
+		t.Errorf("RunFrame did not count frames correctly")
+	}
+
+	if c.ReadFromMemory(0x2000) != 3 {
+		t.Errorf("RST 1 was not fired once per frame")
+	}
+
+	if c.ReadFromMemory(0x2001) != 3 {
+		t.Errorf("RST 2 was not fired once per frame")
+	}
+}
+
+func TestStepFiresMidScreenInterruptAtCyclePosition(t *testing.T) {
+	m, c := createMachineWithProgramLoaded(interruptCounterProgram)
+
+	var cycles uint
+	for cycles+10 < MidScreenCycles {
+		cycles += m.Step()
+	}
+
+	if c.ReadFromMemory(0x2000) != 0 {
+		t.Errorf("RST 1 was fired before mid-screen")
+	}
+
+	for cycles < MidScreenCycles {
+		cycles += m.Step()
+	}
+	runISR(m)
+
+	if c.ReadFromMemory(0x2000) != 1 || c.ReadFromMemory(0x2001) != 0 {
+		t.Errorf("only RST 1 should have been fired at mid-screen")
+	}
+}
+
+func TestInterruptsAreNotFiredWhenDisabled(t *testing.T) {
+	program := append([]byte{}, interruptCounterProgram...)
+	program[3] = 0x00 // NOP instead of EI
+
+	m, c := createMachineWithProgramLoaded(program)
+	m.RunFrame()
+
+	if c.ReadFromMemory(0x2000) != 0 || c.ReadFromMemory(0x2001) != 0 {
+		t.Errorf("interrupts were fired while disabled")
+	}
+}
+
+func TestInterruptRightAfterEIIsNotLost(t *testing.T) {
+	m, c := createMachineWithProgramLoaded(interruptCounterProgram)
+	m.Step() // LXI SP
+
+	// EI reaches mid-screen, but interrupts are only enabled after the
+	// instruction following it
+	m.frameCycles = MidScreenCycles - 4
+	m.Step()
+
+	if c.GetPC() != 0x0004 {
+		t.Fatalf("RST 1 was accepted on the instruction after EI, PC is $%04X", c.GetPC())
+	}
+
+	m.Step() // JMP
+	runISR(m)
+
+	if c.ReadFromMemory(0x2000) != 1 {
+		t.Errorf("RST 1 requested during EI was lost")
+	}
+}
+
+type countingBreaker struct {
+	allowed int
+}
+
+func (b *countingBreaker) CanExecute() bool {
+	if b.allowed == 0 {
+		return false
+	}
+	b.allowed--
+	return true
+}
+
+func TestBreakerStopsRunFrame(t *testing.T) {
+	m, c := createMachineWithProgramLoaded(interruptCounterProgram)
+	breaker := &countingBreaker{allowed: 3}
+	m.SetBreaker(breaker)
+
+	if m.RunFrame() {
+		t.Errorf("RunFrame did not stop when the breaker refused to execute")
+	}
+
+	if c.GetPC() != 0x0004 || m.Frames() != 0 {
+		t.Errorf("RunFrame did not stop before the refused instruction")
+	}
+
+	breaker.allowed = -1
+	if !m.RunFrame() || m.Frames() != 1 {
+		t.Errorf("RunFrame did not carry on with the stopped frame")
+	}
+}
+
+func TestStepFrameIgnoresBreaker(t *testing.T) {
+	m, _ := createMachineWithProgramLoaded(interruptCounterProgram)
+	m.SetBreaker(&countingBreaker{})
+
+	m.StepFrame()
+
+	if m.Frames() != 1 {
+		t.Errorf("StepFrame did not run a whole frame")
+	}
+}
+
+func TestReset(t *testing.T) {
+	m, c := createMachineWithProgramLoaded(interruptCounterProgram)
+
+	for c.ReadFromMemory(0x2000) == 0 {
+		m.Step()
+	}
+	m.Reset()
+
+	if c.GetPC() != 0x0000 || c.ReadFromMemory(0x2000) != 1 {
+		t.Errorf("Reset did not restart the program with memory untouched")
+	}
+
+	// The frame starts over, so RST 1 is not fired until mid-screen again
+	var cycles uint
+	for cycles+10 < MidScreenCycles {
+		cycles += m.Step()
+	}
+
+	if c.ReadFromMemory(0x2000) != 1 {
+		t.Errorf("Reset did not start a new frame")
+	}
+}
@@ -0,0 +1,40 @@
+package machine
+
+const (
+	romEnd     = 0x2000
+	addressMax = 0x4000
+	// Only A0-A13 are decoded, so everything from $4000 up mirrors $0000-$3FFF
+	addressMask = addressMax - 1
+)
+
+// Memory implements the Space Invaders memory map:
+//
+//	$0000-$1FFF: ROM (read-only)
+//	$2000-$23FF: work RAM
+//	$2400-$3FFF: video RAM
+//	$4000-:      mirror
+type Memory struct {
+	data [addressMax]byte
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (mem *Memory) Read(addr uint16) byte {
+	return mem.data[addr&addressMask]
+}
+
+func (mem *Memory) Write(addr uint16, value byte) {
+	addr &= addressMask
+	if addr < romEnd {
+		return
+	}
+	mem.data[addr] = value
+}
+
+func (mem *Memory) Load(data []byte, offset uint16) {
+	for i, b := range data {
+		mem.data[(offset+uint16(i))&addressMask] = b
+	}
+}
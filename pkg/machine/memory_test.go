@@ -0,0 +1,55 @@
+package machine
+
+import "testing"
+
+func TestMemoryROMIsReadOnly(t *testing.T) {
+	mem := NewMemory()
+	mem.Load([]byte{0xAA, 0xBB}, 0x1FFE)
+
+	mem.Write(0x1FFE, 0x00)
+	mem.Write(0x1FFF, 0x00)
+
+	if mem.Read(0x1FFE) != 0xAA || mem.Read(0x1FFF) != 0xBB {
+		t.Errorf("Write did not protect ROM")
+	}
+}
+
+func TestMemoryRAMIsWritable(t *testing.T) {
+	mem := NewMemory()
+	mem.Write(0x2000, 0x42)
+	mem.Write(0x3FFF, 0x24)
+
+	if mem.Read(0x2000) != 0x42 || mem.Read(0x3FFF) != 0x24 {
+		t.Errorf("Write did not store values in RAM")
+	}
+}
+
+func TestMemoryMirrorsRAMAbove4000(t *testing.T) {
+	mem := NewMemory()
+	mem.Write(0x6010, 0x42)
+
+	if mem.Read(0x2010) != 0x42 {
+		t.Errorf("Write did not store value in mirrored RAM")
+	}
+
+	mem.Write(0x4010, 0x42)
+
+	if mem.Read(0x0010) != 0x00 {
+		t.Errorf("Write did not protect mirrored ROM")
+	}
+
+	mem.Write(0x2400, 0x24)
+
+	if mem.Read(0x6400) != 0x24 || mem.Read(0xE400) != 0x24 {
+		t.Errorf("Read did not return mirrored RAM")
+	}
+}
+
+func TestMemoryCoversFullAddressSpace(t *testing.T) {
+	mem := NewMemory()
+	mem.Write(0xFFFF, 0x42)
+
+	if mem.Read(0xFFFF) != 0x42 || mem.Read(0x3FFF) != 0x42 {
+		t.Errorf("address $FFFF is not reachable")
+	}
+}
@@ -0,0 +1,165 @@
+package machine
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+)
+
+var errDeltaSize = errors.New("delta does not match snapshot size")
+
+// Rewinder records machine snapshots every few frames so the game can be
+// played backwards. Only the most recent snapshot is kept as a whole; older
+// ones are stored in a ring buffer as compressed deltas against their
+// successor.
+type Rewinder struct {
+	machine  *Machine
+	interval int
+
+	deltas [][]byte
+	head   int // next slot to be written
+	count  int
+
+	latest  []byte
+	elapsed int // frames since the latest snapshot
+
+	encoder *deltaEncoder
+}
+
+// NewRewinder keeps up to capacity snapshots, one every interval frames. A
+// capacity below 1 disables rewinding.
+func NewRewinder(m *Machine, capacity int, interval int) *Rewinder {
+	return &Rewinder{
+		machine:  m,
+		interval: max(interval, 1),
+		deltas:   make([][]byte, max(capacity, 0)),
+		encoder:  newDeltaEncoder(),
+	}
+}
+
+// Len returns how many snapshots can still be rewound to.
+func (r *Rewinder) Len() int {
+	return r.count
+}
+
+// Record must be called once per emulated frame.
+func (r *Rewinder) Record() error {
+	if len(r.deltas) == 0 {
+		return nil
+	}
+
+	r.elapsed++
+	if r.latest != nil && r.elapsed < r.interval {
+		return nil
+	}
+	r.elapsed = 0
+
+	var buf bytes.Buffer
+	if err := r.machine.SaveState(&buf); err != nil {
+		return err
+	}
+	snapshot := buf.Bytes()
+
+	if r.latest != nil {
+		delta, err := r.encoder.encode(snapshot, r.latest)
+		if err != nil {
+			return err
+		}
+
+		r.deltas[r.head] = delta
+		r.head = (r.head + 1) % len(r.deltas)
+		r.count = min(r.count+1, len(r.deltas))
+	}
+
+	r.latest = snapshot
+
+	return nil
+}
+
+// Rewind restores the machine to the previous snapshot. It returns false when
+// there is nothing left to rewind to.
+func (r *Rewinder) Rewind() (bool, error) {
+	if r.count == 0 {
+		return false, nil
+	}
+
+	// The snapshots are only given up once the previous one is restored, so
+	// a failed rewind can be retried
+	head := (r.head - 1 + len(r.deltas)) % len(r.deltas)
+
+	previous, err := decodeDelta(r.latest, r.deltas[head])
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.machine.LoadState(bytes.NewReader(previous)); err != nil {
+		return false, err
+	}
+
+	r.deltas[head] = nil
+	r.head = head
+	r.count--
+	r.latest = previous
+	r.elapsed = 0
+
+	return true, nil
+}
+
+type deltaEncoder struct {
+	buf bytes.Buffer
+	w   *flate.Writer
+}
+
+func newDeltaEncoder() *deltaEncoder {
+	e := &deltaEncoder{}
+	// BestSpeed never returns an error
+	e.w, _ = flate.NewWriter(&e.buf, flate.BestSpeed)
+	return e
+}
+
+// encode returns target XORed with base and deflated. Snapshots of the same
+// machine only differ in a few bytes, so the XOR is mostly zeros.
+func (e *deltaEncoder) encode(base []byte, target []byte) ([]byte, error) {
+	if len(base) != len(target) {
+		return nil, errDeltaSize
+	}
+
+	xor := make([]byte, len(target))
+	for i := range target {
+		xor[i] = base[i] ^ target[i]
+	}
+
+	e.buf.Reset()
+	e.w.Reset(&e.buf)
+	if _, err := e.w.Write(xor); err != nil {
+		return nil, err
+	}
+	if err := e.w.Close(); err != nil {
+		return nil, err
+	}
+
+	return bytes.Clone(e.buf.Bytes()), nil
+}
+
+func encodeDelta(base []byte, target []byte) ([]byte, error) {
+	return newDeltaEncoder().encode(base, target)
+}
+
+func decodeDelta(base []byte, delta []byte) ([]byte, error) {
+	xor, err := io.ReadAll(flate.NewReader(bytes.NewReader(delta)))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(xor) != len(base) {
+		return nil, errDeltaSize
+	}
+
+	target := make([]byte, len(base))
+	for i := range base {
+		target[i] = base[i] ^ xor[i]
+	}
+
+	return target, nil
+}
@@ -0,0 +1,151 @@
+package machine
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDeltaRoundTrip(t *testing.T) {
+	base := make([]byte, 0x10000)
+	target := make([]byte, 0x10000)
+	for i := range target {
+		base[i] = byte(i)
+		target[i] = byte(i)
+	}
+	target[0x0000] = 0xFF
+	target[0x2400] = 0x42
+	target[0xFFFF] = 0x24
+
+	delta, err := encodeDelta(base, target)
+	if err != nil {
+		t.Fatalf("encodeDelta returned an error: %s", err)
+	}
+
+	if len(delta) >= len(target)/16 {
+		t.Errorf("encodeDelta did not compress the delta (%d bytes)", len(delta))
+	}
+
+	decoded, err := decodeDelta(base, delta)
+	if err != nil {
+		t.Fatalf("decodeDelta returned an error: %s", err)
+	}
+
+	if !bytes.Equal(decoded, target) {
+		t.Errorf("decodeDelta did not restore the target snapshot")
+	}
+}
+
+func TestDeltaRejectsSizeMismatch(t *testing.T) {
+	if _, err := encodeDelta(make([]byte, 2), make([]byte, 3)); err == nil {
+		t.Errorf("encodeDelta accepted snapshots of different sizes")
+	}
+
+	delta, _ := encodeDelta(make([]byte, 2), make([]byte, 2))
+	if _, err := decodeDelta(make([]byte, 3), delta); err == nil {
+		t.Errorf("decodeDelta accepted a base of a different size")
+	}
+}
+
+func snapshot(t *testing.T, m *Machine) []byte {
+	var buf bytes.Buffer
+	if err := m.SaveState(&buf); err != nil {
+		t.Fatalf("SaveState returned an error: %s", err)
+	}
+	return buf.Bytes()
+}
+
+func TestRewindRestoresPreviousSnapshots(t *testing.T) {
+	m, _ := createMachineWithProgramLoaded(interruptCounterProgram)
+	r := NewRewinder(m, 10, 2)
+
+	snapshots := [][]byte{}
+	for frame := 1; frame <= 6; frame++ {
+		m.RunFrame()
+		r.Record()
+		if frame%2 == 1 {
+			snapshots = append(snapshots, snapshot(t, m))
+		}
+	}
+
+	if r.Len() != 2 {
+		t.Fatalf("Rewinder recorded %d snapshots to rewind to, expected 2", r.Len())
+	}
+
+	for i := 1; i >= 0; i-- {
+		if ok, err := r.Rewind(); !ok || err != nil {
+			t.Fatalf("Rewind failed: %v", err)
+		}
+
+		if !bytes.Equal(snapshot(t, m), snapshots[i]) {
+			t.Errorf("Rewind did not restore snapshot %d", i)
+		}
+	}
+
+	if ok, _ := r.Rewind(); ok {
+		t.Errorf("Rewind went past the oldest snapshot")
+	}
+}
+
+func TestRewindDropsOldestSnapshotsWhenFull(t *testing.T) {
+	m, _ := createMachineWithProgramLoaded(interruptCounterProgram)
+	r := NewRewinder(m, 3, 1)
+
+	for i := 0; i < 10; i++ {
+		m.RunFrame()
+		r.Record()
+	}
+
+	if r.Len() != 3 {
+		t.Fatalf("Rewinder kept %d snapshots, expected 3", r.Len())
+	}
+
+	for i := 0; i < 3; i++ {
+		r.Rewind()
+	}
+
+	if m.Frames() != 7 {
+		t.Errorf("Rewind restored frame %d, expected 7", m.Frames())
+	}
+}
+
+func TestRewinderWithoutCapacityIsDisabled(t *testing.T) {
+	m, _ := createMachineWithProgramLoaded(interruptCounterProgram)
+	for _, capacity := range []int{0, -1} {
+		r := NewRewinder(m, capacity, 1)
+
+		m.RunFrame()
+		r.Record()
+
+		if ok, _ := r.Rewind(); ok {
+			t.Errorf("Rewinder with capacity %d rewound the machine", capacity)
+		}
+	}
+}
+
+func TestFailedRewindKeepsSnapshots(t *testing.T) {
+	m, _ := createMachineWithProgramLoaded(interruptCounterProgram)
+	r := NewRewinder(m, 10, 1)
+
+	m.RunFrame()
+	r.Record()
+	previous := snapshot(t, m)
+	m.RunFrame()
+	r.Record()
+	current := snapshot(t, m)
+
+	delta := r.deltas[0]
+	r.deltas[0] = []byte("garbage")
+
+	if ok, err := r.Rewind(); ok || err == nil {
+		t.Fatalf("Rewind did not fail on a corrupt snapshot")
+	}
+
+	if r.Len() != 1 || !bytes.Equal(snapshot(t, m), current) {
+		t.Errorf("failed Rewind dropped the snapshot or changed the machine")
+	}
+
+	r.deltas[0] = delta
+	if ok, err := r.Rewind(); !ok || err != nil || !bytes.Equal(snapshot(t, m), previous) {
+		t.Errorf("Rewind did not restore the snapshot after a failed rewind: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package machine
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// StateVersion is bumped whenever the layout of a saved state changes.
+const StateVersion uint16 = 4
+
+var stateMagic = [4]byte{'S', 'I', '8', '0'}
+
+var (
+	ErrInvalidState       = errors.New("not a machine state")
+	ErrUnsupportedVersion = errors.New("unsupported state version")
+	ErrROMMismatch        = errors.New("state was saved with a different ROM")
+)
+
+type stateHeader struct {
+	Magic   [4]byte
+	Version uint16
+	ROMHash [sha256.Size]byte
+}
+
+type schedulerState struct {
+	FrameCycles      uint32
+	Frames           uint64
+	MidScreen        bool
+	InterruptPending bool
+	PendingRST       uint8
+}
+
+// SaveState writes a versioned snapshot of the CPU, the IO bus and the frame
+// scheduler to w.
+func (m *Machine) SaveState(w io.Writer) error {
+	header := &stateHeader{
+		Magic:   stateMagic,
+		Version: StateVersion,
+		ROMHash: m.romHash,
+	}
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+
+	if err := m.cpu.SaveState(w); err != nil {
+		return err
+	}
+
+	if err := m.bus.SaveState(w); err != nil {
+		return err
+	}
+
+	scheduler := &schedulerState{
+		FrameCycles:      uint32(m.frameCycles),
+		Frames:           m.frames,
+		MidScreen:        m.midScreen,
+		InterruptPending: m.interruptPending,
+		PendingRST:       uint8(m.pendingRST),
+	}
+	return binary.Write(w, binary.LittleEndian, scheduler)
+}
+
+// LoadState restores a snapshot written by SaveState. The state is rejected if
+// it was saved by another format version or with a different ROM. The machine
+// is only changed once the whole snapshot has been read.
+func (m *Machine) LoadState(r io.Reader) error {
+	header := &stateHeader{}
+	if err := binary.Read(r, binary.LittleEndian, header); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidState, err)
+	}
+
+	if header.Magic != stateMagic {
+		return ErrInvalidState
+	}
+
+	if header.Version != StateVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedVersion, header.Version)
+	}
+
+	if header.ROMHash != m.romHash {
+		return ErrROMMismatch
+	}
+
+	applyCPU, err := m.cpu.ReadState(r)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidState, err)
+	}
+
+	applyBus, err := m.bus.ReadState(r)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidState, err)
+	}
+
+	scheduler := &schedulerState{}
+	if err := binary.Read(r, binary.LittleEndian, scheduler); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidState, err)
+	}
+
+	applyCPU()
+	applyBus()
+	m.frameCycles = uint(scheduler.FrameCycles)
+	m.frames = scheduler.Frames
+	m.midScreen = scheduler.MidScreen
+	m.interruptPending = scheduler.InterruptPending
+	m.pendingRST = int(scheduler.PendingRST)
+
+	return nil
+}
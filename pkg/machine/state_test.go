@@ -0,0 +1,102 @@
+package machine
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSaveAndLoadState(t *testing.T) {
+	m, c := createMachineWithProgramLoaded(interruptCounterProgram)
+	m.RunFrame()
+	m.Step()
+	m.bus.(*TestIOBus).latch = 0x42
+
+	var buf bytes.Buffer
+	if err := m.SaveState(&buf); err != nil {
+		t.Fatalf("SaveState returned an error: %s", err)
+	}
+	saved := buf.Bytes()
+
+	m.RunFrame()
+	m.RunFrame()
+	m.bus.(*TestIOBus).latch = 0x00
+
+	if err := m.LoadState(bytes.NewReader(saved)); err != nil {
+		t.Fatalf("LoadState returned an error: %s", err)
+	}
+
+	if m.Frames() != 1 || c.ReadFromMemory(0x2000) != 1 {
+		t.Errorf("LoadState did not restore the machine correctly")
+	}
+
+	if m.bus.(*TestIOBus).latch != 0x42 {
+		t.Errorf("LoadState did not restore the IO bus correctly")
+	}
+
+	var again bytes.Buffer
+	m.SaveState(&again)
+	if !bytes.Equal(saved, again.Bytes()) {
+		t.Errorf("restored machine does not save the same state")
+	}
+}
+
+func TestLoadStateRejectsDifferentROM(t *testing.T) {
+	m, _ := createMachineWithProgramLoaded(interruptCounterProgram)
+
+	var buf bytes.Buffer
+	m.SaveState(&buf)
+
+	other, _ := createMachineWithProgramLoaded([]byte{0x00})
+	if err := other.LoadState(&buf); !errors.Is(err, ErrROMMismatch) {
+		t.Errorf("LoadState did not reject a state saved with another ROM")
+	}
+}
+
+func TestLoadStateRejectsOtherVersions(t *testing.T) {
+	m, _ := createMachineWithProgramLoaded(interruptCounterProgram)
+
+	var buf bytes.Buffer
+	m.SaveState(&buf)
+	state := buf.Bytes()
+	state[4]++ // version follows the 4 byte magic
+
+	if err := m.LoadState(bytes.NewReader(state)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("LoadState did not reject an unsupported version")
+	}
+}
+
+func TestLoadStateRejectsGarbage(t *testing.T) {
+	m, _ := createMachineWithProgramLoaded(interruptCounterProgram)
+
+	if err := m.LoadState(bytes.NewReader([]byte("garbage"))); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("LoadState did not reject an invalid state")
+	}
+}
+
+func TestLoadStateTruncatedLeavesMachineUnchanged(t *testing.T) {
+	m, _ := createMachineWithProgramLoaded(interruptCounterProgram)
+	m.RunFrame()
+	m.bus.(*TestIOBus).latch = 0x42
+
+	var buf bytes.Buffer
+	m.SaveState(&buf)
+	saved := buf.Bytes()
+
+	m.RunFrame()
+	m.bus.(*TestIOBus).latch = 0x07
+
+	var before bytes.Buffer
+	m.SaveState(&before)
+
+	// Cut in the middle of the IO bus block, after the whole CPU block
+	if err := m.LoadState(bytes.NewReader(saved[:len(saved)-14])); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("LoadState did not reject a truncated state, got %v", err)
+	}
+
+	var after bytes.Buffer
+	m.SaveState(&after)
+	if !bytes.Equal(before.Bytes(), after.Bytes()) {
+		t.Errorf("LoadState changed the machine before failing")
+	}
+}
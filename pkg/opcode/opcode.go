@@ -0,0 +1,219 @@
+package opcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+type OperandKind int
+
+const (
+	// Register is a register or register pair, including M and PSW
+	Register OperandKind = iota
+	Data8
+	Data16
+	// Address is a memory address or the destination of a jump or call
+	Address
+	Port
+	// Restart is the number of an RST instruction
+	Restart
+)
+
+type Operand struct {
+	Kind OperandKind
+	// Name of a register, or the number of an RST
+	Name string
+}
+
+// Flow is how an instruction affects the program counter.
+type Flow int
+
+const (
+	// Next goes on with the following instruction
+	Next Flow = iota
+	Jump
+	Call
+	Return
+	Halt
+)
+
+// Access is what an instruction does with the memory at its address operand.
+// Memory accessed through register pairs, such as M, is not known without
+// running the instruction.
+type Access int
+
+const (
+	NoAccess Access = iota
+	Read
+	Write
+)
+
+// Flags is a set of flags, laid out as PUSH PSW stores them.
+type Flags byte
+
+const (
+	None     Flags = 0
+	Sign     Flags = 1 << 7
+	Zero     Flags = 1 << 6
+	AuxCarry Flags = 1 << 4
+	Parity   Flags = 1 << 2
+	Carry    Flags = 1 << 0
+
+	All = Sign | Zero | AuxCarry | Parity | Carry
+	// Set by INR and DCR
+	AllButCarry = Sign | Zero | AuxCarry | Parity
+)
+
+// Opcode describes what an opcode does, as far as the CPU, the disassembler
+// and their tests need to know without running it.
+type Opcode struct {
+	Mnemonic string
+	Operands []Operand
+	Size     uint16
+	// Cycles spent, or spent when the condition of a conditional instruction
+	// does not hold. CyclesTaken is spent when it holds.
+	Cycles      uint
+	CyclesTaken uint
+	// Flags the instruction may change. The 8085 V and K flags are not
+	// tracked.
+	Flags Flags
+
+	Flow        Flow
+	Conditional bool
+	// Address RST and RSTV call
+	Vector    uint16
+	HasVector bool
+
+	// Access to the memory at the address operand, of AccessSize bytes
+	Access     Access
+	AccessSize uint16
+
+	// Undocumented opcodes, most of them aliases of documented ones
+	Undocumented bool
+}
+
+var conditions = map[string]bool{
+	"NZ": true, "Z": true, "NC": true, "C": true,
+	"PO": true, "PE": true, "P": true, "M": true,
+	// 8085 only, on K
+	"NK": true, "K": true,
+}
+
+func flow(mnemonic string) (Flow, bool) {
+	switch mnemonic {
+	case "JMP", "PCHL":
+		return Jump, false
+	case "CALL", "RST":
+		return Call, false
+	case "RSTV":
+		return Call, true
+	case "RET":
+		return Return, false
+	case "HLT":
+		return Halt, false
+	}
+
+	switch {
+	case mnemonic[0] == 'J' && conditions[mnemonic[1:]]:
+		return Jump, true
+	case mnemonic[0] == 'C' && conditions[mnemonic[1:]]:
+		return Call, true
+	case mnemonic[0] == 'R' && conditions[mnemonic[1:]]:
+		return Return, true
+	}
+
+	return Next, false
+}
+
+func access(mnemonic string) (Access, uint16) {
+	switch mnemonic {
+	case "LDA":
+		return Read, 1
+	case "STA":
+		return Write, 1
+	case "LHLD":
+		return Read, 2
+	case "SHLD":
+		return Write, 2
+	}
+	return NoAccess, 0
+}
+
+// parse builds an opcode from its assembly template, e.g. "MVI B,d8".
+// Operands are registers or one of d8 (data byte), d16 (data word), a16
+// (address) and p8 (port). Undocumented opcodes start with *. cyclesTaken
+// is 0 for instructions without a condition.
+func parse(spec string, cycles uint, cyclesTaken uint, flags Flags) Opcode {
+	o := Opcode{Size: 1, Cycles: cycles, CyclesTaken: cyclesTaken, Flags: flags}
+
+	if strings.HasPrefix(spec, "*") {
+		o.Undocumented = true
+		spec = spec[1:]
+	}
+
+	mnemonic, operands, _ := strings.Cut(spec, " ")
+	o.Mnemonic = mnemonic
+
+	if operands != "" {
+		for _, name := range strings.Split(operands, ",") {
+			operand := Operand{Kind: Register, Name: name}
+			switch name {
+			case "d8":
+				operand = Operand{Kind: Data8}
+				o.Size += 1
+			case "p8":
+				operand = Operand{Kind: Port}
+				o.Size += 1
+			case "d16":
+				operand = Operand{Kind: Data16}
+				o.Size += 2
+			case "a16":
+				operand = Operand{Kind: Address}
+				o.Size += 2
+			default:
+				if mnemonic == "RST" {
+					operand.Kind = Restart
+					o.Vector, o.HasVector = uint16(name[0]-'0')*8, true
+				}
+			}
+			o.Operands = append(o.Operands, operand)
+		}
+	}
+
+	if mnemonic == "RSTV" {
+		o.Vector, o.HasVector = 0x0040, true
+	}
+
+	o.Flow, o.Conditional = flow(mnemonic)
+	o.Access, o.AccessSize = access(mnemonic)
+	if o.CyclesTaken == 0 {
+		o.CyclesTaken = o.Cycles
+	}
+
+	return o
+}
+
+// Name returns the mnemonic with the operands that are part of the opcode,
+// such as registers, e.g. MOV B,C or LXI SP. Undocumented opcodes are marked
+// with *.
+func (o Opcode) Name() string {
+	var operands []string
+	for _, operand := range o.Operands {
+		if operand.Kind == Register || operand.Kind == Restart {
+			operands = append(operands, operand.Name)
+		}
+	}
+
+	name := o.Mnemonic
+	if len(operands) > 0 {
+		name += " " + strings.Join(operands, ",")
+	}
+	if o.Undocumented {
+		name = "*" + name
+	}
+	return name
+}
+
+func (o Opcode) String() string {
+	return fmt.Sprintf("%s (%d bytes, %d/%d cycles)", o.Name(), o.Size, o.Cycles, o.CyclesTaken)
+}
@@ -0,0 +1,553 @@
+package opcode
+
+// The 8080 instruction set. Undocumented opcodes behave like the documented
+// instruction they alias.
+var Intel8080 = [256]Opcode{
+	0x00: parse("NOP", 4, 0, None),
+	0x01: parse("LXI B,d16", 10, 0, None),
+	0x02: parse("STAX B", 7, 0, None),
+	0x03: parse("INX B", 5, 0, None),
+	0x04: parse("INR B", 5, 0, AllButCarry),
+	0x05: parse("DCR B", 5, 0, AllButCarry),
+	0x06: parse("MVI B,d8", 7, 0, None),
+	0x07: parse("RLC", 4, 0, Carry),
+	0x08: parse("*NOP", 4, 0, None),
+	0x09: parse("DAD B", 10, 0, Carry),
+	0x0a: parse("LDAX B", 7, 0, None),
+	0x0b: parse("DCX B", 5, 0, None),
+	0x0c: parse("INR C", 5, 0, AllButCarry),
+	0x0d: parse("DCR C", 5, 0, AllButCarry),
+	0x0e: parse("MVI C,d8", 7, 0, None),
+	0x0f: parse("RRC", 4, 0, Carry),
+
+	0x10: parse("*NOP", 4, 0, None),
+	0x11: parse("LXI D,d16", 10, 0, None),
+	0x12: parse("STAX D", 7, 0, None),
+	0x13: parse("INX D", 5, 0, None),
+	0x14: parse("INR D", 5, 0, AllButCarry),
+	0x15: parse("DCR D", 5, 0, AllButCarry),
+	0x16: parse("MVI D,d8", 7, 0, None),
+	0x17: parse("RAL", 4, 0, Carry),
+	0x18: parse("*NOP", 4, 0, None),
+	0x19: parse("DAD D", 10, 0, Carry),
+	0x1a: parse("LDAX D", 7, 0, None),
+	0x1b: parse("DCX D", 5, 0, None),
+	0x1c: parse("INR E", 5, 0, AllButCarry),
+	0x1d: parse("DCR E", 5, 0, AllButCarry),
+	0x1e: parse("MVI E,d8", 7, 0, None),
+	0x1f: parse("RAR", 4, 0, Carry),
+
+	0x20: parse("*NOP", 4, 0, None),
+	0x21: parse("LXI H,d16", 10, 0, None),
+	0x22: parse("SHLD a16", 16, 0, None),
+	0x23: parse("INX H", 5, 0, None),
+	0x24: parse("INR H", 5, 0, AllButCarry),
+	0x25: parse("DCR H", 5, 0, AllButCarry),
+	0x26: parse("MVI H,d8", 7, 0, None),
+	0x27: parse("DAA", 4, 0, All),
+	0x28: parse("*NOP", 4, 0, None),
+	0x29: parse("DAD H", 10, 0, Carry),
+	0x2a: parse("LHLD a16", 16, 0, None),
+	0x2b: parse("DCX H", 5, 0, None),
+	0x2c: parse("INR L", 5, 0, AllButCarry),
+	0x2d: parse("DCR L", 5, 0, AllButCarry),
+	0x2e: parse("MVI L,d8", 7, 0, None),
+	0x2f: parse("CMA", 4, 0, None),
+
+	0x30: parse("*NOP", 4, 0, None),
+	0x31: parse("LXI SP,d16", 10, 0, None),
+	0x32: parse("STA a16", 13, 0, None),
+	0x33: parse("INX SP", 5, 0, None),
+	0x34: parse("INR M", 10, 0, AllButCarry),
+	0x35: parse("DCR M", 10, 0, AllButCarry),
+	0x36: parse("MVI M,d8", 10, 0, None),
+	0x37: parse("STC", 4, 0, Carry),
+	0x38: parse("*NOP", 4, 0, None),
+	0x39: parse("DAD SP", 10, 0, Carry),
+	0x3a: parse("LDA a16", 13, 0, None),
+	0x3b: parse("DCX SP", 5, 0, None),
+	0x3c: parse("INR A", 5, 0, AllButCarry),
+	0x3d: parse("DCR A", 5, 0, AllButCarry),
+	0x3e: parse("MVI A,d8", 7, 0, None),
+	0x3f: parse("CMC", 4, 0, Carry),
+
+	0x40: parse("MOV B,B", 5, 0, None),
+	0x41: parse("MOV B,C", 5, 0, None),
+	0x42: parse("MOV B,D", 5, 0, None),
+	0x43: parse("MOV B,E", 5, 0, None),
+	0x44: parse("MOV B,H", 5, 0, None),
+	0x45: parse("MOV B,L", 5, 0, None),
+	0x46: parse("MOV B,M", 7, 0, None),
+	0x47: parse("MOV B,A", 5, 0, None),
+	0x48: parse("MOV C,B", 5, 0, None),
+	0x49: parse("MOV C,C", 5, 0, None),
+	0x4a: parse("MOV C,D", 5, 0, None),
+	0x4b: parse("MOV C,E", 5, 0, None),
+	0x4c: parse("MOV C,H", 5, 0, None),
+	0x4d: parse("MOV C,L", 5, 0, None),
+	0x4e: parse("MOV C,M", 7, 0, None),
+	0x4f: parse("MOV C,A", 5, 0, None),
+
+	0x50: parse("MOV D,B", 5, 0, None),
+	0x51: parse("MOV D,C", 5, 0, None),
+	0x52: parse("MOV D,D", 5, 0, None),
+	0x53: parse("MOV D,E", 5, 0, None),
+	0x54: parse("MOV D,H", 5, 0, None),
+	0x55: parse("MOV D,L", 5, 0, None),
+	0x56: parse("MOV D,M", 7, 0, None),
+	0x57: parse("MOV D,A", 5, 0, None),
+	0x58: parse("MOV E,B", 5, 0, None),
+	0x59: parse("MOV E,C", 5, 0, None),
+	0x5a: parse("MOV E,D", 5, 0, None),
+	0x5b: parse("MOV E,E", 5, 0, None),
+	0x5c: parse("MOV E,H", 5, 0, None),
+	0x5d: parse("MOV E,L", 5, 0, None),
+	0x5e: parse("MOV E,M", 7, 0, None),
+	0x5f: parse("MOV E,A", 5, 0, None),
+
+	0x60: parse("MOV H,B", 5, 0, None),
+	0x61: parse("MOV H,C", 5, 0, None),
+	0x62: parse("MOV H,D", 5, 0, None),
+	0x63: parse("MOV H,E", 5, 0, None),
+	0x64: parse("MOV H,H", 5, 0, None),
+	0x65: parse("MOV H,L", 5, 0, None),
+	0x66: parse("MOV H,M", 7, 0, None),
+	0x67: parse("MOV H,A", 5, 0, None),
+	0x68: parse("MOV L,B", 5, 0, None),
+	0x69: parse("MOV L,C", 5, 0, None),
+	0x6a: parse("MOV L,D", 5, 0, None),
+	0x6b: parse("MOV L,E", 5, 0, None),
+	0x6c: parse("MOV L,H", 5, 0, None),
+	0x6d: parse("MOV L,L", 5, 0, None),
+	0x6e: parse("MOV L,M", 7, 0, None),
+	0x6f: parse("MOV L,A", 5, 0, None),
+
+	0x70: parse("MOV M,B", 7, 0, None),
+	0x71: parse("MOV M,C", 7, 0, None),
+	0x72: parse("MOV M,D", 7, 0, None),
+	0x73: parse("MOV M,E", 7, 0, None),
+	0x74: parse("MOV M,H", 7, 0, None),
+	0x75: parse("MOV M,L", 7, 0, None),
+	0x76: parse("HLT", 7, 0, None),
+	0x77: parse("MOV M,A", 7, 0, None),
+	0x78: parse("MOV A,B", 5, 0, None),
+	0x79: parse("MOV A,C", 5, 0, None),
+	0x7a: parse("MOV A,D", 5, 0, None),
+	0x7b: parse("MOV A,E", 5, 0, None),
+	0x7c: parse("MOV A,H", 5, 0, None),
+	0x7d: parse("MOV A,L", 5, 0, None),
+	0x7e: parse("MOV A,M", 7, 0, None),
+	0x7f: parse("MOV A,A", 5, 0, None),
+
+	0x80: parse("ADD B", 4, 0, All),
+	0x81: parse("ADD C", 4, 0, All),
+	0x82: parse("ADD D", 4, 0, All),
+	0x83: parse("ADD E", 4, 0, All),
+	0x84: parse("ADD H", 4, 0, All),
+	0x85: parse("ADD L", 4, 0, All),
+	0x86: parse("ADD M", 7, 0, All),
+	0x87: parse("ADD A", 4, 0, All),
+	0x88: parse("ADC B", 4, 0, All),
+	0x89: parse("ADC C", 4, 0, All),
+	0x8a: parse("ADC D", 4, 0, All),
+	0x8b: parse("ADC E", 4, 0, All),
+	0x8c: parse("ADC H", 4, 0, All),
+	0x8d: parse("ADC L", 4, 0, All),
+	0x8e: parse("ADC M", 7, 0, All),
+	0x8f: parse("ADC A", 4, 0, All),
+
+	0x90: parse("SUB B", 4, 0, All),
+	0x91: parse("SUB C", 4, 0, All),
+	0x92: parse("SUB D", 4, 0, All),
+	0x93: parse("SUB E", 4, 0, All),
+	0x94: parse("SUB H", 4, 0, All),
+	0x95: parse("SUB L", 4, 0, All),
+	0x96: parse("SUB M", 7, 0, All),
+	0x97: parse("SUB A", 4, 0, All),
+	0x98: parse("SBB B", 4, 0, All),
+	0x99: parse("SBB C", 4, 0, All),
+	0x9a: parse("SBB D", 4, 0, All),
+	0x9b: parse("SBB E", 4, 0, All),
+	0x9c: parse("SBB H", 4, 0, All),
+	0x9d: parse("SBB L", 4, 0, All),
+	0x9e: parse("SBB M", 7, 0, All),
+	0x9f: parse("SBB A", 4, 0, All),
+
+	0xa0: parse("ANA B", 4, 0, All),
+	0xa1: parse("ANA C", 4, 0, All),
+	0xa2: parse("ANA D", 4, 0, All),
+	0xa3: parse("ANA E", 4, 0, All),
+	0xa4: parse("ANA H", 4, 0, All),
+	0xa5: parse("ANA L", 4, 0, All),
+	0xa6: parse("ANA M", 7, 0, All),
+	0xa7: parse("ANA A", 4, 0, All),
+	0xa8: parse("XRA B", 4, 0, All),
+	0xa9: parse("XRA C", 4, 0, All),
+	0xaa: parse("XRA D", 4, 0, All),
+	0xab: parse("XRA E", 4, 0, All),
+	0xac: parse("XRA H", 4, 0, All),
+	0xad: parse("XRA L", 4, 0, All),
+	0xae: parse("XRA M", 7, 0, All),
+	0xaf: parse("XRA A", 4, 0, All),
+
+	0xb0: parse("ORA B", 4, 0, All),
+	0xb1: parse("ORA C", 4, 0, All),
+	0xb2: parse("ORA D", 4, 0, All),
+	0xb3: parse("ORA E", 4, 0, All),
+	0xb4: parse("ORA H", 4, 0, All),
+	0xb5: parse("ORA L", 4, 0, All),
+	0xb6: parse("ORA M", 7, 0, All),
+	0xb7: parse("ORA A", 4, 0, All),
+	0xb8: parse("CMP B", 4, 0, All),
+	0xb9: parse("CMP C", 4, 0, All),
+	0xba: parse("CMP D", 4, 0, All),
+	0xbb: parse("CMP E", 4, 0, All),
+	0xbc: parse("CMP H", 4, 0, All),
+	0xbd: parse("CMP L", 4, 0, All),
+	0xbe: parse("CMP M", 7, 0, All),
+	0xbf: parse("CMP A", 4, 0, All),
+
+	0xc0: parse("RNZ", 5, 11, None),
+	0xc1: parse("POP B", 10, 0, None),
+	0xc2: parse("JNZ a16", 10, 10, None),
+	0xc3: parse("JMP a16", 10, 0, None),
+	0xc4: parse("CNZ a16", 11, 17, None),
+	0xc5: parse("PUSH B", 11, 0, None),
+	0xc6: parse("ADI d8", 7, 0, All),
+	0xc7: parse("RST 0", 11, 0, None),
+	0xc8: parse("RZ", 5, 11, None),
+	0xc9: parse("RET", 10, 0, None),
+	0xca: parse("JZ a16", 10, 10, None),
+	0xcb: parse("*JMP a16", 10, 0, None),
+	0xcc: parse("CZ a16", 11, 17, None),
+	0xcd: parse("CALL a16", 17, 0, None),
+	0xce: parse("ACI d8", 7, 0, All),
+	0xcf: parse("RST 1", 11, 0, None),
+
+	0xd0: parse("RNC", 5, 11, None),
+	0xd1: parse("POP D", 10, 0, None),
+	0xd2: parse("JNC a16", 10, 10, None),
+	0xd3: parse("OUT p8", 10, 0, None),
+	0xd4: parse("CNC a16", 11, 17, None),
+	0xd5: parse("PUSH D", 11, 0, None),
+	0xd6: parse("SUI d8", 7, 0, All),
+	0xd7: parse("RST 2", 11, 0, None),
+	0xd8: parse("RC", 5, 11, None),
+	0xd9: parse("*RET", 10, 0, None),
+	0xda: parse("JC a16", 10, 10, None),
+	0xdb: parse("IN p8", 10, 0, None),
+	0xdc: parse("CC a16", 11, 17, None),
+	0xdd: parse("*CALL a16", 17, 0, None),
+	0xde: parse("SBI d8", 7, 0, All),
+	0xdf: parse("RST 3", 11, 0, None),
+
+	0xe0: parse("RPO", 5, 11, None),
+	0xe1: parse("POP H", 10, 0, None),
+	0xe2: parse("JPO a16", 10, 10, None),
+	0xe3: parse("XTHL", 18, 0, None),
+	0xe4: parse("CPO a16", 11, 17, None),
+	0xe5: parse("PUSH H", 11, 0, None),
+	0xe6: parse("ANI d8", 7, 0, All),
+	0xe7: parse("RST 4", 11, 0, None),
+	0xe8: parse("RPE", 5, 11, None),
+	0xe9: parse("PCHL", 5, 0, None),
+	0xea: parse("JPE a16", 10, 10, None),
+	0xeb: parse("XCHG", 5, 0, None),
+	0xec: parse("CPE a16", 11, 17, None),
+	0xed: parse("*CALL a16", 17, 0, None),
+	0xee: parse("XRI d8", 7, 0, All),
+	0xef: parse("RST 5", 11, 0, None),
+
+	0xf0: parse("RP", 5, 11, None),
+	0xf1: parse("POP PSW", 10, 0, All),
+	0xf2: parse("JP a16", 10, 10, None),
+	0xf3: parse("DI", 4, 0, None),
+	0xf4: parse("CP a16", 11, 17, None),
+	0xf5: parse("PUSH PSW", 11, 0, None),
+	0xf6: parse("ORI d8", 7, 0, All),
+	0xf7: parse("RST 6", 11, 0, None),
+	0xf8: parse("RM", 5, 11, None),
+	0xf9: parse("SPHL", 5, 0, None),
+	0xfa: parse("JM a16", 10, 10, None),
+	0xfb: parse("EI", 4, 0, None),
+	0xfc: parse("CM a16", 11, 17, None),
+	0xfd: parse("*CALL a16", 17, 0, None),
+	0xfe: parse("CPI d8", 7, 0, All),
+	0xff: parse("RST 7", 11, 0, None),
+}
+
+// The 8085 instruction set: RIM, SIM, 8085 timings and the undocumented 8085
+// instructions.
+var Intel8085 = [256]Opcode{
+	0x00: parse("NOP", 4, 0, None),
+	0x01: parse("LXI B,d16", 10, 0, None),
+	0x02: parse("STAX B", 7, 0, None),
+	0x03: parse("INX B", 6, 0, None),
+	0x04: parse("INR B", 4, 0, AllButCarry),
+	0x05: parse("DCR B", 4, 0, AllButCarry),
+	0x06: parse("MVI B,d8", 7, 0, None),
+	0x07: parse("RLC", 4, 0, Carry),
+	0x08: parse("*DSUB", 10, 0, All),
+	0x09: parse("DAD B", 10, 0, Carry),
+	0x0a: parse("LDAX B", 7, 0, None),
+	0x0b: parse("DCX B", 6, 0, None),
+	0x0c: parse("INR C", 4, 0, AllButCarry),
+	0x0d: parse("DCR C", 4, 0, AllButCarry),
+	0x0e: parse("MVI C,d8", 7, 0, None),
+	0x0f: parse("RRC", 4, 0, Carry),
+
+	0x10: parse("*ARHL", 7, 0, Carry),
+	0x11: parse("LXI D,d16", 10, 0, None),
+	0x12: parse("STAX D", 7, 0, None),
+	0x13: parse("INX D", 6, 0, None),
+	0x14: parse("INR D", 4, 0, AllButCarry),
+	0x15: parse("DCR D", 4, 0, AllButCarry),
+	0x16: parse("MVI D,d8", 7, 0, None),
+	0x17: parse("RAL", 4, 0, Carry),
+	0x18: parse("*RDEL", 10, 0, Carry),
+	0x19: parse("DAD D", 10, 0, Carry),
+	0x1a: parse("LDAX D", 7, 0, None),
+	0x1b: parse("DCX D", 6, 0, None),
+	0x1c: parse("INR E", 4, 0, AllButCarry),
+	0x1d: parse("DCR E", 4, 0, AllButCarry),
+	0x1e: parse("MVI E,d8", 7, 0, None),
+	0x1f: parse("RAR", 4, 0, Carry),
+
+	0x20: parse("RIM", 4, 0, None),
+	0x21: parse("LXI H,d16", 10, 0, None),
+	0x22: parse("SHLD a16", 16, 0, None),
+	0x23: parse("INX H", 6, 0, None),
+	0x24: parse("INR H", 4, 0, AllButCarry),
+	0x25: parse("DCR H", 4, 0, AllButCarry),
+	0x26: parse("MVI H,d8", 7, 0, None),
+	0x27: parse("DAA", 4, 0, All),
+	0x28: parse("*LDHI d8", 10, 0, None),
+	0x29: parse("DAD H", 10, 0, Carry),
+	0x2a: parse("LHLD a16", 16, 0, None),
+	0x2b: parse("DCX H", 6, 0, None),
+	0x2c: parse("INR L", 4, 0, AllButCarry),
+	0x2d: parse("DCR L", 4, 0, AllButCarry),
+	0x2e: parse("MVI L,d8", 7, 0, None),
+	0x2f: parse("CMA", 4, 0, None),
+
+	0x30: parse("SIM", 4, 0, None),
+	0x31: parse("LXI SP,d16", 10, 0, None),
+	0x32: parse("STA a16", 13, 0, None),
+	0x33: parse("INX SP", 6, 0, None),
+	0x34: parse("INR M", 10, 0, AllButCarry),
+	0x35: parse("DCR M", 10, 0, AllButCarry),
+	0x36: parse("MVI M,d8", 10, 0, None),
+	0x37: parse("STC", 4, 0, Carry),
+	0x38: parse("*LDSI d8", 10, 0, None),
+	0x39: parse("DAD SP", 10, 0, Carry),
+	0x3a: parse("LDA a16", 13, 0, None),
+	0x3b: parse("DCX SP", 6, 0, None),
+	0x3c: parse("INR A", 4, 0, AllButCarry),
+	0x3d: parse("DCR A", 4, 0, AllButCarry),
+	0x3e: parse("MVI A,d8", 7, 0, None),
+	0x3f: parse("CMC", 4, 0, Carry),
+
+	0x40: parse("MOV B,B", 4, 0, None),
+	0x41: parse("MOV B,C", 4, 0, None),
+	0x42: parse("MOV B,D", 4, 0, None),
+	0x43: parse("MOV B,E", 4, 0, None),
+	0x44: parse("MOV B,H", 4, 0, None),
+	0x45: parse("MOV B,L", 4, 0, None),
+	0x46: parse("MOV B,M", 7, 0, None),
+	0x47: parse("MOV B,A", 4, 0, None),
+	0x48: parse("MOV C,B", 4, 0, None),
+	0x49: parse("MOV C,C", 4, 0, None),
+	0x4a: parse("MOV C,D", 4, 0, None),
+	0x4b: parse("MOV C,E", 4, 0, None),
+	0x4c: parse("MOV C,H", 4, 0, None),
+	0x4d: parse("MOV C,L", 4, 0, None),
+	0x4e: parse("MOV C,M", 7, 0, None),
+	0x4f: parse("MOV C,A", 4, 0, None),
+
+	0x50: parse("MOV D,B", 4, 0, None),
+	0x51: parse("MOV D,C", 4, 0, None),
+	0x52: parse("MOV D,D", 4, 0, None),
+	0x53: parse("MOV D,E", 4, 0, None),
+	0x54: parse("MOV D,H", 4, 0, None),
+	0x55: parse("MOV D,L", 4, 0, None),
+	0x56: parse("MOV D,M", 7, 0, None),
+	0x57: parse("MOV D,A", 4, 0, None),
+	0x58: parse("MOV E,B", 4, 0, None),
+	0x59: parse("MOV E,C", 4, 0, None),
+	0x5a: parse("MOV E,D", 4, 0, None),
+	0x5b: parse("MOV E,E", 4, 0, None),
+	0x5c: parse("MOV E,H", 4, 0, None),
+	0x5d: parse("MOV E,L", 4, 0, None),
+	0x5e: parse("MOV E,M", 7, 0, None),
+	0x5f: parse("MOV E,A", 4, 0, None),
+
+	0x60: parse("MOV H,B", 4, 0, None),
+	0x61: parse("MOV H,C", 4, 0, None),
+	0x62: parse("MOV H,D", 4, 0, None),
+	0x63: parse("MOV H,E", 4, 0, None),
+	0x64: parse("MOV H,H", 4, 0, None),
+	0x65: parse("MOV H,L", 4, 0, None),
+	0x66: parse("MOV H,M", 7, 0, None),
+	0x67: parse("MOV H,A", 4, 0, None),
+	0x68: parse("MOV L,B", 4, 0, None),
+	0x69: parse("MOV L,C", 4, 0, None),
+	0x6a: parse("MOV L,D", 4, 0, None),
+	0x6b: parse("MOV L,E", 4, 0, None),
+	0x6c: parse("MOV L,H", 4, 0, None),
+	0x6d: parse("MOV L,L", 4, 0, None),
+	0x6e: parse("MOV L,M", 7, 0, None),
+	0x6f: parse("MOV L,A", 4, 0, None),
+
+	0x70: parse("MOV M,B", 7, 0, None),
+	0x71: parse("MOV M,C", 7, 0, None),
+	0x72: parse("MOV M,D", 7, 0, None),
+	0x73: parse("MOV M,E", 7, 0, None),
+	0x74: parse("MOV M,H", 7, 0, None),
+	0x75: parse("MOV M,L", 7, 0, None),
+	0x76: parse("HLT", 5, 0, None),
+	0x77: parse("MOV M,A", 7, 0, None),
+	0x78: parse("MOV A,B", 4, 0, None),
+	0x79: parse("MOV A,C", 4, 0, None),
+	0x7a: parse("MOV A,D", 4, 0, None),
+	0x7b: parse("MOV A,E", 4, 0, None),
+	0x7c: parse("MOV A,H", 4, 0, None),
+	0x7d: parse("MOV A,L", 4, 0, None),
+	0x7e: parse("MOV A,M", 7, 0, None),
+	0x7f: parse("MOV A,A", 4, 0, None),
+
+	0x80: parse("ADD B", 4, 0, All),
+	0x81: parse("ADD C", 4, 0, All),
+	0x82: parse("ADD D", 4, 0, All),
+	0x83: parse("ADD E", 4, 0, All),
+	0x84: parse("ADD H", 4, 0, All),
+	0x85: parse("ADD L", 4, 0, All),
+	0x86: parse("ADD M", 7, 0, All),
+	0x87: parse("ADD A", 4, 0, All),
+	0x88: parse("ADC B", 4, 0, All),
+	0x89: parse("ADC C", 4, 0, All),
+	0x8a: parse("ADC D", 4, 0, All),
+	0x8b: parse("ADC E", 4, 0, All),
+	0x8c: parse("ADC H", 4, 0, All),
+	0x8d: parse("ADC L", 4, 0, All),
+	0x8e: parse("ADC M", 7, 0, All),
+	0x8f: parse("ADC A", 4, 0, All),
+
+	0x90: parse("SUB B", 4, 0, All),
+	0x91: parse("SUB C", 4, 0, All),
+	0x92: parse("SUB D", 4, 0, All),
+	0x93: parse("SUB E", 4, 0, All),
+	0x94: parse("SUB H", 4, 0, All),
+	0x95: parse("SUB L", 4, 0, All),
+	0x96: parse("SUB M", 7, 0, All),
+	0x97: parse("SUB A", 4, 0, All),
+	0x98: parse("SBB B", 4, 0, All),
+	0x99: parse("SBB C", 4, 0, All),
+	0x9a: parse("SBB D", 4, 0, All),
+	0x9b: parse("SBB E", 4, 0, All),
+	0x9c: parse("SBB H", 4, 0, All),
+	0x9d: parse("SBB L", 4, 0, All),
+	0x9e: parse("SBB M", 7, 0, All),
+	0x9f: parse("SBB A", 4, 0, All),
+
+	0xa0: parse("ANA B", 4, 0, All),
+	0xa1: parse("ANA C", 4, 0, All),
+	0xa2: parse("ANA D", 4, 0, All),
+	0xa3: parse("ANA E", 4, 0, All),
+	0xa4: parse("ANA H", 4, 0, All),
+	0xa5: parse("ANA L", 4, 0, All),
+	0xa6: parse("ANA M", 7, 0, All),
+	0xa7: parse("ANA A", 4, 0, All),
+	0xa8: parse("XRA B", 4, 0, All),
+	0xa9: parse("XRA C", 4, 0, All),
+	0xaa: parse("XRA D", 4, 0, All),
+	0xab: parse("XRA E", 4, 0, All),
+	0xac: parse("XRA H", 4, 0, All),
+	0xad: parse("XRA L", 4, 0, All),
+	0xae: parse("XRA M", 7, 0, All),
+	0xaf: parse("XRA A", 4, 0, All),
+
+	0xb0: parse("ORA B", 4, 0, All),
+	0xb1: parse("ORA C", 4, 0, All),
+	0xb2: parse("ORA D", 4, 0, All),
+	0xb3: parse("ORA E", 4, 0, All),
+	0xb4: parse("ORA H", 4, 0, All),
+	0xb5: parse("ORA L", 4, 0, All),
+	0xb6: parse("ORA M", 7, 0, All),
+	0xb7: parse("ORA A", 4, 0, All),
+	0xb8: parse("CMP B", 4, 0, All),
+	0xb9: parse("CMP C", 4, 0, All),
+	0xba: parse("CMP D", 4, 0, All),
+	0xbb: parse("CMP E", 4, 0, All),
+	0xbc: parse("CMP H", 4, 0, All),
+	0xbd: parse("CMP L", 4, 0, All),
+	0xbe: parse("CMP M", 7, 0, All),
+	0xbf: parse("CMP A", 4, 0, All),
+
+	0xc0: parse("RNZ", 6, 12, None),
+	0xc1: parse("POP B", 10, 0, None),
+	0xc2: parse("JNZ a16", 7, 10, None),
+	0xc3: parse("JMP a16", 10, 0, None),
+	0xc4: parse("CNZ a16", 9, 18, None),
+	0xc5: parse("PUSH B", 12, 0, None),
+	0xc6: parse("ADI d8", 7, 0, All),
+	0xc7: parse("RST 0", 12, 0, None),
+	0xc8: parse("RZ", 6, 12, None),
+	0xc9: parse("RET", 10, 0, None),
+	0xca: parse("JZ a16", 7, 10, None),
+	0xcb: parse("*RSTV", 6, 12, None),
+	0xcc: parse("CZ a16", 9, 18, None),
+	0xcd: parse("CALL a16", 18, 0, None),
+	0xce: parse("ACI d8", 7, 0, All),
+	0xcf: parse("RST 1", 12, 0, None),
+
+	0xd0: parse("RNC", 6, 12, None),
+	0xd1: parse("POP D", 10, 0, None),
+	0xd2: parse("JNC a16", 7, 10, None),
+	0xd3: parse("OUT p8", 10, 0, None),
+	0xd4: parse("CNC a16", 9, 18, None),
+	0xd5: parse("PUSH D", 12, 0, None),
+	0xd6: parse("SUI d8", 7, 0, All),
+	0xd7: parse("RST 2", 12, 0, None),
+	0xd8: parse("RC", 6, 12, None),
+	0xd9: parse("*SHLX", 10, 0, None),
+	0xda: parse("JC a16", 7, 10, None),
+	0xdb: parse("IN p8", 10, 0, None),
+	0xdc: parse("CC a16", 9, 18, None),
+	0xdd: parse("*JNK a16", 7, 10, None),
+	0xde: parse("SBI d8", 7, 0, All),
+	0xdf: parse("RST 3", 12, 0, None),
+
+	0xe0: parse("RPO", 6, 12, None),
+	0xe1: parse("POP H", 10, 0, None),
+	0xe2: parse("JPO a16", 7, 10, None),
+	0xe3: parse("XTHL", 16, 0, None),
+	0xe4: parse("CPO a16", 9, 18, None),
+	0xe5: parse("PUSH H", 12, 0, None),
+	0xe6: parse("ANI d8", 7, 0, All),
+	0xe7: parse("RST 4", 12, 0, None),
+	0xe8: parse("RPE", 6, 12, None),
+	0xe9: parse("PCHL", 6, 0, None),
+	0xea: parse("JPE a16", 7, 10, None),
+	0xeb: parse("XCHG", 4, 0, None),
+	0xec: parse("CPE a16", 9, 18, None),
+	0xed: parse("*LHLX", 10, 0, None),
+	0xee: parse("XRI d8", 7, 0, All),
+	0xef: parse("RST 5", 12, 0, None),
+
+	0xf0: parse("RP", 6, 12, None),
+	0xf1: parse("POP PSW", 10, 0, All),
+	0xf2: parse("JP a16", 7, 10, None),
+	0xf3: parse("DI", 4, 0, None),
+	0xf4: parse("CP a16", 9, 18, None),
+	0xf5: parse("PUSH PSW", 12, 0, None),
+	0xf6: parse("ORI d8", 7, 0, All),
+	0xf7: parse("RST 6", 12, 0, None),
+	0xf8: parse("RM", 6, 12, None),
+	0xf9: parse("SPHL", 6, 0, None),
+	0xfa: parse("JM a16", 7, 10, None),
+	0xfb: parse("EI", 4, 0, None),
+	0xfc: parse("CM a16", 9, 18, None),
+	0xfd: parse("*JK a16", 7, 10, None),
+	0xfe: parse("CPI d8", 7, 0, All),
+	0xff: parse("RST 7", 12, 0, None),
+}
@@ -0,0 +1,281 @@
+package z80
+
+// alu runs ADD, ADC, SUB, SBC, AND, XOR, OR or CP against A.
+func (cpu *Z80) alu(operation byte, value byte) {
+	carry := cpu.f & Carry
+
+	switch operation {
+	case 0:
+		cpu.add8(value, 0)
+	case 1:
+		cpu.add8(value, carry)
+	case 2:
+		cpu.a = cpu.sub8(value, 0)
+	case 3:
+		cpu.a = cpu.sub8(value, carry)
+	case 4:
+		cpu.a &= value
+		cpu.f = sz53pTable[cpu.a] | HalfCarry
+	case 5:
+		cpu.a ^= value
+		cpu.f = sz53pTable[cpu.a]
+	case 6:
+		cpu.a |= value
+		cpu.f = sz53pTable[cpu.a]
+	case 7:
+		cpu.sub8(value, 0)
+		// CP takes the undocumented bits from the operand
+		cpu.f = cpu.f&^undocumentedFlags | value&undocumentedFlags
+	}
+}
+
+func (cpu *Z80) add8(value byte, carry byte) {
+	sum := uint16(cpu.a) + uint16(value) + uint16(carry)
+	result := byte(sum)
+
+	f := sz53Table[result] | (cpu.a^value^result)&HalfCarry
+	if sum > 0xFF {
+		f |= Carry
+	}
+	if (cpu.a^value)&0x80 == 0 && (cpu.a^result)&0x80 != 0 {
+		f |= ParityOverflow
+	}
+
+	cpu.a = result
+	cpu.f = f
+}
+
+// sub8 computes A - value - carry and sets flags, leaving A unchanged.
+func (cpu *Z80) sub8(value byte, carry byte) byte {
+	difference := uint16(cpu.a) - uint16(value) - uint16(carry)
+	result := byte(difference)
+
+	f := Subtract | sz53Table[result] | (cpu.a^value^result)&HalfCarry
+	if difference > 0xFF {
+		f |= Carry
+	}
+	if (cpu.a^value)&0x80 != 0 && (cpu.a^result)&0x80 != 0 {
+		f |= ParityOverflow
+	}
+
+	cpu.f = f
+	return result
+}
+
+func (cpu *Z80) inc(value byte) byte {
+	result := value + 1
+
+	cpu.f = cpu.f&Carry | sz53Table[result]
+	if value&0x0F == 0x0F {
+		cpu.f |= HalfCarry
+	}
+	if value == 0x7F {
+		cpu.f |= ParityOverflow
+	}
+
+	return result
+}
+
+func (cpu *Z80) dec(value byte) byte {
+	result := value - 1
+
+	cpu.f = cpu.f&Carry | Subtract | sz53Table[result]
+	if value&0x0F == 0 {
+		cpu.f |= HalfCarry
+	}
+	if value == 0x80 {
+		cpu.f |= ParityOverflow
+	}
+
+	return result
+}
+
+// add16 is ADD HL,rp, which leaves S, Z and P/V alone.
+func (cpu *Z80) add16(a uint16, b uint16) uint16 {
+	sum := uint32(a) + uint32(b)
+	result := uint16(sum)
+
+	cpu.f = cpu.f&(Sign|Zero|ParityOverflow) | byte(result>>8)&undocumentedFlags
+	if (a^b^result)&0x1000 != 0 {
+		cpu.f |= HalfCarry
+	}
+	if sum > 0xFFFF {
+		cpu.f |= Carry
+	}
+
+	return result
+}
+
+func (cpu *Z80) adc16(a uint16, b uint16) uint16 {
+	sum := uint32(a) + uint32(b) + uint32(cpu.f&Carry)
+	result := uint16(sum)
+
+	cpu.f = cpu.flags16(result)
+	if (a^b^result)&0x1000 != 0 {
+		cpu.f |= HalfCarry
+	}
+	if (a^b)&0x8000 == 0 && (a^result)&0x8000 != 0 {
+		cpu.f |= ParityOverflow
+	}
+	if sum > 0xFFFF {
+		cpu.f |= Carry
+	}
+
+	return result
+}
+
+func (cpu *Z80) sbc16(a uint16, b uint16) uint16 {
+	difference := uint32(a) - uint32(b) - uint32(cpu.f&Carry)
+	result := uint16(difference)
+
+	cpu.f = Subtract | cpu.flags16(result)
+	if (a^b^result)&0x1000 != 0 {
+		cpu.f |= HalfCarry
+	}
+	if (a^b)&0x8000 != 0 && (a^result)&0x8000 != 0 {
+		cpu.f |= ParityOverflow
+	}
+	if difference > 0xFFFF {
+		cpu.f |= Carry
+	}
+
+	return result
+}
+
+// flags16 returns S, Z and the undocumented bits of a 16 bit result.
+func (cpu *Z80) flags16(result uint16) byte {
+	f := sz53Table[byte(result>>8)] &^ Zero
+	if result == 0 {
+		f |= Zero
+	}
+
+	return f
+}
+
+// accumulatorOperation runs RLCA, RRCA, RLA, RRA, DAA, CPL, SCF or CCF.
+func (cpu *Z80) accumulatorOperation(operation byte) {
+	keep := cpu.f & (Sign | Zero | ParityOverflow)
+
+	switch operation {
+	case 0: // RLCA
+		cpu.a = cpu.a<<1 | cpu.a>>7
+		cpu.f = keep | cpu.a&undocumentedFlags | cpu.a&Carry
+	case 1: // RRCA
+		carry := cpu.a & 1
+		cpu.a = cpu.a>>1 | cpu.a<<7
+		cpu.f = keep | cpu.a&undocumentedFlags | carry
+	case 2: // RLA
+		carry := cpu.a >> 7
+		cpu.a = cpu.a<<1 | cpu.f&Carry
+		cpu.f = keep | cpu.a&undocumentedFlags | carry
+	case 3: // RRA
+		carry := cpu.a & 1
+		cpu.a = cpu.a>>1 | (cpu.f&Carry)<<7
+		cpu.f = keep | cpu.a&undocumentedFlags | carry
+	case 4:
+		cpu.daa()
+	case 5: // CPL
+		cpu.a = ^cpu.a
+		cpu.f = keep | cpu.f&Carry | HalfCarry | Subtract | cpu.a&undocumentedFlags
+	case 6: // SCF
+		cpu.f = keep | cpu.a&undocumentedFlags | Carry
+	case 7: // CCF
+		f := keep | cpu.a&undocumentedFlags
+		if cpu.f&Carry != 0 {
+			f |= HalfCarry
+		} else {
+			f |= Carry
+		}
+		cpu.f = f
+	}
+}
+
+// daa adjusts A to BCD after an addition or a subtraction, as told by N.
+func (cpu *Z80) daa() {
+	correction := byte(0)
+	carry := cpu.f & Carry
+	halfCarry := cpu.f&HalfCarry != 0
+	subtract := cpu.f&Subtract != 0
+
+	if halfCarry || cpu.a&0x0F > 9 {
+		correction |= 0x06
+	}
+	if carry != 0 || cpu.a > 0x99 {
+		correction |= 0x60
+		carry = Carry
+	}
+
+	var h byte
+	if subtract {
+		if halfCarry && cpu.a&0x0F < 6 {
+			h = HalfCarry
+		}
+		cpu.a -= correction
+	} else {
+		if cpu.a&0x0F > 9 {
+			h = HalfCarry
+		}
+		cpu.a += correction
+	}
+
+	cpu.f = sz53pTable[cpu.a] | h | cpu.f&Subtract | carry
+}
+
+// bitOperation runs the rotate/shift (x = 0), RES (x = 2) and SET (x = 3)
+// groups of the CB prefix.
+func (cpu *Z80) bitOperation(x, y, value byte) byte {
+	switch x {
+	case 0:
+		return cpu.rotate(y, value)
+	case 2:
+		return value &^ (1 << y)
+	}
+
+	return value | 1<<y
+}
+
+// rotate runs RLC, RRC, RL, RR, SLA, SRA, SLL (undocumented) or SRL.
+func (cpu *Z80) rotate(operation byte, value byte) byte {
+	var result, carry byte
+
+	switch operation {
+	case 0: // RLC
+		carry = value >> 7
+		result = value<<1 | carry
+	case 1: // RRC
+		carry = value & 1
+		result = value>>1 | carry<<7
+	case 2: // RL
+		carry = value >> 7
+		result = value<<1 | cpu.f&Carry
+	case 3: // RR
+		carry = value & 1
+		result = value>>1 | (cpu.f&Carry)<<7
+	case 4: // SLA
+		carry = value >> 7
+		result = value << 1
+	case 5: // SRA
+		carry = value & 1
+		result = value>>1 | value&0x80
+	case 6: // SLL
+		carry = value >> 7
+		result = value<<1 | 1
+	case 7: // SRL
+		carry = value & 1
+		result = value >> 1
+	}
+
+	cpu.f = sz53pTable[result] | carry
+	return result
+}
+
+func (cpu *Z80) bit(n byte, value byte) {
+	cpu.f = cpu.f&Carry | HalfCarry | value&undocumentedFlags
+
+	if value&(1<<n) == 0 {
+		cpu.f |= Zero | ParityOverflow
+	}
+	if n == 7 && value&0x80 != 0 {
+		cpu.f |= Sign
+	}
+}
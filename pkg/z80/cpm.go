@@ -0,0 +1,39 @@
+package z80
+
+import "io"
+
+// RunCPM runs a CP/M program, such as ZEXDOC, loaded at $0100 until it warm
+// boots by jumping to $0000. Characters printed with BDOS function 2 (E) and
+// strings printed with function 9 (DE, up to '$') are written to out.
+func RunCPM(cpu *Z80, program []byte, out io.Writer) {
+	cpu.LoadProgram(program, 0x100)
+	cpu.SetPC(0x100)
+
+	// $0000 halts on warm boot, BDOS calls at $0005 are an OUT the listener
+	// carries out followed by RET, and the program sets its stack from the
+	// BDOS entry address at $0006
+	cpu.WriteIntoMemory(0x0000, 0x76) // HALT
+	cpu.WriteIntoMemory(0x0005, 0xD3) // OUT (n),A
+	cpu.WriteIntoMemory(0x0006, 0x00)
+	cpu.WriteIntoMemory(0x0007, 0xC9) // RET
+
+	cpu.SetOutputListener(func(cpu *Z80) {
+		registers := cpu.GetRegisters()
+		switch registers["C"] {
+		case 0x02:
+			out.Write([]byte{registers["E"]})
+		case 0x09:
+			addr := uint16(registers["D"])<<8 | uint16(registers["E"])
+			var s []byte
+			for c := cpu.ReadFromMemory(addr); c != '$'; c = cpu.ReadFromMemory(addr) {
+				s = append(s, c)
+				addr++
+			}
+			out.Write(s)
+		}
+	})
+
+	for !cpu.IsHalted() {
+		cpu.Run()
+	}
+}
@@ -0,0 +1,57 @@
+/**
+ * 7 	6 	5 	4 	3 	2 	1 	0
+ * S 	Z 	Y 	H 	X 	P/V	N 	C
+ *
+ * S   - Sign Flag
+ * Z   - Zero Flag
+ * Y   - Undocumented, copy of bit 5 of the result
+ * H   - Half Carry Flag
+ * X   - Undocumented, copy of bit 3 of the result
+ * P/V - Parity Flag for logical operations, Overflow Flag for arithmetic ones
+ * N   - Add/Subtract Flag, set when the last operation was a subtraction
+ * C   - Carry Flag
+ */
+
+package z80
+
+import "math/bits"
+
+type Flag = byte
+
+const (
+	Sign           Flag = 1 << 7
+	Zero           Flag = 1 << 6
+	Flag5          Flag = 1 << 5
+	HalfCarry      Flag = 1 << 4
+	Flag3          Flag = 1 << 3
+	ParityOverflow Flag = 1 << 2
+	Subtract       Flag = 1 << 1
+	Carry          Flag = 1 << 0
+)
+
+const undocumentedFlags = Flag5 | Flag3
+
+var (
+	// S, Z and the undocumented bits for every result
+	sz53Table [256]byte
+	// sz53Table plus parity
+	sz53pTable [256]byte
+)
+
+func init() {
+	for i := 0; i < 256; i++ {
+		v := byte(i)
+		f := v & (Sign | undocumentedFlags)
+		if v == 0 {
+			f |= Zero
+		}
+
+		sz53Table[i] = f
+
+		if bits.OnesCount8(v)%2 == 0 {
+			f |= ParityOverflow
+		}
+
+		sz53pTable[i] = f
+	}
+}
@@ -0,0 +1,687 @@
+package z80
+
+// Opcodes are decoded from their bit fields instead of a table, as most of
+// the instruction set is laid out regularly:
+//
+//	7 6 | 5 4 3 | 2 1 0
+//	 x  |   y   |   z     with y = p (bits 5-4) and q (bit 3)
+//
+// See http://www.z80.info/decoding.htm
+
+func decode(opcode byte) (x, y, z, p, q byte) {
+	x = opcode >> 6
+	y = (opcode >> 3) & 0x7
+	z = opcode & 0x7
+	p = y >> 1
+	q = y & 0x1
+	return
+}
+
+var interruptModes = [8]byte{0, 0, 1, 2, 0, 0, 1, 2}
+
+func (cpu *Z80) execute(opcode byte) uint {
+	switch opcode {
+	case 0xCB:
+		return cpu.executeCB()
+	case 0xED:
+		return cpu.executeED()
+	case 0xDD:
+		return cpu.executeIndexed(&cpu.ix)
+	case 0xFD:
+		return cpu.executeIndexed(&cpu.iy)
+	}
+
+	return cpu.executeMain(opcode)
+}
+
+// executeIndexed runs an instruction prefixed by DD or FD, where HL, H and L
+// stand for IX/IY and their halves and (HL) becomes (IX+d)/(IY+d).
+func (cpu *Z80) executeIndexed(index *indexRegister) uint {
+	opcode := cpu.fetchOpcode()
+
+	switch opcode {
+	case 0xCB:
+		return cpu.executeIndexedCB(index)
+	case 0xDD, 0xED, 0xFD:
+		// The prefix has no effect and only costs its fetch
+		return 4 + cpu.execute(opcode)
+	}
+
+	cpu.index = index
+	cycles := cpu.executeMain(opcode)
+	cpu.index = nil
+
+	return 4 + cycles
+}
+
+// register returns register r of the opcode encoding: B, C, D, E, H, L, -, A.
+func (cpu *Z80) register(r byte) *byte {
+	switch r {
+	case 0:
+		return &cpu.b
+	case 1:
+		return &cpu.c
+	case 2:
+		return &cpu.d
+	case 3:
+		return &cpu.e
+	case 4:
+		return &cpu.h
+	case 5:
+		return &cpu.l
+	case 7:
+		return &cpu.a
+	}
+
+	return nil
+}
+
+// indexedRegister is register, except H and L stand for the halves of IX or
+// IY after a DD or FD prefix.
+func (cpu *Z80) indexedRegister(r byte) *byte {
+	if cpu.index != nil {
+		switch r {
+		case 4:
+			return &cpu.index.h
+		case 5:
+			return &cpu.index.l
+		}
+	}
+
+	return cpu.register(r)
+}
+
+func (cpu *Z80) hl() uint16 {
+	if cpu.index != nil {
+		return cpu.index.get()
+	}
+
+	return uint16(cpu.h)<<8 | uint16(cpu.l)
+}
+
+func (cpu *Z80) setHL(value uint16) {
+	if cpu.index != nil {
+		cpu.index.set(value)
+		return
+	}
+
+	cpu.h = byte(value >> 8)
+	cpu.l = byte(value)
+}
+
+// memoryOperand returns the address of the (HL) operand, fetching the
+// displacement of (IX+d)/(IY+d), and the extra cycles spent computing it.
+func (cpu *Z80) memoryOperand() (uint16, uint) {
+	if cpu.index == nil {
+		return cpu.hl(), 0
+	}
+
+	d := int8(cpu.fetch())
+	return cpu.index.get() + uint16(d), 8
+}
+
+// registerPair returns BC, DE, HL or SP.
+func (cpu *Z80) registerPair(p byte) uint16 {
+	switch p {
+	case 0:
+		return uint16(cpu.b)<<8 | uint16(cpu.c)
+	case 1:
+		return uint16(cpu.d)<<8 | uint16(cpu.e)
+	case 2:
+		return cpu.hl()
+	}
+
+	return cpu.sp
+}
+
+func (cpu *Z80) setRegisterPair(p byte, value uint16) {
+	switch p {
+	case 0:
+		cpu.b, cpu.c = byte(value>>8), byte(value)
+	case 1:
+		cpu.d, cpu.e = byte(value>>8), byte(value)
+	case 2:
+		cpu.setHL(value)
+	default:
+		cpu.sp = value
+	}
+}
+
+// stackRegisterPair is registerPair with AF in place of SP, as used by PUSH
+// and POP.
+func (cpu *Z80) stackRegisterPair(p byte) uint16 {
+	if p == 3 {
+		return uint16(cpu.a)<<8 | uint16(cpu.f)
+	}
+
+	return cpu.registerPair(p)
+}
+
+func (cpu *Z80) setStackRegisterPair(p byte, value uint16) {
+	if p == 3 {
+		cpu.a, cpu.f = byte(value>>8), byte(value)
+		return
+	}
+
+	cpu.setRegisterPair(p, value)
+}
+
+// condition evaluates NZ, Z, NC, C, PO, PE, P and M.
+func (cpu *Z80) condition(cc byte) bool {
+	var flag Flag
+	switch cc >> 1 {
+	case 0:
+		flag = Zero
+	case 1:
+		flag = Carry
+	case 2:
+		flag = ParityOverflow
+	case 3:
+		flag = Sign
+	}
+
+	return (cpu.f&flag != 0) == (cc&1 == 1)
+}
+
+func (cpu *Z80) jumpRelative(d int8) {
+	cpu.pc += uint16(d)
+}
+
+func (cpu *Z80) executeMain(opcode byte) uint {
+	x, y, z, p, q := decode(opcode)
+
+	switch x {
+	case 0:
+		switch z {
+		case 0:
+			switch y {
+			case 0: // NOP
+				return 4
+			case 1: // EX AF,AF'
+				cpu.a, cpu.alt.a = cpu.alt.a, cpu.a
+				cpu.f, cpu.alt.f = cpu.alt.f, cpu.f
+				return 4
+			case 2: // DJNZ d
+				d := int8(cpu.fetch())
+				cpu.b--
+				if cpu.b != 0 {
+					cpu.jumpRelative(d)
+					return 13
+				}
+				return 8
+			case 3: // JR d
+				cpu.jumpRelative(int8(cpu.fetch()))
+				return 12
+			default: // JR cc,d
+				d := int8(cpu.fetch())
+				if cpu.condition(y - 4) {
+					cpu.jumpRelative(d)
+					return 12
+				}
+				return 7
+			}
+		case 1:
+			if q == 0 { // LD rp,nn
+				cpu.setRegisterPair(p, cpu.fetchWord())
+				return 10
+			}
+
+			// ADD HL,rp
+			cpu.setHL(cpu.add16(cpu.hl(), cpu.registerPair(p)))
+			return 11
+		case 2:
+			switch y {
+			case 0: // LD (BC),A
+				cpu.writeByte(cpu.registerPair(0), cpu.a)
+				return 7
+			case 1: // LD (DE),A
+				cpu.writeByte(cpu.registerPair(1), cpu.a)
+				return 7
+			case 2: // LD (nn),HL
+				cpu.writeWord(cpu.fetchWord(), cpu.hl())
+				return 16
+			case 3: // LD (nn),A
+				cpu.writeByte(cpu.fetchWord(), cpu.a)
+				return 13
+			case 4: // LD A,(BC)
+				cpu.a = cpu.readByte(cpu.registerPair(0))
+				return 7
+			case 5: // LD A,(DE)
+				cpu.a = cpu.readByte(cpu.registerPair(1))
+				return 7
+			case 6: // LD HL,(nn)
+				cpu.setHL(cpu.readWord(cpu.fetchWord()))
+				return 16
+			default: // LD A,(nn)
+				cpu.a = cpu.readByte(cpu.fetchWord())
+				return 13
+			}
+		case 3:
+			if q == 0 { // INC rp
+				cpu.setRegisterPair(p, cpu.registerPair(p)+1)
+			} else { // DEC rp
+				cpu.setRegisterPair(p, cpu.registerPair(p)-1)
+			}
+			return 6
+		case 4: // INC r
+			if y == 6 {
+				addr, extra := cpu.memoryOperand()
+				cpu.writeByte(addr, cpu.inc(cpu.readByte(addr)))
+				return 11 + extra
+			}
+
+			r := cpu.indexedRegister(y)
+			*r = cpu.inc(*r)
+			return 4
+		case 5: // DEC r
+			if y == 6 {
+				addr, extra := cpu.memoryOperand()
+				cpu.writeByte(addr, cpu.dec(cpu.readByte(addr)))
+				return 11 + extra
+			}
+
+			r := cpu.indexedRegister(y)
+			*r = cpu.dec(*r)
+			return 4
+		case 6: // LD r,n
+			if y == 6 {
+				addr, extra := cpu.memoryOperand()
+				cpu.writeByte(addr, cpu.fetch())
+				if extra > 0 {
+					// n is fetched while the address is computed
+					return 15
+				}
+				return 10
+			}
+
+			*cpu.indexedRegister(y) = cpu.fetch()
+			return 7
+		default:
+			cpu.accumulatorOperation(y)
+			return 4
+		}
+	case 1:
+		if y == 6 && z == 6 { // HALT
+			cpu.halted = true
+			return 4
+		}
+
+		// LD r,r'
+		if y == 6 {
+			addr, extra := cpu.memoryOperand()
+			cpu.writeByte(addr, *cpu.register(z))
+			return 7 + extra
+		}
+
+		if z == 6 {
+			addr, extra := cpu.memoryOperand()
+			*cpu.register(y) = cpu.readByte(addr)
+			return 7 + extra
+		}
+
+		*cpu.indexedRegister(y) = *cpu.indexedRegister(z)
+		return 4
+	case 2: // ALU A,r
+		if z == 6 {
+			addr, extra := cpu.memoryOperand()
+			cpu.alu(y, cpu.readByte(addr))
+			return 7 + extra
+		}
+
+		cpu.alu(y, *cpu.indexedRegister(z))
+		return 4
+	}
+
+	switch z {
+	case 0: // RET cc
+		if cpu.condition(y) {
+			cpu.pc = cpu.pop()
+			return 11
+		}
+		return 5
+	case 1:
+		if q == 0 { // POP rp
+			cpu.setStackRegisterPair(p, cpu.pop())
+			return 10
+		}
+
+		switch p {
+		case 0: // RET
+			cpu.pc = cpu.pop()
+			return 10
+		case 1: // EXX
+			cpu.b, cpu.alt.b = cpu.alt.b, cpu.b
+			cpu.c, cpu.alt.c = cpu.alt.c, cpu.c
+			cpu.d, cpu.alt.d = cpu.alt.d, cpu.d
+			cpu.e, cpu.alt.e = cpu.alt.e, cpu.e
+			cpu.h, cpu.alt.h = cpu.alt.h, cpu.h
+			cpu.l, cpu.alt.l = cpu.alt.l, cpu.l
+			return 4
+		case 2: // JP (HL)
+			cpu.pc = cpu.hl()
+			return 4
+		default: // LD SP,HL
+			cpu.sp = cpu.hl()
+			return 6
+		}
+	case 2: // JP cc,nn
+		addr := cpu.fetchWord()
+		if cpu.condition(y) {
+			cpu.pc = addr
+		}
+		return 10
+	case 3:
+		switch y {
+		case 0: // JP nn
+			cpu.pc = cpu.fetchWord()
+			return 10
+		case 2: // OUT (n),A
+			port := cpu.fetch()
+			if cpu.onOutput != nil {
+				cpu.onOutput(cpu)
+			}
+
+			cpu.ioBus.Write(port, cpu.a)
+			return 11
+		case 3: // IN A,(n)
+			port := cpu.fetch()
+			if cpu.onInput != nil {
+				cpu.onInput(cpu)
+			}
+
+			cpu.a = cpu.ioBus.Read(port)
+			return 11
+		case 4: // EX (SP),HL
+			value := cpu.readWord(cpu.sp)
+			cpu.writeWord(cpu.sp, cpu.hl())
+			cpu.setHL(value)
+			return 19
+		case 5: // EX DE,HL, never affected by a prefix
+			cpu.d, cpu.h = cpu.h, cpu.d
+			cpu.e, cpu.l = cpu.l, cpu.e
+			return 4
+		case 6: // DI
+			cpu.iff1 = false
+			cpu.iff2 = false
+			return 4
+		case 7: // EI
+			cpu.iff1 = true
+			cpu.iff2 = true
+			cpu.eiDeferred = true
+			return 4
+		}
+	case 4: // CALL cc,nn
+		addr := cpu.fetchWord()
+		if cpu.condition(y) {
+			cpu.push(cpu.pc)
+			cpu.pc = addr
+			return 17
+		}
+		return 10
+	case 5:
+		if q == 0 { // PUSH rp
+			cpu.push(cpu.stackRegisterPair(p))
+			return 11
+		}
+
+		if p == 0 { // CALL nn
+			addr := cpu.fetchWord()
+			cpu.push(cpu.pc)
+			cpu.pc = addr
+			return 17
+		}
+	case 6: // ALU A,n
+		cpu.alu(y, cpu.fetch())
+		return 7
+	case 7: // RST
+		cpu.push(cpu.pc)
+		cpu.pc = uint16(y) * 8
+		return 11
+	}
+
+	// Prefixes are dispatched by execute
+	return 4
+}
+
+func (cpu *Z80) executeCB() uint {
+	x, y, z, _, _ := decode(cpu.fetchOpcode())
+
+	if z == 6 {
+		addr := cpu.hl()
+		value := cpu.readByte(addr)
+
+		if x == 1 {
+			cpu.bit(y, value)
+			return 12
+		}
+
+		cpu.writeByte(addr, cpu.bitOperation(x, y, value))
+		return 15
+	}
+
+	r := cpu.register(z)
+	if x == 1 {
+		cpu.bit(y, *r)
+		return 8
+	}
+
+	*r = cpu.bitOperation(x, y, *r)
+	return 8
+}
+
+// executeIndexedCB runs DD CB d op / FD CB d op. The operand is always
+// (IX+d)/(IY+d); other register encodings also get a copy of the result.
+func (cpu *Z80) executeIndexedCB(index *indexRegister) uint {
+	d := int8(cpu.fetch())
+	// The opcode is read as an operand, it does not refresh memory
+	x, y, z, _, _ := decode(cpu.fetch())
+
+	addr := index.get() + uint16(d)
+	value := cpu.readByte(addr)
+
+	if x == 1 {
+		cpu.bit(y, value)
+		cpu.f = cpu.f&^undocumentedFlags | byte(addr>>8)&undocumentedFlags
+		return 20
+	}
+
+	result := cpu.bitOperation(x, y, value)
+	cpu.writeByte(addr, result)
+
+	if z != 6 {
+		*cpu.register(z) = result
+	}
+
+	return 23
+}
+
+func (cpu *Z80) executeED() uint {
+	x, y, z, p, q := decode(cpu.fetchOpcode())
+
+	if x == 2 && z <= 3 && y >= 4 {
+		return cpu.blockInstruction(y, z)
+	}
+
+	if x != 1 {
+		// Undefined, runs as a long NOP
+		return 8
+	}
+
+	switch z {
+	case 0: // IN r,(C)
+		if cpu.onInput != nil {
+			cpu.onInput(cpu)
+		}
+
+		value := cpu.ioBus.Read(cpu.c)
+		if y != 6 {
+			*cpu.register(y) = value
+		}
+
+		cpu.f = cpu.f&Carry | sz53pTable[value]
+		return 12
+	case 1: // OUT (C),r
+		if cpu.onOutput != nil {
+			cpu.onOutput(cpu)
+		}
+
+		value := byte(0)
+		if y != 6 {
+			value = *cpu.register(y)
+		}
+
+		cpu.ioBus.Write(cpu.c, value)
+		return 12
+	case 2:
+		if q == 0 { // SBC HL,rp
+			cpu.setHL(cpu.sbc16(cpu.hl(), cpu.registerPair(p)))
+		} else { // ADC HL,rp
+			cpu.setHL(cpu.adc16(cpu.hl(), cpu.registerPair(p)))
+		}
+		return 15
+	case 3:
+		addr := cpu.fetchWord()
+		if q == 0 { // LD (nn),rp
+			cpu.writeWord(addr, cpu.registerPair(p))
+		} else { // LD rp,(nn)
+			cpu.setRegisterPair(p, cpu.readWord(addr))
+		}
+		return 20
+	case 4: // NEG
+		value := cpu.a
+		cpu.a = 0
+		cpu.a = cpu.sub8(value, 0)
+		return 8
+	case 5: // RETN, RETI
+		cpu.pc = cpu.pop()
+		cpu.iff1 = cpu.iff2
+		return 14
+	case 6: // IM
+		cpu.interruptMode = interruptModes[y]
+		return 8
+	}
+
+	switch y {
+	case 0: // LD I,A
+		cpu.i = cpu.a
+		return 9
+	case 1: // LD R,A
+		cpu.r = cpu.a
+		return 9
+	case 2: // LD A,I
+		cpu.a = cpu.i
+		cpu.loadInterruptRegisterFlags()
+		return 9
+	case 3: // LD A,R
+		cpu.a = cpu.r
+		cpu.loadInterruptRegisterFlags()
+		return 9
+	case 4: // RRD
+		addr := cpu.hl()
+		value := cpu.readByte(addr)
+		cpu.writeByte(addr, cpu.a<<4|value>>4)
+		cpu.a = cpu.a&0xF0 | value&0x0F
+		cpu.f = cpu.f&Carry | sz53pTable[cpu.a]
+		return 18
+	case 5: // RLD
+		addr := cpu.hl()
+		value := cpu.readByte(addr)
+		cpu.writeByte(addr, value<<4|cpu.a&0x0F)
+		cpu.a = cpu.a&0xF0 | value>>4
+		cpu.f = cpu.f&Carry | sz53pTable[cpu.a]
+		return 18
+	}
+
+	return 8
+}
+
+func (cpu *Z80) loadInterruptRegisterFlags() {
+	cpu.f = cpu.f&Carry | sz53Table[cpu.a]
+	if cpu.iff2 {
+		cpu.f |= ParityOverflow
+	}
+}
+
+// blockInstruction runs LDI, CPI, INI, OUTI, their decrementing variants
+// (y = 5) and repeating variants (y = 6, 7). Repeating instructions run one
+// iteration at a time by moving PC back onto themselves, so interrupts can
+// be accepted in between.
+func (cpu *Z80) blockInstruction(y, z byte) uint {
+	step := uint16(1)
+	if y&1 == 1 {
+		step = 0xFFFF
+	}
+
+	hl := cpu.hl()
+	bc := cpu.registerPair(0)
+	repeat := false
+
+	switch z {
+	case 0: // LDI
+		de := cpu.registerPair(1)
+		value := cpu.readByte(hl)
+		cpu.writeByte(de, value)
+
+		cpu.setRegisterPair(1, de+step)
+		bc--
+
+		n := value + cpu.a
+		cpu.f = cpu.f&(Sign|Zero|Carry) | n&Flag3 | (n<<4)&Flag5
+		if bc != 0 {
+			cpu.f |= ParityOverflow
+		}
+
+		repeat = bc != 0
+	case 1: // CPI
+		value := cpu.readByte(hl)
+		result := cpu.a - value
+		bc--
+
+		cpu.f = cpu.f&Carry | Subtract | sz53Table[result]&(Sign|Zero) | (cpu.a^value^result)&HalfCarry
+
+		n := result
+		if cpu.f&HalfCarry != 0 {
+			n--
+		}
+		cpu.f |= n&Flag3 | (n<<4)&Flag5
+		if bc != 0 {
+			cpu.f |= ParityOverflow
+		}
+
+		repeat = bc != 0 && result != 0
+	case 2: // INI
+		if cpu.onInput != nil {
+			cpu.onInput(cpu)
+		}
+
+		cpu.writeByte(hl, cpu.ioBus.Read(cpu.c))
+		cpu.b--
+		bc = cpu.registerPair(0)
+
+		cpu.f = cpu.f&Carry | Subtract | sz53Table[cpu.b]
+		repeat = cpu.b != 0
+	default: // OUTI
+		value := cpu.readByte(hl)
+		cpu.b--
+		bc = cpu.registerPair(0)
+
+		if cpu.onOutput != nil {
+			cpu.onOutput(cpu)
+		}
+
+		cpu.ioBus.Write(cpu.c, value)
+
+		cpu.f = cpu.f&Carry | Subtract | sz53Table[cpu.b]
+		repeat = cpu.b != 0
+	}
+
+	cpu.setHL(hl + step)
+	cpu.setRegisterPair(0, bc)
+
+	if y >= 6 && repeat {
+		cpu.pc -= 2
+		return 21
+	}
+
+	return 16
+}
@@ -0,0 +1,294 @@
+// Package z80 emulates the Zilog Z80. It shares the IOBus and Memory
+// interfaces of the Intel 8080 core, so the same boards and hosts can drive
+// either CPU.
+package z80
+
+import (
+	"github.com/gaoliveira21/intel8080-space-invaders/pkg/cpu"
+)
+
+// Cycles reported by Run for each step spent in the halted state
+const haltCycles = 4
+
+type registers struct {
+	a, f byte
+	b, c byte
+	d, e byte
+	h, l byte
+}
+
+type indexRegister struct {
+	h, l byte
+}
+
+func (r *indexRegister) get() uint16 {
+	return uint16(r.h)<<8 | uint16(r.l)
+}
+
+func (r *indexRegister) set(value uint16) {
+	r.h = byte(value >> 8)
+	r.l = byte(value)
+}
+
+type Z80 struct {
+	registers
+	// alternate register set, swapped in by EX AF,AF' and EXX
+	alt registers
+
+	ix indexRegister
+	iy indexRegister
+	// IX or IY while executing a DD or FD prefixed instruction
+	index *indexRegister
+
+	i byte
+	r byte
+
+	sp     uint16
+	pc     uint16
+	cycles uint
+
+	memory cpu.Memory
+
+	iff1          bool
+	iff2          bool
+	interruptMode byte
+	eiDeferred    bool
+	halted        bool
+
+	// data placed on the bus by an interrupting device in interrupt mode 0
+	busInstruction []byte
+	busIndex       int
+
+	// listeners
+	onInput  func(cpu *Z80)
+	onOutput func(cpu *Z80)
+
+	ioBus cpu.IOBus
+}
+
+func NewZ80(bus cpu.IOBus) *Z80 {
+	return NewZ80WithMemory(bus, cpu.NewRAM())
+}
+
+func NewZ80WithMemory(bus cpu.IOBus, mem cpu.Memory) *Z80 {
+	return &Z80{
+		memory: mem,
+		ioBus:  bus,
+		sp:     0xFFFF,
+	}
+}
+
+func (cpu *Z80) readByte(addr uint16) byte {
+	return cpu.memory.Read(addr)
+}
+
+func (cpu *Z80) writeByte(addr uint16, value byte) {
+	cpu.memory.Write(addr, value)
+}
+
+func (cpu *Z80) readWord(addr uint16) uint16 {
+	return uint16(cpu.readByte(addr+1))<<8 | uint16(cpu.readByte(addr))
+}
+
+func (cpu *Z80) writeWord(addr uint16, value uint16) {
+	cpu.writeByte(addr, byte(value))
+	cpu.writeByte(addr+1, byte(value>>8))
+}
+
+func (cpu *Z80) GetMemory() []byte {
+	mem := make([]byte, 0x10000)
+	for i := range mem {
+		mem[i] = cpu.memory.Read(uint16(i))
+	}
+
+	return mem
+}
+
+func (cpu *Z80) LoadProgram(program []byte, offset int) {
+	cpu.memory.Load(program, uint16(offset))
+}
+
+func (cpu *Z80) SetPC(value uint16) {
+	cpu.pc = value
+}
+
+func (cpu *Z80) WriteIntoMemory(addr uint16, b byte) {
+	cpu.memory.Write(addr, b)
+}
+
+func (cpu *Z80) ReadFromMemory(addr uint16) byte {
+	return cpu.memory.Read(addr)
+}
+
+func (cpu *Z80) SetInputListener(listener func(cpu *Z80)) {
+	cpu.onInput = listener
+}
+
+func (cpu *Z80) SetOutputListener(listener func(cpu *Z80)) {
+	cpu.onOutput = listener
+}
+
+func (cpu *Z80) GetRegisters() map[string]byte {
+	return map[string]byte{
+		"A":  cpu.a,
+		"F":  cpu.f,
+		"B":  cpu.b,
+		"C":  cpu.c,
+		"D":  cpu.d,
+		"E":  cpu.e,
+		"H":  cpu.h,
+		"L":  cpu.l,
+		"A'": cpu.alt.a,
+		"F'": cpu.alt.f,
+		"B'": cpu.alt.b,
+		"C'": cpu.alt.c,
+		"D'": cpu.alt.d,
+		"E'": cpu.alt.e,
+		"H'": cpu.alt.h,
+		"L'": cpu.alt.l,
+		"I":  cpu.i,
+		"R":  cpu.r,
+	}
+}
+
+func (cpu *Z80) GetPointers() map[string]uint16 {
+	return map[string]uint16{
+		"pc": cpu.pc,
+		"sp": cpu.sp,
+		"ix": cpu.ix.get(),
+		"iy": cpu.iy.get(),
+	}
+}
+
+// IsHalted reports whether the CPU is stopped on a HALT instruction, waiting
+// for an interrupt.
+func (cpu *Z80) IsHalted() bool {
+	return cpu.halted
+}
+
+func (cpu *Z80) Run() uint {
+	if cpu.halted {
+		// HALT keeps executing NOPs, so memory refresh goes on
+		cpu.incrementR()
+		cpu.cycles += haltCycles
+		return haltCycles
+	}
+
+	cpu.eiDeferred = false
+
+	cycles := cpu.execute(cpu.fetchOpcode())
+	cpu.cycles += cycles
+
+	return cycles
+}
+
+// Interrupt raises a maskable interrupt with RST interruptType on the data
+// bus, which is what interrupt mode 0 executes and interrupt mode 2 uses as
+// the low byte of the vector address.
+func (cpu *Z80) Interrupt(interruptType int) uint {
+	return cpu.InterruptWithInstruction(0xC7 | byte(interruptType&0x7)<<3)
+}
+
+// InterruptWithInstruction acknowledges a maskable interrupt with data placed
+// on the bus by the interrupting device. In interrupt mode 0 it is executed
+// as an instruction, mode 1 ignores it and restarts at $0038, and mode 2
+// reads the handler address from the table at I * 256 + data[0]. The request
+// is ignored while interrupts are disabled, including the instruction
+// following EI. It returns the cycles spent, or 0 if the interrupt was not
+// accepted.
+func (cpu *Z80) InterruptWithInstruction(data ...byte) uint {
+	if len(data) == 0 || !cpu.iff1 || cpu.eiDeferred {
+		return 0
+	}
+
+	cpu.iff1 = false
+	cpu.iff2 = false
+	// PC already points past a HALT, so that is where the handler returns to
+	cpu.halted = false
+	cpu.incrementR()
+
+	var cycles uint
+	switch cpu.interruptMode {
+	case 0:
+		cpu.busInstruction = data
+		cpu.busIndex = 0
+
+		// Two wait states are added to the acknowledge cycle
+		cycles = cpu.execute(cpu.fetch()) + 2
+
+		cpu.busInstruction = nil
+	case 1:
+		cpu.push(cpu.pc)
+		cpu.pc = 0x0038
+		cycles = 13
+	case 2:
+		cpu.push(cpu.pc)
+		cpu.pc = cpu.readWord(uint16(cpu.i)<<8 | uint16(data[0]))
+		cycles = 19
+	}
+
+	cpu.cycles += cycles
+
+	return cycles
+}
+
+// NMI raises a non-maskable interrupt, which restarts at $0066. IFF2 keeps
+// the previous interrupt state so RETN can restore it.
+func (cpu *Z80) NMI() uint {
+	cpu.iff1 = false
+	cpu.halted = false
+	cpu.incrementR()
+
+	cpu.push(cpu.pc)
+	cpu.pc = 0x0066
+
+	cpu.cycles += 11
+	return 11
+}
+
+// fetch reads the next byte of the instruction stream, which comes from the
+// bus while acknowledging an interrupt in mode 0.
+func (cpu *Z80) fetch() byte {
+	if cpu.busInstruction != nil {
+		if cpu.busIndex >= len(cpu.busInstruction) {
+			// Nothing drives the bus, so the pull-ups are read
+			return 0xFF
+		}
+
+		b := cpu.busInstruction[cpu.busIndex]
+		cpu.busIndex++
+		return b
+	}
+
+	b := cpu.readByte(cpu.pc)
+	cpu.pc++
+	return b
+}
+
+func (cpu *Z80) fetchWord() uint16 {
+	lo := cpu.fetch()
+	hi := cpu.fetch()
+	return uint16(hi)<<8 | uint16(lo)
+}
+
+// fetchOpcode is fetch for opcodes and prefixes, which also refresh memory.
+func (cpu *Z80) fetchOpcode() byte {
+	cpu.incrementR()
+	return cpu.fetch()
+}
+
+// incrementR advances the lower 7 bits of the memory refresh register.
+func (cpu *Z80) incrementR() {
+	cpu.r = cpu.r&0x80 | (cpu.r+1)&0x7F
+}
+
+func (cpu *Z80) push(value uint16) {
+	cpu.sp -= 2
+	cpu.writeWord(cpu.sp, value)
+}
+
+func (cpu *Z80) pop() uint16 {
+	value := cpu.readWord(cpu.sp)
+	cpu.sp += 2
+	return value
+}
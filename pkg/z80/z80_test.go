@@ -0,0 +1,846 @@
+package z80
+
+import (
+	"testing"
+)
+
+type TestIOBus struct {
+	port  byte
+	value byte
+}
+
+func (tb *TestIOBus) Read(b byte) byte {
+	return b
+}
+
+func (tb *TestIOBus) Write(b1 byte, b2 byte) {
+	tb.port = b1
+	tb.value = b2
+}
+
+func createCPUWithProgramLoaded(p []byte) *Z80 {
+	cpu := NewZ80(&TestIOBus{})
+	cpu.LoadProgram(p, 0)
+
+	return cpu
+}
+
+func assertCycles(t *testing.T, cpu *Z80, expected uint) {
+	if cpu.cycles != expected {
+		t.Errorf("cpu cycles have not been set correctly, got %d expected %d", cpu.cycles, expected)
+	}
+}
+
+func assertFlags(t *testing.T, cpu *Z80, instruction string, expected byte) {
+	mask := ^undocumentedFlags
+	if cpu.f&mask != expected&mask {
+		t.Errorf("%s did not set flags correctly, got %08b expected %08b", instruction, cpu.f, expected)
+	}
+}
+
+func Test_LD_BC_nn(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x01, 0x34, 0x12})
+
+	cpu.Run()
+
+	if cpu.b != 0x12 || cpu.c != 0x34 {
+		t.Errorf("LD BC,nn did not set registers correctly")
+	}
+
+	if cpu.pc != 3 {
+		t.Errorf("LD BC,nn did not increment PC correctly")
+	}
+
+	assertCycles(t, cpu, 10)
+}
+
+func Test_LD_r_r(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x78, 0x46})
+	cpu.b = 0x42
+	cpu.h, cpu.l = 0x00, 0x01
+
+	cpu.Run()
+	cpu.Run()
+
+	if cpu.a != 0x42 || cpu.b != 0x46 {
+		t.Errorf("LD did not copy registers correctly")
+	}
+
+	assertCycles(t, cpu, 4+7)
+}
+
+func Test_ADD_A_n(t *testing.T) {
+	tData := []struct {
+		a, n, result, flags byte
+	}{
+		{0x0F, 0x01, 0x10, HalfCarry},
+		{0x7F, 0x01, 0x80, Sign | HalfCarry | ParityOverflow},
+		{0xFF, 0x01, 0x00, Zero | HalfCarry | Carry},
+		{0x80, 0x80, 0x00, Zero | ParityOverflow | Carry},
+	}
+
+	for _, d := range tData {
+		cpu := createCPUWithProgramLoaded([]byte{0xC6, d.n})
+		cpu.a = d.a
+
+		cpu.Run()
+
+		if cpu.a != d.result {
+			t.Errorf("ADD A,n did not add correctly")
+		}
+
+		assertFlags(t, cpu, "ADD A,n", d.flags)
+		assertCycles(t, cpu, 7)
+	}
+}
+
+func Test_SUB_n(t *testing.T) {
+	tData := []struct {
+		a, n, result, flags byte
+	}{
+		{0x10, 0x01, 0x0F, Subtract | HalfCarry},
+		{0x80, 0x01, 0x7F, Subtract | HalfCarry | ParityOverflow},
+		{0x00, 0x01, 0xFF, Sign | Subtract | HalfCarry | Carry},
+		{0x42, 0x42, 0x00, Zero | Subtract},
+	}
+
+	for _, d := range tData {
+		cpu := createCPUWithProgramLoaded([]byte{0xD6, d.n})
+		cpu.a = d.a
+
+		cpu.Run()
+
+		if cpu.a != d.result {
+			t.Errorf("SUB n did not subtract correctly")
+		}
+
+		assertFlags(t, cpu, "SUB n", d.flags)
+	}
+}
+
+func Test_CP_n(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xFE, 0x28})
+	cpu.a = 0x10
+
+	cpu.Run()
+
+	if cpu.a != 0x10 {
+		t.Errorf("CP n changed A")
+	}
+
+	if cpu.f != Sign|Flag5|HalfCarry|Flag3|Subtract|Carry {
+		t.Errorf("CP n did not set flags correctly, got %08b", cpu.f)
+	}
+}
+
+func Test_AND_XOR_OR(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xE6, 0x0F, 0xEE, 0x0F, 0xF6, 0x81})
+	cpu.a = 0x3C
+
+	cpu.Run()
+	if cpu.a != 0x0C {
+		t.Errorf("AND n did not set A correctly")
+	}
+	assertFlags(t, cpu, "AND n", HalfCarry|ParityOverflow)
+
+	cpu.Run()
+	if cpu.a != 0x03 {
+		t.Errorf("XOR n did not set A correctly")
+	}
+	assertFlags(t, cpu, "XOR n", ParityOverflow)
+
+	cpu.Run()
+	if cpu.a != 0x83 {
+		t.Errorf("OR n did not set A correctly")
+	}
+	assertFlags(t, cpu, "OR n", Sign)
+}
+
+func Test_INC_DEC(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x3C, 0x05})
+	cpu.a = 0x7F
+	cpu.b = 0x80
+	cpu.f = Carry
+
+	cpu.Run()
+	if cpu.a != 0x80 {
+		t.Errorf("INC A did not increment A")
+	}
+	assertFlags(t, cpu, "INC A", Sign|HalfCarry|ParityOverflow|Carry)
+
+	cpu.Run()
+	if cpu.b != 0x7F {
+		t.Errorf("DEC B did not decrement B")
+	}
+	assertFlags(t, cpu, "DEC B", HalfCarry|ParityOverflow|Subtract|Carry)
+
+	assertCycles(t, cpu, 8)
+}
+
+func Test_DAA(t *testing.T) {
+	// 0x15 + 0x27 = 0x42 in BCD, then 0x42 - 0x15 = 0x27
+	cpu := createCPUWithProgramLoaded([]byte{0xC6, 0x27, 0x27, 0xD6, 0x15, 0x27})
+	cpu.a = 0x15
+
+	cpu.Run()
+	cpu.Run()
+
+	if cpu.a != 0x42 {
+		t.Errorf("DAA did not adjust an addition, got 0x%02x", cpu.a)
+	}
+
+	cpu.Run()
+	cpu.Run()
+
+	if cpu.a != 0x27 {
+		t.Errorf("DAA did not adjust a subtraction, got 0x%02x", cpu.a)
+	}
+
+	if cpu.f&Subtract == 0 || cpu.f&Carry != 0 {
+		t.Errorf("DAA did not set flags correctly")
+	}
+}
+
+func Test_DAAWithCarry(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xC6, 0x99, 0x27})
+	cpu.a = 0x99
+
+	cpu.Run()
+	cpu.Run()
+
+	if cpu.a != 0x98 || cpu.f&Carry == 0 {
+		t.Errorf("DAA did not carry into the next digit")
+	}
+}
+
+func Test_RLCA_RRA(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x07, 0x1F})
+	cpu.a = 0x81
+	cpu.f = Zero
+
+	cpu.Run()
+	if cpu.a != 0x03 || cpu.f != Zero|Carry {
+		t.Errorf("RLCA did not rotate A correctly")
+	}
+
+	cpu.Run()
+	if cpu.a != 0x81 || cpu.f != Zero|Carry {
+		t.Errorf("RRA did not rotate A through carry correctly")
+	}
+}
+
+func Test_CPL_SCF_CCF(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x2F, 0x37, 0x3F})
+	cpu.a = 0xF0
+
+	cpu.Run()
+	if cpu.a != 0x0F {
+		t.Errorf("CPL did not complement A")
+	}
+	assertFlags(t, cpu, "CPL", HalfCarry|Subtract)
+
+	cpu.Run()
+	assertFlags(t, cpu, "SCF", Carry)
+
+	cpu.Run()
+	assertFlags(t, cpu, "CCF", HalfCarry)
+}
+
+func Test_JR(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x18, 0x02, 0x00, 0x00, 0x20, 0xFA})
+	cpu.f = Zero
+
+	cpu.Run()
+	if cpu.pc != 4 {
+		t.Errorf("JR d did not jump forward")
+	}
+
+	cpu.Run()
+	if cpu.pc != 6 {
+		t.Errorf("JR NZ,d jumped while Z was set")
+	}
+
+	assertCycles(t, cpu, 12+7)
+}
+
+func Test_DJNZ(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x10, 0xFE})
+	cpu.b = 3
+
+	for cpu.pc != 2 {
+		cpu.Run()
+	}
+
+	if cpu.b != 0 {
+		t.Errorf("DJNZ did not decrement B until zero")
+	}
+
+	assertCycles(t, cpu, 13+13+8)
+}
+
+func Test_EX_AF(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x08})
+	cpu.a, cpu.f = 0x12, 0x34
+	cpu.alt.a, cpu.alt.f = 0x56, 0x78
+
+	cpu.Run()
+
+	if cpu.a != 0x56 || cpu.f != 0x78 || cpu.alt.a != 0x12 || cpu.alt.f != 0x34 {
+		t.Errorf("EX AF,AF' did not swap registers")
+	}
+}
+
+func Test_EXX(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xD9})
+	cpu.b, cpu.c, cpu.d, cpu.e, cpu.h, cpu.l = 1, 2, 3, 4, 5, 6
+	cpu.alt.b, cpu.alt.c, cpu.alt.d, cpu.alt.e, cpu.alt.h, cpu.alt.l = 7, 8, 9, 10, 11, 12
+	cpu.a = 0x42
+
+	cpu.Run()
+
+	if cpu.b != 7 || cpu.c != 8 || cpu.d != 9 || cpu.e != 10 || cpu.h != 11 || cpu.l != 12 {
+		t.Errorf("EXX did not swap in the alternate registers")
+	}
+
+	if cpu.alt.b != 1 || cpu.alt.l != 6 || cpu.a != 0x42 {
+		t.Errorf("EXX did not swap out the registers")
+	}
+
+	assertCycles(t, cpu, 4)
+}
+
+func Test_CALL_RET(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xCD, 0x10, 0x00, 0x0010: 0xC9})
+	cpu.sp = 0x2000
+
+	cpu.Run()
+
+	if cpu.pc != 0x0010 || cpu.readWord(0x1FFE) != 0x0003 {
+		t.Errorf("CALL nn did not push the return address")
+	}
+
+	cpu.Run()
+
+	if cpu.pc != 0x0003 || cpu.sp != 0x2000 {
+		t.Errorf("RET did not return")
+	}
+
+	assertCycles(t, cpu, 17+10)
+}
+
+func Test_PUSH_POP_AF(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xF5, 0xC1})
+	cpu.sp = 0x2000
+	cpu.a, cpu.f = 0x12, 0xFF
+
+	cpu.Run()
+	cpu.Run()
+
+	if cpu.b != 0x12 || cpu.c != 0xFF {
+		t.Errorf("PUSH AF did not push all flag bits")
+	}
+
+	assertCycles(t, cpu, 11+10)
+}
+
+func Test_OUT_IN(t *testing.T) {
+	bus := &TestIOBus{}
+	cpu := NewZ80(bus)
+	cpu.LoadProgram([]byte{0xD3, 0x10, 0xDB, 0x20, 0xED, 0x41, 0xED, 0x50}, 0)
+	cpu.a = 0x42
+	cpu.b = 0x99
+	cpu.c = 0x30
+
+	cpu.Run()
+	if bus.port != 0x10 || bus.value != 0x42 {
+		t.Errorf("OUT (n),A did not write to the bus")
+	}
+
+	cpu.Run()
+	if cpu.a != 0x20 {
+		t.Errorf("IN A,(n) did not read from the bus")
+	}
+
+	cpu.Run()
+	if bus.port != 0x30 || bus.value != 0x99 {
+		t.Errorf("OUT (C),B did not write to the bus")
+	}
+
+	cpu.Run()
+	if cpu.d != 0x30 {
+		t.Errorf("IN D,(C) did not read from the bus")
+	}
+
+	assertCycles(t, cpu, 11+11+12+12)
+}
+
+func Test_LD_IX_nn(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xDD, 0x21, 0x34, 0x12, 0xFD, 0x21, 0x78, 0x56})
+
+	cpu.Run()
+	cpu.Run()
+
+	if cpu.ix.get() != 0x1234 || cpu.iy.get() != 0x5678 {
+		t.Errorf("LD IX/IY,nn did not set registers correctly")
+	}
+
+	if cpu.h != 0 || cpu.l != 0 {
+		t.Errorf("LD IX,nn changed HL")
+	}
+
+	assertCycles(t, cpu, 14+14)
+}
+
+func Test_LD_r_IXd(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xDD, 0x66, 0xFF, 0xFD, 0x75, 0x02, 0x2000: 0x42})
+	cpu.ix.set(0x2001)
+	cpu.iy.set(0x2000)
+	cpu.l = 0x24
+
+	cpu.Run()
+	if cpu.h != 0x42 {
+		t.Errorf("LD H,(IX+d) did not load H")
+	}
+
+	cpu.Run()
+	if cpu.memory.Read(0x2002) != 0x24 {
+		t.Errorf("LD (IY+d),L did not store L")
+	}
+
+	if cpu.ix.get() != 0x2001 || cpu.iy.get() != 0x2000 {
+		t.Errorf("(IX+d) addressing changed the index registers")
+	}
+
+	assertCycles(t, cpu, 19+19)
+}
+
+func Test_LD_IXd_n(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xDD, 0x36, 0x05, 0x42})
+	cpu.ix.set(0x2000)
+
+	cpu.Run()
+
+	if cpu.memory.Read(0x2005) != 0x42 {
+		t.Errorf("LD (IX+d),n did not store n")
+	}
+
+	if cpu.pc != 4 {
+		t.Errorf("LD (IX+d),n did not increment PC correctly")
+	}
+
+	assertCycles(t, cpu, 19)
+}
+
+func Test_ADD_A_IXH(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xDD, 0x84, 0xFD, 0x6F})
+	cpu.ix.set(0x1200)
+	cpu.a = 0x30
+
+	cpu.Run()
+	if cpu.a != 0x42 {
+		t.Errorf("ADD A,IXH did not add the high half of IX")
+	}
+
+	cpu.Run()
+	if cpu.iy.l != 0x42 || cpu.l != 0 {
+		t.Errorf("LD IYL,A did not set the low half of IY")
+	}
+
+	assertCycles(t, cpu, 8+8)
+}
+
+func Test_ADD_IX_rp(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xDD, 0x29, 0xDD, 0x19})
+	cpu.ix.set(0x8000)
+	cpu.d, cpu.e = 0x00, 0x01
+
+	cpu.Run()
+	if cpu.ix.get() != 0 || cpu.f&Carry == 0 {
+		t.Errorf("ADD IX,IX did not add IX to itself")
+	}
+
+	cpu.Run()
+	if cpu.ix.get() != 1 || cpu.f&Carry != 0 {
+		t.Errorf("ADD IX,DE did not add DE to IX")
+	}
+
+	assertCycles(t, cpu, 15+15)
+}
+
+func Test_EX_DE_HL_IgnoresPrefix(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xDD, 0xEB})
+	cpu.d, cpu.e = 0x12, 0x34
+	cpu.ix.set(0x5678)
+
+	cpu.Run()
+
+	if cpu.h != 0x12 || cpu.l != 0x34 || cpu.ix.get() != 0x5678 {
+		t.Errorf("EX DE,HL was affected by the DD prefix")
+	}
+}
+
+func Test_CB_Rotates(t *testing.T) {
+	tData := []struct {
+		name     string
+		opcode   byte
+		value    byte
+		carry    byte
+		result   byte
+		carryOut byte
+	}{
+		{"RLC B", 0x00, 0x81, 0, 0x03, Carry},
+		{"RRC B", 0x08, 0x01, 0, 0x80, Carry},
+		{"RL B", 0x10, 0x80, Carry, 0x01, Carry},
+		{"RR B", 0x18, 0x01, 0, 0x00, Carry},
+		{"SLA B", 0x20, 0x41, Carry, 0x82, 0},
+		{"SRA B", 0x28, 0x81, 0, 0xC0, Carry},
+		{"SLL B", 0x30, 0x80, 0, 0x01, Carry},
+		{"SRL B", 0x38, 0x81, 0, 0x40, Carry},
+	}
+
+	for _, d := range tData {
+		cpu := createCPUWithProgramLoaded([]byte{0xCB, d.opcode})
+		cpu.b = d.value
+		cpu.f = d.carry
+
+		cpu.Run()
+
+		if cpu.b != d.result || cpu.f&Carry != d.carryOut {
+			t.Errorf("%s did not shift correctly, got 0x%02x", d.name, cpu.b)
+		}
+
+		assertCycles(t, cpu, 8)
+	}
+}
+
+func Test_BIT_SET_RES(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xCB, 0x7E, 0xCB, 0xFE, 0xCB, 0x7E, 0xCB, 0x87})
+	cpu.h, cpu.l = 0x20, 0x00
+	cpu.a = 0xFF
+
+	cpu.Run()
+	assertFlags(t, cpu, "BIT 7,(HL)", Zero|HalfCarry|ParityOverflow)
+
+	cpu.Run()
+	if cpu.memory.Read(0x2000) != 0x80 {
+		t.Errorf("SET 7,(HL) did not set bit 7")
+	}
+
+	cpu.Run()
+	assertFlags(t, cpu, "BIT 7,(HL)", Sign|HalfCarry)
+
+	cpu.Run()
+	if cpu.a != 0xFE {
+		t.Errorf("RES 0,A did not reset bit 0")
+	}
+
+	assertCycles(t, cpu, 12+15+12+8)
+}
+
+func Test_IndexedCB(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xDD, 0xCB, 0x01, 0xC6, 0xFD, 0xCB, 0xFF, 0x00, 0xDD, 0xCB, 0x01, 0x46})
+	cpu.ix.set(0x2000)
+	cpu.iy.set(0x2002)
+
+	cpu.Run()
+	if cpu.memory.Read(0x2001) != 0x01 {
+		t.Errorf("SET 0,(IX+d) did not set bit 0")
+	}
+
+	cpu.Run()
+	if cpu.memory.Read(0x2001) != 0x02 || cpu.b != 0x02 {
+		t.Errorf("RLC (IY+d),B did not rotate memory and copy it into B")
+	}
+
+	cpu.Run()
+	assertFlags(t, cpu, "BIT 0,(IX+d)", Zero|HalfCarry|ParityOverflow)
+
+	if cpu.pc != 12 {
+		t.Errorf("indexed CB instructions did not increment PC correctly")
+	}
+
+	assertCycles(t, cpu, 23+23+20)
+}
+
+func Test_ADC_SBC_HL(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xED, 0x4A, 0xED, 0x52})
+	cpu.h, cpu.l = 0x7F, 0xFF
+	cpu.b, cpu.c = 0x00, 0x00
+	cpu.d, cpu.e = 0x00, 0x01
+	cpu.f = Carry
+
+	cpu.Run()
+	if cpu.h != 0x80 || cpu.l != 0x00 {
+		t.Errorf("ADC HL,BC did not add carry")
+	}
+	assertFlags(t, cpu, "ADC HL,BC", Sign|HalfCarry|ParityOverflow)
+
+	cpu.Run()
+	if cpu.h != 0x7F || cpu.l != 0xFF {
+		t.Errorf("SBC HL,DE did not subtract DE")
+	}
+	assertFlags(t, cpu, "SBC HL,DE", HalfCarry|ParityOverflow|Subtract)
+
+	assertCycles(t, cpu, 15+15)
+}
+
+func Test_LD_nn_rp(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xED, 0x43, 0x00, 0x20, 0xED, 0x7B, 0x00, 0x20})
+	cpu.b, cpu.c = 0x12, 0x34
+
+	cpu.Run()
+	cpu.Run()
+
+	if cpu.sp != 0x1234 {
+		t.Errorf("LD (nn),BC / LD SP,(nn) did not copy BC into SP")
+	}
+
+	assertCycles(t, cpu, 20+20)
+}
+
+func Test_NEG(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xED, 0x44, 0xED, 0x44})
+	cpu.a = 0x01
+
+	cpu.Run()
+	if cpu.a != 0xFF {
+		t.Errorf("NEG did not negate A")
+	}
+	assertFlags(t, cpu, "NEG", Sign|HalfCarry|Subtract|Carry)
+
+	cpu.a = 0x80
+	cpu.Run()
+	if cpu.a != 0x80 {
+		t.Errorf("NEG did not negate A")
+	}
+	assertFlags(t, cpu, "NEG", Sign|ParityOverflow|Subtract|Carry)
+}
+
+func Test_RLD_RRD(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xED, 0x6F, 0xED, 0x67, 0x2000: 0x31})
+	cpu.h, cpu.l = 0x20, 0x00
+	cpu.a = 0x7A
+
+	cpu.Run()
+	if cpu.a != 0x73 || cpu.memory.Read(0x2000) != 0x1A {
+		t.Errorf("RLD did not rotate digits left")
+	}
+
+	cpu.Run()
+	if cpu.a != 0x7A || cpu.memory.Read(0x2000) != 0x31 {
+		t.Errorf("RRD did not rotate digits right")
+	}
+
+	assertCycles(t, cpu, 18+18)
+}
+
+func Test_LD_A_I(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xED, 0x47, 0xAF, 0xED, 0x57})
+	cpu.a = 0x80
+	cpu.iff2 = true
+
+	cpu.Run()
+	cpu.Run()
+	cpu.Run()
+
+	if cpu.i != 0x80 || cpu.a != 0x80 {
+		t.Errorf("LD I,A / LD A,I did not copy the register")
+	}
+
+	assertFlags(t, cpu, "LD A,I", Sign|ParityOverflow)
+	assertCycles(t, cpu, 9+4+9)
+}
+
+func Test_LDIR(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xED, 0xB0, 0x2000: 1, 2, 3})
+	cpu.h, cpu.l = 0x20, 0x00
+	cpu.d, cpu.e = 0x30, 0x00
+	cpu.b, cpu.c = 0x00, 0x03
+
+	for cpu.pc == 0 {
+		cpu.Run()
+	}
+
+	for i := uint16(0); i < 3; i++ {
+		if cpu.memory.Read(0x3000+i) != byte(i+1) {
+			t.Errorf("LDIR did not copy the block")
+		}
+	}
+
+	if cpu.registerPair(0) != 0 || cpu.registerPair(1) != 0x3003 || cpu.hl() != 0x2003 {
+		t.Errorf("LDIR did not update the register pairs")
+	}
+
+	if cpu.f&ParityOverflow != 0 {
+		t.Errorf("LDIR did not reset P/V")
+	}
+
+	assertCycles(t, cpu, 21+21+16)
+}
+
+func Test_LDD(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xED, 0xA8, 0x2000: 0x42})
+	cpu.h, cpu.l = 0x20, 0x00
+	cpu.d, cpu.e = 0x30, 0x00
+	cpu.b, cpu.c = 0x00, 0x02
+
+	cpu.Run()
+
+	if cpu.memory.Read(0x3000) != 0x42 || cpu.hl() != 0x1FFF || cpu.registerPair(1) != 0x2FFF {
+		t.Errorf("LDD did not copy and decrement")
+	}
+
+	if cpu.f&ParityOverflow == 0 {
+		t.Errorf("LDD did not set P/V while BC is not zero")
+	}
+
+	assertCycles(t, cpu, 16)
+}
+
+func Test_CPIR(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xED, 0xB1, 0x2000: 1, 2, 3, 4})
+	cpu.h, cpu.l = 0x20, 0x00
+	cpu.b, cpu.c = 0x00, 0x04
+	cpu.a = 3
+
+	for cpu.pc == 0 {
+		cpu.Run()
+	}
+
+	if cpu.hl() != 0x2003 || cpu.registerPair(0) != 1 {
+		t.Errorf("CPIR did not stop on the match")
+	}
+
+	if cpu.f&Zero == 0 || cpu.f&ParityOverflow == 0 {
+		t.Errorf("CPIR did not set flags correctly")
+	}
+
+	assertCycles(t, cpu, 21+21+16)
+}
+
+func Test_HALT(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x76, 0x3C})
+
+	cpu.Run()
+	cpu.Run()
+
+	if !cpu.IsHalted() || cpu.pc != 1 || cpu.a != 0 {
+		t.Errorf("HALT did not stop the CPU")
+	}
+
+	assertCycles(t, cpu, 4+4)
+}
+
+func Test_R(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x00, 0xDD, 0x21, 0x00, 0x00, 0xCB, 0x00})
+	cpu.r = 0xFF
+
+	cpu.Run()
+	cpu.Run()
+	cpu.Run()
+
+	if cpu.r != 0x84 {
+		t.Errorf("R was not incremented on each opcode fetch, got 0x%02x", cpu.r)
+	}
+}
+
+func TestInterruptMode0(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xFB, 0x00, 0x00})
+	cpu.sp = 0x2000
+
+	cpu.Run()
+
+	if cycles := cpu.Interrupt(2); cycles != 0 {
+		t.Errorf("interrupt was accepted right after EI")
+	}
+
+	cpu.Run()
+	cycles := cpu.Interrupt(2)
+
+	if cpu.pc != 0x0010 || cpu.readWord(0x1FFE) != 0x0002 {
+		t.Errorf("IM 0 did not execute RST 2 from the bus")
+	}
+
+	if cycles != 13 || cpu.iff1 || cpu.iff2 {
+		t.Errorf("IM 0 interrupt was not acknowledged correctly")
+	}
+}
+
+func TestInterruptMode0WithCALL(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x00, 0x00})
+	cpu.sp = 0x2000
+	cpu.iff1 = true
+
+	cpu.Run()
+	cpu.InterruptWithInstruction(0xCD, 0x34, 0x12)
+
+	if cpu.pc != 0x1234 || cpu.readWord(0x1FFE) != 0x0001 {
+		t.Errorf("IM 0 did not execute CALL from the bus")
+	}
+}
+
+func TestInterruptMode1(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xED, 0x56, 0xFB, 0x00, 0x76})
+	cpu.sp = 0x2000
+
+	for !cpu.IsHalted() {
+		cpu.Run()
+	}
+
+	cycles := cpu.Interrupt(7)
+
+	if cpu.pc != 0x0038 || cpu.readWord(0x1FFE) != 0x0005 || cpu.IsHalted() {
+		t.Errorf("IM 1 did not restart at 0x0038")
+	}
+
+	if cycles != 13 {
+		t.Errorf("IM 1 interrupt was not acknowledged correctly")
+	}
+}
+
+func TestInterruptMode2(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0x3E, 0x20, 0xED, 0x47, 0xED, 0x5E, 0xFB, 0x00, 0x20D7: 0x34, 0x12})
+	cpu.sp = 0x3000
+
+	for cpu.pc != 8 {
+		cpu.Run()
+	}
+
+	cycles := cpu.Interrupt(2) // RST 2 is $D7 on the bus
+
+	if cpu.pc != 0x1234 || cpu.readWord(0x2FFE) != 0x0008 {
+		t.Errorf("IM 2 did not jump through the vector table, got 0x%04x", cpu.pc)
+	}
+
+	if cycles != 19 {
+		t.Errorf("IM 2 interrupt was not acknowledged correctly")
+	}
+}
+
+func TestInterruptIsIgnoredWhenDisabled(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xF3, 0x00})
+
+	cpu.Run()
+
+	if cycles := cpu.Interrupt(1); cycles != 0 || cpu.pc != 1 {
+		t.Errorf("interrupt was accepted while disabled")
+	}
+}
+
+func TestNMI(t *testing.T) {
+	cpu := createCPUWithProgramLoaded([]byte{0xFB, 0x00, 0x0066: 0xED, 0x45})
+	cpu.sp = 0x2000
+
+	cpu.Run()
+	cpu.NMI()
+
+	if cpu.pc != 0x0066 || cpu.iff1 || !cpu.iff2 {
+		t.Errorf("NMI did not restart at 0x0066 keeping IFF2")
+	}
+
+	cpu.Run()
+
+	if cpu.pc != 0x0001 || !cpu.iff1 {
+		t.Errorf("RETN did not restore IFF1")
+	}
+}
@@ -0,0 +1,60 @@
+package z80
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// The ROM is not bundled, CI downloads it, see the README
+const zexdocPath = "../../cmd/zexdoc/roms/tests/zexdoc.com"
+
+func runCPM(program []byte) string {
+	var out strings.Builder
+	RunCPM(NewZ80(&TestIOBus{}), program, &out)
+	return out.String()
+}
+
+func Test_RunCPM(t *testing.T) {
+	program := []byte{
+		0x31, 0x00, 0xC9, // LD SP,$C900
+		0x0E, 0x09, // LD C,$09
+		0x11, 0x15, 0x01, // LD DE,$0115
+		0xCD, 0x05, 0x00, // CALL $0005
+		0x0E, 0x02, // LD C,$02
+		0x1E, 0x21, // LD E,'!'
+		0xCD, 0x05, 0x00, // CALL $0005
+		0xC3, 0x00, 0x00, // JP $0000
+		'o', 'k', '$', // $0115
+	}
+
+	if out := runCPM(program); out != "ok!" {
+		t.Errorf("runCPM did not print the BDOS output, got %q", out)
+	}
+}
+
+func Test_ZEXDOC(t *testing.T) {
+	rom, err := os.ReadFile(zexdocPath)
+	if err != nil {
+		// CI downloads the ROM and must not skip the test
+		if os.Getenv("ZEXDOC_REQUIRED") != "" {
+			t.Fatalf("Cannot read %s: %s", zexdocPath, err)
+		}
+		t.Skipf("%s not found, see the README", zexdocPath)
+	}
+	if testing.Short() {
+		t.Skip("ZEXDOC takes minutes to run")
+	}
+
+	out := runCPM(rom)
+	t.Log(out)
+
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, "ERROR") {
+			t.Errorf("ZEXDOC failed: %s", strings.TrimSpace(line))
+		}
+	}
+	if !strings.Contains(out, "Tests complete") {
+		t.Errorf("ZEXDOC did not run to completion")
+	}
+}